@@ -1,16 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/auto"
 	"github.com/xseman/bysquare/go/pkg/bysquare/invoice"
 	"github.com/xseman/bysquare/go/pkg/bysquare/pay"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
 )
 
 // version is set by ldflags at build time
@@ -21,20 +29,31 @@ const (
 
 USAGE:
     bysquare pay encode [OPTIONS] <input.json>
-    bysquare pay decode <qr-string>
+    bysquare pay decode <qr-string|image>
     bysquare invoice encode [OPTIONS] <input.json>
-    bysquare invoice decode <qr-string>
-    bysquare decode <qr-string>
+    bysquare invoice decode <qr-string|image>
+    bysquare decode <qr-string|image>
+    bysquare encode <type> <input.json>
+    bysquare types
+    bysquare schema <type>
+    bysquare inspect [OPTIONS] <qr-string|image>
     bysquare version
 
 COMMANDS:
     pay          PAY by square operations
     invoice      Invoice by square operations
     decode       Auto-detect and decode any BySquare QR string
+    encode       Encode with a registered document type and default options
+    types        List registered document types
+    schema       Print the JSON Schema of a registered document type
+    inspect      Show every layer of a QR string: header, sizes, CRC32, fields
     version      Print version information
 
 PAY ENCODE OPTIONS:
     -D, --no-deburr           Keep diacritics (deburr enabled by default)
+    -t, --transliterate NAME  Deburr table: default (Latin), extended (also Cyrillic, Greek)
+    -d, --domestic CC         Accept SK or CZ domestic account numbers (prefix-number/bank code)
+    -b, --fill-bic            Derive missing BICs from the IBAN, warn about mismatching ones
     -V, --no-validate         Skip validation (validation enabled by default)
     -s, --spec-version VER    Specification version: 1.0.0, 1.1.0, 1.2.0 (default: 1.2.0)
 
@@ -42,6 +61,9 @@ INVOICE ENCODE OPTIONS:
     -V, --no-validate         Skip validation (validation enabled by default)
     -s, --spec-version VER    Specification version: 1.0.0 (default: 1.0.0)
 
+INSPECT OPTIONS:
+    -j, --json                Print JSON instead of a human-readable report
+
 EXAMPLES:
     # PAY: Encode with defaults
     $ bysquare pay encode payment.json
@@ -55,9 +77,18 @@ EXAMPLES:
     # Invoice: Encode
     $ bysquare invoice encode invoice.json
 
+    # Encode a credit note with a registered document type
+    $ bysquare encode credit-note note.json
+
     # Auto-detect and decode any BySquare QR
     $ bysquare decode "00D80..."
 
+    # Scan and decode a QR code image (PNG, JPEG or GIF)
+    $ bysquare decode scan.png
+
+    # Inspect a QR string rejected by a bank app
+    $ bysquare inspect "00D80..."
+
 For more information, visit: https://github.com/xseman/bysquare
 `
 )
@@ -86,6 +117,23 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+	case "encode":
+		if err := cmdEncode(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "types":
+		cmdTypes()
+	case "schema":
+		if err := cmdSchema(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "inspect":
+		if err := cmdInspect(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "version", "-v", "--version":
 		fmt.Printf("bysquare version %s\n", version)
 	case "help", "-h", "--help":
@@ -118,6 +166,15 @@ func cmdPayEncode(args []string) error {
 	noDeburr := fs.Bool("no-deburr", false, "Keep diacritics")
 	fs.BoolVar(noDeburr, "D", false, "Keep diacritics (shorthand)")
 
+	transliterate := fs.String("transliterate", "default", "Deburr table (default, extended)")
+	fs.StringVar(transliterate, "t", "default", "Deburr table (shorthand)")
+
+	domestic := fs.String("domestic", "", "Country of domestic account numbers (SK, CZ)")
+	fs.StringVar(domestic, "d", "", "Country of domestic account numbers (shorthand)")
+
+	fillBIC := fs.Bool("fill-bic", false, "Derive missing BICs from the IBAN")
+	fs.BoolVar(fillBIC, "b", false, "Derive missing BICs from the IBAN (shorthand)")
+
 	noValidate := fs.Bool("no-validate", false, "Skip validation")
 	fs.BoolVar(noValidate, "V", false, "Skip validation (shorthand)")
 
@@ -138,10 +195,18 @@ func cmdPayEncode(args []string) error {
 		return err
 	}
 
+	transliterator, err := parseTransliterator(*transliterate)
+	if err != nil {
+		return err
+	}
+
 	cfg := pay.EncodeOptions{
-		Deburr:   !*noDeburr,
-		Validate: !*noValidate,
-		Version:  ver,
+		Deburr:          !*noDeburr,
+		Transliterator:  transliterator,
+		DomesticCountry: strings.ToUpper(*domestic),
+		FillBIC:         *fillBIC,
+		Validate:        !*noValidate,
+		Version:         ver,
 	}
 
 	for _, inputFile := range positionals {
@@ -159,12 +224,15 @@ func encodePayAndPrint(data []byte, cfg pay.EncodeOptions) error {
 		return fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	qr, err := pay.Encode(model, cfg)
+	result, err := pay.EncodeDetailed(model, cfg)
 	if err != nil {
 		return fmt.Errorf("encoding failed: %w", err)
 	}
 
-	fmt.Println(qr)
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", w)
+	}
+	fmt.Println(result.QR)
 	return nil
 }
 
@@ -290,33 +358,160 @@ func cmdDecodeAuto(args []string) error {
 		return err
 	}
 
-	rawBytes, err := bysquare.DecodeBase32Hex(qr, true)
+	doc, _, err := auto.Decode(qr)
 	if err != nil {
-		return fmt.Errorf("decoding failed: invalid base32hex: %w", err)
+		return fmt.Errorf("decoding failed: %w", err)
 	}
 
-	if len(rawBytes) < 2 {
-		return fmt.Errorf("decoding failed: input too short")
+	return printJSON(doc)
+}
+
+// cmdEncode encodes JSON files with the codec registered under a name.
+func cmdEncode(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: bysquare encode <type> <input.json>")
 	}
 
-	header := bysquare.ParseBysquareHeader(rawBytes[:2])
+	codec, err := codecByName(args[0])
+	if err != nil {
+		return err
+	}
 
-	switch header.BySquareType {
-	case 0x00:
-		model, err := pay.Decode(qr)
+	for _, inputFile := range args[1:] {
+		input, err := readInput(inputFile)
 		if err != nil {
-			return fmt.Errorf("decoding failed: %w", err)
+			return err
 		}
-		return printJSON(model)
-	case 0x01:
-		model, err := invoice.Decode(qr)
+
+		qr, err := codec.EncodeJSON(input)
 		if err != nil {
-			return fmt.Errorf("decoding failed: %w", err)
+			return fmt.Errorf("encoding failed: %w", err)
 		}
-		return printJSON(model)
-	default:
-		return fmt.Errorf("unsupported bysquareType: %d", header.BySquareType)
+
+		fmt.Println(qr)
+	}
+
+	return nil
+}
+
+// cmdTypes lists the registered codecs.
+func cmdTypes() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tBYSQUARE TYPE\tDOCUMENT TYPE")
+	for _, c := range bysquare.Codecs() {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", c.Name, c.BysquareType, c.DocumentType)
 	}
+	_ = w.Flush()
+}
+
+// cmdSchema prints the JSON Schema of a registered codec.
+func cmdSchema(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing type argument")
+	}
+
+	codec, err := codecByName(args[0])
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, codec.Schema, "", "  "); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+func codecByName(name string) (bysquare.Codec, error) {
+	codec, ok := bysquare.CodecByName(name)
+	if !ok {
+		var names []string
+		for _, c := range bysquare.Codecs() {
+			names = append(names, c.Name)
+		}
+		return bysquare.Codec{}, fmt.Errorf("unknown type: %s (use %s)", name, strings.Join(names, ", "))
+	}
+	return codec, nil
+}
+
+// cmdInspect prints every layer of a QR string. A partial report is
+// printed before the error when a layer cannot be decoded.
+func cmdInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+
+	asJSON := fs.Bool("json", false, "Print JSON")
+	fs.BoolVar(asJSON, "j", false, "Print JSON (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	qr, err := readQRInput(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	in, inspectErr := bysquare.Inspect(qr)
+	if in != nil {
+		if *asJSON {
+			if err := printJSON(in); err != nil {
+				return err
+			}
+		} else {
+			printInspection(os.Stdout, in)
+		}
+	}
+	if inspectErr != nil {
+		return fmt.Errorf("inspection failed: %w", inspectErr)
+	}
+
+	return nil
+}
+
+func printInspection(out io.Writer, in *bysquare.Inspection) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	h := in.Header
+
+	named := func(value uint8, name string) string {
+		if name == "" {
+			return fmt.Sprintf("%d", value)
+		}
+		return fmt.Sprintf("%d (%s)", value, name)
+	}
+
+	fmt.Fprintln(w, "HEADER")
+	fmt.Fprintf(w, "  bysquareType\t%s\n", named(h.BySquareType, h.TypeName))
+	fmt.Fprintf(w, "  version\t%s\n", named(h.Version, h.VersionName))
+	fmt.Fprintf(w, "  documentType\t%s\n", named(h.DocumentType, h.DocumentTypeName))
+	fmt.Fprintf(w, "  reserved\t%d\n", h.Reserved)
+
+	fmt.Fprintln(w, "\nPAYLOAD")
+	fmt.Fprintf(w, "  declared length\t%d\n", in.DeclaredLength)
+	fmt.Fprintf(w, "  actual length\t%d\n", in.ActualLength)
+	fmt.Fprintf(w, "  compressed size\t%d\n", in.CompressedSize)
+	fmt.Fprintf(w, "  compression ratio\t%.2f\n", in.CompressionRatio)
+	if in.TrailingData {
+		fmt.Fprintln(w, "  trailing data\tyes")
+	}
+	fmt.Fprintf(w, "  stored CRC32\t%08x\n", in.StoredCRC32)
+	status := "ok"
+	if !in.ChecksumValid {
+		status = "MISMATCH"
+	}
+	fmt.Fprintf(w, "  computed CRC32\t%08x (%s)\n", in.ComputedCRC32, status)
+
+	fmt.Fprintln(w, "\nRAW")
+	fmt.Fprintf(w, "  %x\n", []byte(in.Raw))
+
+	if len(in.Fields) > 0 {
+		fmt.Fprintln(w, "\nFIELDS")
+		for _, f := range in.Fields {
+			fmt.Fprintf(w, "  %d\t%s\t%q\n", f.Index, f.Name, f.Value)
+		}
+	}
+
+	_ = w.Flush()
 }
 
 // readInput reads file contents or stdin.
@@ -337,6 +532,8 @@ func readInput(path string) ([]byte, error) {
 }
 
 // readQRInput extracts a QR string from args (literal, file, or stdin).
+// Files and stdin holding a PNG, JPEG or GIF image are scanned for a QR
+// code.
 func readQRInput(args []string) (string, error) {
 	if len(args) < 1 {
 		return "", fmt.Errorf("missing QR string argument")
@@ -349,7 +546,7 @@ func readQRInput(args []string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("failed to read stdin: %w", err)
 		}
-		return strings.TrimSpace(string(input)), nil
+		return qrFromContent(input)
 	}
 
 	if fileInfo, err := os.Stat(qrInput); err == nil && !fileInfo.IsDir() {
@@ -357,12 +554,30 @@ func readQRInput(args []string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("failed to read file: %w", err)
 		}
-		return strings.TrimSpace(string(content)), nil
+		return qrFromContent(content)
 	}
 
 	return qrInput, nil
 }
 
+// qrFromContent returns the QR string held in file or stdin content,
+// scanning it first when the content is an image.
+func qrFromContent(content []byte) (string, error) {
+	if _, _, err := image.DecodeConfig(bytes.NewReader(content)); err != nil {
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	text, err := qr.Scan(img)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan image: %w", err)
+	}
+	return text, nil
+}
+
 func parseVersion(s string) (bysquare.Version, error) {
 	switch s {
 	case "1.0.0":
@@ -376,6 +591,17 @@ func parseVersion(s string) (bysquare.Version, error) {
 	}
 }
 
+func parseTransliterator(s string) (bysquare.Transliterator, error) {
+	switch s {
+	case "default":
+		return bysquare.DefaultTransliterator, nil
+	case "extended":
+		return bysquare.ExtendedTransliterator, nil
+	default:
+		return nil, fmt.Errorf("unknown transliterator: %s (use default or extended)", s)
+	}
+}
+
 func printJSON(v interface{}) error {
 	output, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare/pay"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
 )
 
 var (
@@ -506,6 +507,89 @@ func TestDecodeAutoDetect(t *testing.T) {
 	}
 }
 
+func TestDecodeAutoFromImage(t *testing.T) {
+	qrString := "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+
+	code, err := qr.Encode(qrString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := code.PNG(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imagePath := filepath.Join(t.TempDir(), "scan.png")
+	if err := os.WriteFile(imagePath, png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		stdout, stderr, exitCode := runCLI(t, []string{"decode", imagePath}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "random-id") {
+			t.Errorf("Expected decoded invoiceId in output, got: %s", stdout)
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		stdout, stderr, exitCode := runCLI(t, []string{"pay", "decode", "-"}, string(png))
+		if exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "random-id") {
+			t.Errorf("Expected decoded invoiceId in output, got: %s", stdout)
+		}
+	})
+}
+
+func TestInspect(t *testing.T) {
+	qrString := "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+
+	t.Run("human-readable", func(t *testing.T) {
+		stdout, stderr, exitCode := runCLI(t, []string{"inspect", qrString}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr)
+		}
+		for _, want := range []string{"PAY by square", "1.2.0", "(ok)", "payments[0].bankAccounts[0].iban"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("Expected output to contain %q, got: %s", want, stdout)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		stdout, stderr, exitCode := runCLI(t, []string{"inspect", "--json", qrString}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr)
+		}
+
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		if result["checksumValid"] != true {
+			t.Errorf("Expected checksumValid true, got: %v", result["checksumValid"])
+		}
+		fields, ok := result["fields"].([]interface{})
+		if !ok || len(fields) == 0 {
+			t.Fatalf("Expected fields array, got: %v", result["fields"])
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, stderr, exitCode := runCLI(t, []string{"inspect", "!!!"}, "")
+		if exitCode == 0 {
+			t.Error("Expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "inspection failed") {
+			t.Errorf("Expected inspection error, got: %s", stderr)
+		}
+	})
+}
+
 func TestDecodeAutoMissingArg(t *testing.T) {
 	err := cmdDecodeAuto([]string{})
 	if err == nil {
@@ -537,3 +621,82 @@ func TestInvoiceMissingSubcommand(t *testing.T) {
 		t.Errorf("Expected missing subcommand error, got: %s", stderr)
 	}
 }
+
+func TestEncodeRegisteredType(t *testing.T) {
+	qrString, stderr, exitCode := runCLI(t, []string{"encode", "pay", exampleJSON}, "")
+	if exitCode != 0 {
+		t.Fatalf("Encode failed with exit code %d. Stderr: %s", exitCode, stderr)
+	}
+
+	stdout, stderr, exitCode := runCLI(t, []string{"decode", qrString}, "")
+	if exitCode != 0 {
+		t.Fatalf("Decode failed with exit code %d. Stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stdout, `"invoiceId": "random-id"`) {
+		t.Errorf("expected decoded invoiceId, got: %s", stdout)
+	}
+
+	_, stderr, exitCode = runCLI(t, []string{"encode", "unknown", exampleJSON}, "")
+	if exitCode == 0 || !strings.Contains(stderr, "unknown type") {
+		t.Errorf("expected unknown type error, got exit code %d. Stderr: %s", exitCode, stderr)
+	}
+}
+
+func TestTypesAndSchema(t *testing.T) {
+	stdout, _, exitCode := runCLI(t, []string{"types"}, "")
+	if exitCode != 0 {
+		t.Fatalf("types failed with exit code %d", exitCode)
+	}
+	for _, name := range []string{"pay", "invoice", "credit-note"} {
+		if !strings.Contains(stdout, name) {
+			t.Errorf("expected %q in types output: %s", name, stdout)
+		}
+	}
+
+	stdout, stderr, exitCode := runCLI(t, []string{"schema", "invoice"}, "")
+	if exitCode != 0 {
+		t.Fatalf("schema failed with exit code %d. Stderr: %s", exitCode, stderr)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if schema["type"] != "object" {
+		t.Errorf("expected object schema, got: %v", schema["type"])
+	}
+}
+
+func TestPayEncodeDomestic(t *testing.T) {
+	input := `{"payments":[{"type":1,"amount":100,"currencyCode":"EUR","bankAccounts":[{"iban":"19-8742637541/1200"}],"beneficiary":{"name":"Test"}}]}`
+
+	if _, _, exitCode := runCLI(t, []string{"pay", "encode", "-"}, input); exitCode == 0 {
+		t.Fatal("expected domestic account number to fail without --domestic")
+	}
+
+	qrString, stderr, exitCode := runCLI(t, []string{"pay", "encode", "--domestic", "sk", "-"}, input)
+	if exitCode != 0 {
+		t.Fatalf("Encode failed with exit code %d. Stderr: %s", exitCode, stderr)
+	}
+
+	stdout, _, _ := runCLI(t, []string{"pay", "decode", strings.TrimSpace(qrString)}, "")
+	if !strings.Contains(stdout, "SK3112000000198742637541") {
+		t.Errorf("expected converted IBAN, got: %s", stdout)
+	}
+}
+
+func TestPayEncodeFillBIC(t *testing.T) {
+	input := `{"payments":[{"type":1,"amount":100,"currencyCode":"EUR","bankAccounts":[{"iban":"SK9611000000002918599669"},{"iban":"CZ6508000000192000145399","bic":"KOMBCZPP"}],"beneficiary":{"name":"Test"}}]}`
+
+	qrString, stderr, exitCode := runCLI(t, []string{"pay", "encode", "--fill-bic", "-"}, input)
+	if exitCode != 0 {
+		t.Fatalf("Encode failed with exit code %d. Stderr: %s", exitCode, stderr)
+	}
+	if !strings.Contains(stderr, "warning: BIC KOMBCZPP does not match bank 0800") {
+		t.Errorf("expected BIC mismatch warning, got: %s", stderr)
+	}
+
+	stdout, _, _ := runCLI(t, []string{"pay", "decode", strings.TrimSpace(qrString)}, "")
+	if !strings.Contains(stdout, `"bic": "TATRSKBX"`) {
+		t.Errorf("expected derived BIC, got: %s", stdout)
+	}
+}
@@ -6,6 +6,9 @@ package main
 //    INVOICE_ENCODE: bysquare_invoice_encode(json, config) -> *char
 //    INVOICE_DECODE: bysquare_invoice_decode(qr) -> *json
 //    DETECT:         bysquare_detect_type(qr) -> int (0=pay, 1=invoice, -1=error)
+//    ENCODE:         bysquare_encode(type, json) -> *char, any registered type, default options
+//    DECODE:         bysquare_decode(qr) -> *json {"type": name, "document": {...}}
+//    SCHEMA:         bysquare_schema(type) -> *json (JSON Schema of a registered type)
 //    CLEANUP:        free(ptr)
 //
 // Configuration is passed as a bitflag integer (C.int):
@@ -42,6 +45,7 @@ import (
 	"unsafe"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/auto"
 	"github.com/xseman/bysquare/go/pkg/bysquare/invoice"
 	"github.com/xseman/bysquare/go/pkg/bysquare/pay"
 )
@@ -202,15 +206,93 @@ func bysquare_detect_type(qrString *C.char) C.int {
 
 	input := C.GoString(qrString)
 
-	rawBytes, err := bysquare.DecodeBase32Hex(input, true)
-	if err != nil || len(rawBytes) < 2 {
+	header, err := auto.ParseHeader(input)
+	if err != nil {
 		return -1
 	}
 
-	header := bysquare.ParseBysquareHeader(rawBytes[:2])
 	return C.int(header.BySquareType)
 }
 
+//export bysquare_encode
+func bysquare_encode(typeName *C.char, input *C.char) (ret *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = C.CString(fmt.Sprintf("ERROR:panic: %v", r))
+		}
+	}()
+
+	if typeName == nil || input == nil {
+		return C.CString("ERROR:null input")
+	}
+
+	codec, ok := bysquare.CodecByName(C.GoString(typeName))
+	if !ok {
+		return C.CString(fmt.Sprintf("ERROR:unknown type: %s", C.GoString(typeName)))
+	}
+
+	length := C.strlen(input)
+	inputBytes := unsafe.Slice((*byte)(unsafe.Pointer(input)), length)
+
+	result, err := codec.EncodeJSON(inputBytes)
+	if err != nil {
+		return C.CString(fmt.Sprintf("ERROR:%s", err.Error()))
+	}
+
+	return C.CString(result)
+}
+
+//export bysquare_decode
+func bysquare_decode(qrString *C.char) (ret *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = C.CString(fmt.Sprintf("ERROR:panic: %v", r))
+		}
+	}()
+
+	if qrString == nil {
+		return C.CString("ERROR:null input")
+	}
+
+	input := C.GoString(qrString)
+
+	doc, header, err := auto.Decode(input)
+	if err != nil {
+		return C.CString(fmt.Sprintf("ERROR:%s", err.Error()))
+	}
+
+	codec, _ := auto.Lookup(header)
+	output, err := json.Marshal(struct {
+		Type     string            `json:"type"`
+		Document bysquare.Document `json:"document"`
+	}{codec.Name, doc})
+	if err != nil {
+		return C.CString(fmt.Sprintf("ERROR:JSON marshal error: %s", err.Error()))
+	}
+
+	return C.CString(string(output))
+}
+
+//export bysquare_schema
+func bysquare_schema(typeName *C.char) (ret *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = C.CString(fmt.Sprintf("ERROR:panic: %v", r))
+		}
+	}()
+
+	if typeName == nil {
+		return C.CString("ERROR:null input")
+	}
+
+	codec, ok := bysquare.CodecByName(C.GoString(typeName))
+	if !ok {
+		return C.CString(fmt.Sprintf("ERROR:unknown type: %s", C.GoString(typeName)))
+	}
+
+	return C.CString(string(codec.Schema))
+}
+
 //export bysquare_free
 func bysquare_free(ptr *C.char) {
 	C.free(unsafe.Pointer(ptr))
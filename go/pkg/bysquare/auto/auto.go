@@ -0,0 +1,95 @@
+// Package auto decodes any BySquare QR string by detecting its type from
+// the header.
+//
+// It dispatches to the codecs registered with bysquare.RegisterCodec and
+// links the pay and invoice packages, so their codecs are always available.
+// Link further codec packages with a blank import to make them detectable.
+package auto
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+	_ "github.com/xseman/bysquare/go/pkg/bysquare/invoice"
+	_ "github.com/xseman/bysquare/go/pkg/bysquare/pay"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
+)
+
+// Decode reads the header of a BySquare QR string and decodes it with the
+// registered codec.
+//
+//	+----------------+--------------------+
+//	| bysquareType   | Document           |
+//	+----------------+--------------------+
+//	| 0x00           | *pay.DataModel     |
+//	| 0x01           | *invoice.DataModel |
+//	| other          | registered codecs  |
+//	+----------------+--------------------+
+//
+// The codec is looked up by bysquareType and documentType. Without an exact
+// match the first codec of the bysquareType decodes, so unknown document
+// types reach the decoder of their family.
+//
+// The parsed header is returned along with the document. It is also
+// returned when decoding fails after the header was read, so callers can
+// report what kind of document was rejected.
+//
+// @see 3.5.
+func Decode(qr string) (bysquare.Document, bysquare.BysquareHeader, error) {
+	header, err := ParseHeader(qr)
+	if err != nil {
+		return nil, bysquare.BysquareHeader{}, err
+	}
+
+	codec, ok := Lookup(header)
+	if !ok {
+		return nil, header, fmt.Errorf("%w: unsupported %d", bysquare.ErrUnexpectedType, header.BySquareType)
+	}
+
+	doc, err := codec.Decode(qr)
+	if err != nil {
+		return nil, header, err
+	}
+	return doc, header, nil
+}
+
+// Lookup returns the codec Decode uses for header.
+func Lookup(header bysquare.BysquareHeader) (bysquare.Codec, bool) {
+	if codec, ok := bysquare.LookupCodec(header.BySquareType, header.DocumentType); ok {
+		return codec, true
+	}
+	for _, codec := range bysquare.Codecs() {
+		if codec.BysquareType == header.BySquareType {
+			return codec, true
+		}
+	}
+	return bysquare.Codec{}, false
+}
+
+// ParseHeader decodes only the header of a BySquare QR string. Unlike
+// Decode it does not decompress or verify the payload.
+//
+// @see 3.5.
+func ParseHeader(qr string) (bysquare.BysquareHeader, error) {
+	bytes, err := bysquare.DecodeBase32Hex(qr, true)
+	if err != nil {
+		return bysquare.BysquareHeader{}, fmt.Errorf("base32hex decode failed: %w", err)
+	}
+
+	var header bysquare.BysquareHeader
+	if err := header.UnmarshalBinary(bytes); err != nil {
+		return bysquare.BysquareHeader{}, err
+	}
+	return header, nil
+}
+
+// DecodeImage scans an image for a QR code and decodes it with Decode.
+func DecodeImage(img image.Image) (bysquare.Document, bysquare.BysquareHeader, error) {
+	text, err := qr.Scan(img)
+	if err != nil {
+		return nil, bysquare.BysquareHeader{}, fmt.Errorf("QR scan failed: %w", err)
+	}
+
+	return Decode(text)
+}
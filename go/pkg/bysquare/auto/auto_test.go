@@ -0,0 +1,134 @@
+package auto
+
+import (
+	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/invoice"
+	"github.com/xseman/bysquare/go/pkg/bysquare/pay"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
+)
+
+const payQR = "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+
+func encodeInvoice(t *testing.T) string {
+	t.Helper()
+	numLines := 1
+	model := &invoice.DataModel{
+		DocumentType:      invoice.InvoiceDocumentTypeCreditNote,
+		InvoiceID:         "INV-001",
+		IssueDate:         "20240101",
+		LocalCurrencyCode: "EUR",
+		SupplierParty: invoice.SupplierParty{
+			Party: invoice.Party{PartyName: "Supplier s.r.o."},
+			PostalAddress: invoice.PostalAddress{
+				StreetName: "Main Street",
+				CityName:   "Bratislava",
+				PostalZone: "81101",
+				Country:    "SVK",
+			},
+		},
+		CustomerParty:        invoice.CustomerParty{Party: invoice.Party{PartyName: "Customer a.s."}},
+		NumberOfInvoiceLines: &numLines,
+		TaxCategorySummaries: []invoice.TaxCategorySummary{{
+			ClassifiedTaxCategory: "0.20",
+			TaxExclusiveAmount:    "100",
+			TaxAmount:             "20",
+		}},
+	}
+
+	encoded, err := invoice.Encode(model)
+	if err != nil {
+		t.Fatalf("invoice.Encode() error: %v", err)
+	}
+	return encoded
+}
+
+func TestDecodePay(t *testing.T) {
+	doc, header, err := Decode(payQR)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+
+	if header.BySquareType != 0x00 {
+		t.Errorf("expected bysquareType 0, got %d", header.BySquareType)
+	}
+	if doc.BysquareType() != header.BySquareType {
+		t.Errorf("expected document type %d, got %d", header.BySquareType, doc.BysquareType())
+	}
+
+	model, ok := doc.(*pay.DataModel)
+	if !ok {
+		t.Fatalf("expected *pay.DataModel, got %T", doc)
+	}
+	if model.InvoiceID != "random-id" {
+		t.Errorf("expected InvoiceID 'random-id', got %q", model.InvoiceID)
+	}
+}
+
+func TestDecodeInvoice(t *testing.T) {
+	doc, header, err := Decode(encodeInvoice(t))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+
+	if header.BySquareType != 0x01 {
+		t.Errorf("expected bysquareType 1, got %d", header.BySquareType)
+	}
+	if header.DocumentType != uint8(invoice.InvoiceDocumentTypeCreditNote) {
+		t.Errorf("expected documentType %d, got %d", invoice.InvoiceDocumentTypeCreditNote, header.DocumentType)
+	}
+
+	model, ok := doc.(*invoice.DataModel)
+	if !ok {
+		t.Fatalf("expected *invoice.DataModel, got %T", doc)
+	}
+	if model.InvoiceID != "INV-001" {
+		t.Errorf("expected InvoiceID 'INV-001', got %q", model.InvoiceID)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	unknownType := bysquare.EncodeBase32Hex([]byte{0x20, 0x00}, false)
+
+	tests := []struct {
+		name     string
+		input    string
+		wantType uint8
+	}{
+		{"empty string", "", 0x00},
+		{"invalid base32hex", "!!!INVALID!!!", 0x00},
+		{"unsupported type", unknownType, 0x02},
+		{"corrupted pay payload", payQR[:20], 0x00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, header, err := Decode(tt.input)
+			if err == nil {
+				t.Fatalf("expected error for input %q", tt.input)
+			}
+			if doc != nil {
+				t.Errorf("expected nil document, got %T", doc)
+			}
+			if header.BySquareType != tt.wantType {
+				t.Errorf("expected bysquareType %d, got %d", tt.wantType, header.BySquareType)
+			}
+		})
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	code, err := qr.Encode(payQR)
+	if err != nil {
+		t.Fatalf("qr.Encode() error: %v", err)
+	}
+
+	doc, _, err := DecodeImage(code.Image(4))
+	if err != nil {
+		t.Fatalf("DecodeImage() error: %v", err)
+	}
+	if _, ok := doc.(*pay.DataModel); !ok {
+		t.Errorf("expected *pay.DataModel, got %T", doc)
+	}
+}
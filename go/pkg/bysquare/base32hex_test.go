@@ -264,3 +264,26 @@ func TestDecodeBase32HexInvalidInput(t *testing.T) {
 		})
 	}
 }
+
+func FuzzDecodeBase32Hex(f *testing.F) {
+	f.Add("0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00")
+	f.Add("C5H66P0=")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, loose := range []bool{false, true} {
+			decoded, err := DecodeBase32Hex(input, loose)
+			if err != nil {
+				continue
+			}
+			// Whatever decodes must survive a round trip.
+			again, err := DecodeBase32Hex(EncodeBase32Hex(decoded, false), false)
+			if err != nil {
+				t.Fatalf("round trip of %q failed: %v", input, err)
+			}
+			if !bytes.Equal(again, decoded) {
+				t.Fatalf("round trip of %q: expected %x, got %x", input, decoded, again)
+			}
+		}
+	})
+}
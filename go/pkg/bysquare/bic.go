@@ -0,0 +1,123 @@
+package bysquare
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+//go:embed banks.tsv
+var banksTSV string
+
+// Bank is one entry of a BICTable.
+type Bank struct {
+	// Country is the ISO 3166-1 alpha-2 code of the IBAN.
+	Country string
+	// Code is the national bank code at the start of the BBAN.
+	Code string
+	BIC  string
+	Name string
+}
+
+// MatchesBIC reports whether bic belongs to the same institution and
+// location as b, i.e. whether their first 8 characters agree. Branch codes
+// are ignored, so "GIBASKBX" matches "GIBASKBXXXX".
+func (b Bank) MatchesBIC(bic string) bool {
+	bic = strings.ToUpper(strings.TrimSpace(bic))
+	return len(bic) >= 8 && len(b.BIC) >= 8 && bic[:8] == b.BIC[:8]
+}
+
+// BICTable maps national bank codes to BICs, so a BIC can be derived from
+// an IBAN offline.
+type BICTable struct {
+	banks map[string]Bank
+	// codeLength is the bank code length of each country.
+	codeLength map[string]int
+}
+
+var defaultBICTable = sync.OnceValue(func() *BICTable {
+	t, err := ParseBICTable(strings.NewReader(banksTSV))
+	if err != nil {
+		panic(err)
+	}
+	return t
+})
+
+// DefaultBICTable returns the table embedded in this package. It covers
+// the Slovak and Czech bank codes.
+func DefaultBICTable() *BICTable {
+	return defaultBICTable()
+}
+
+// ParseBICTable reads a table in the format of the embedded banks.tsv, so
+// applications can load a newer list without a new release.
+//
+// Every line holds country, bank code, BIC and an optional bank name,
+// separated by tabs. Empty lines and lines starting with "#" are skipped.
+// All bank codes of a country must have the same length.
+func ParseBICTable(r io.Reader) (*BICTable, error) {
+	t := &BICTable{
+		banks:      make(map[string]Bank),
+		codeLength: make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		cols := strings.Split(text, "\t")
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("line %d: expected country, bank code and BIC", line)
+		}
+
+		b := Bank{
+			Country: strings.ToUpper(cols[0]),
+			Code:    strings.ToUpper(cols[1]),
+			BIC:     strings.ToUpper(cols[2]),
+		}
+		if len(cols) > 3 {
+			b.Name = cols[3]
+		}
+
+		if !IsValidBIC(b.BIC) {
+			return nil, fmt.Errorf("line %d: invalid BIC %q", line, b.BIC)
+		}
+		if n, ok := t.codeLength[b.Country]; ok && n != len(b.Code) {
+			return nil, fmt.Errorf("line %d: %s bank codes have %d characters, got %q", line, b.Country, n, b.Code)
+		}
+		t.codeLength[b.Country] = len(b.Code)
+		t.banks[b.Country+b.Code] = b
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Lookup returns the bank of a country and national bank code.
+func (t *BICTable) Lookup(country, code string) (Bank, bool) {
+	b, ok := t.banks[strings.ToUpper(country)+strings.ToUpper(code)]
+	return b, ok
+}
+
+// LookupIBAN returns the bank the IBAN belongs to. Spaces are ignored and
+// letters may be lower case; the IBAN itself is not validated.
+func (t *BICTable) LookupIBAN(iban string) (Bank, bool) {
+	iban = strings.ReplaceAll(strings.ToUpper(iban), " ", "")
+	if len(iban) < 4 {
+		return Bank{}, false
+	}
+
+	n, ok := t.codeLength[iban[:2]]
+	if !ok || len(iban) < 4+n {
+		return Bank{}, false
+	}
+	return t.Lookup(iban[:2], iban[4:4+n])
+}
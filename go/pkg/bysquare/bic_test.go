@@ -0,0 +1,57 @@
+package bysquare
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultBICTable(t *testing.T) {
+	tests := []struct {
+		iban string
+		bic  string
+	}{
+		{"SK9611000000002918599669", "TATRSKBX"},
+		{"sk31 1200 0000 1987 4263 7541", ""},
+		{"SK0809000000000123123123", "GIBASKBX"},
+		{"CZ6508000000192000145399", "GIBACZPX"},
+		{"CZ7001000000000000123457", "KOMBCZPP"},
+		{"DE89370400440532013000", ""},
+		{"SK", ""},
+	}
+
+	table := DefaultBICTable()
+	for _, tt := range tests {
+		t.Run(tt.iban, func(t *testing.T) {
+			bank, ok := table.LookupIBAN(tt.iban)
+			if ok != (tt.bic != "") || bank.BIC != tt.bic {
+				t.Errorf("expected %q, got %q (%v)", tt.bic, bank.BIC, ok)
+			}
+		})
+	}
+
+	bank, _ := table.Lookup("SK", "1100")
+	if !bank.MatchesBIC("tatrskbxxxx") || bank.MatchesBIC("GIBASKBX") || bank.MatchesBIC("TATR") {
+		t.Errorf("unexpected BIC matching for %+v", bank)
+	}
+}
+
+func TestParseBICTable(t *testing.T) {
+	table, err := ParseBICTable(strings.NewReader("# comment\n\nAT\t12000\tBKAUATWW\tUniCredit Bank Austria\n"))
+	if err != nil {
+		t.Fatalf("ParseBICTable() error: %v", err)
+	}
+	bank, ok := table.LookupIBAN("AT611200000234573201")
+	if !ok || bank.BIC != "BKAUATWW" || bank.Name != "UniCredit Bank Austria" {
+		t.Errorf("expected UniCredit Bank Austria, got %+v", bank)
+	}
+
+	for _, data := range []string{
+		"SK\t1100\n",
+		"SK\t1100\tTATR\n",
+		"SK\t1100\tTATRSKBX\nSK\t900\tGIBASKBX\n",
+	} {
+		if _, err := ParseBICTable(strings.NewReader(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package bysquare
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
+)
+
+// Plan describes how an encoded string fits into QR symbols.
+type Plan struct {
+	// Length is the number of characters of the QR string.
+	Length int `json:"length"`
+	// Versions holds the smallest QR version that holds the string, indexed
+	// by error correction level L, M, Q, H. Zero means even version 40 is
+	// too small.
+	Versions [qr.LevelH + 1]int `json:"versions"`
+	// Fields lists the non-empty payload fields, largest Cost first.
+	Fields []FieldSize `json:"fields"`
+}
+
+// Version returns the smallest QR version that holds the string at level,
+// or 0 if it does not fit.
+func (p *Plan) Version(level qr.Level) int {
+	if level > qr.LevelH {
+		return 0
+	}
+	return p.Versions[level]
+}
+
+// FieldSize is the contribution of one payload field to the QR string.
+type FieldSize struct {
+	// Index is the zero-based position of the field in the payload.
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	// Length is the sanitized value length in bytes.
+	Length int `json:"length"`
+	// Cost is the number of QR characters saved by leaving the field
+	// empty. Compression shares bytes between fields, so the costs do not
+	// add up to the total and can be zero or negative.
+	Cost int `json:"cost"`
+}
+
+// PlanPayload seals payload and reports its QR length, the minimal QR
+// version for every error correction level and the cost of each field.
+//
+// Each cost takes one extra Seal with the field emptied, so planning is
+// about as expensive as encoding once per non-empty field. opts are passed
+// to Seal.
+func PlanPayload(header BysquareHeader, payload string, opts ...SealOptions) (*Plan, error) {
+	sealed, err := Seal(header, payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Length: len(sealed)}
+	for level := qr.LevelL; level <= qr.LevelH; level++ {
+		plan.Versions[level] = qr.MinVersionFor(plan.Length, level)
+	}
+
+	values := strings.Split(payload, "\t")
+	names := fieldNames(header.BySquareType, values)
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+
+		values[i] = ""
+		without, err := Seal(header, strings.Join(values, "\t"), opts...)
+		values[i] = v
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Fields = append(plan.Fields, FieldSize{
+			Index:  i,
+			Name:   names[i],
+			Length: len(v),
+			Cost:   plan.Length - len(without),
+		})
+	}
+
+	sort.SliceStable(plan.Fields, func(a, b int) bool {
+		return plan.Fields[a].Cost > plan.Fields[b].Cost
+	})
+
+	return plan, nil
+}
+
+// FitPolicy selects what an encoder does with a string that exceeds
+// FitOptions.MaxVersion.
+type FitPolicy uint8
+
+const (
+	// FitError fails the encode with ErrCapacityExceeded.
+	FitError FitPolicy = iota
+	// FitTruncate shortens the free-text field of the document, keeping
+	// the longest prefix that fits: PaymentNote for PAY by square, last
+	// payment first, and InvoiceDescription for Invoice by square. The
+	// encode fails with ErrCapacityExceeded only if the string does not
+	// fit with the field empty.
+	FitTruncate
+)
+
+// FitOptions bounds the QR symbol an encoded string may need.
+type FitOptions struct {
+	// MaxVersion is the largest QR version allowed, 1-40. Zero disables
+	// the check.
+	MaxVersion int
+	// Level is the error correction level the symbol will be printed at.
+	Level qr.Level
+	// Policy is applied when the string does not fit.
+	Policy FitPolicy
+}
+
+// Check returns nil if qrString fits the configured version and level, and
+// an error wrapping ErrCapacityExceeded otherwise.
+func (o FitOptions) Check(qrString string) error {
+	if o.MaxVersion == 0 {
+		return nil
+	}
+	if o.MaxVersion < qr.MinVersion || o.MaxVersion > qr.MaxVersion {
+		return fmt.Errorf("invalid maximum QR version: %d", o.MaxVersion)
+	}
+	if o.Level > qr.LevelH {
+		return fmt.Errorf("invalid error correction level: %d", o.Level)
+	}
+
+	if capacity := qr.Capacity(o.MaxVersion, o.Level); len(qrString) > capacity {
+		return fmt.Errorf("%w: %d characters, version %d-%s holds %d",
+			ErrCapacityExceeded, len(qrString), o.MaxVersion, o.Level, capacity)
+	}
+	return nil
+}
+
+// Truncate finds the longest prefix of text, counted in runes, for which
+// seal returns a string that passes Check, and returns that string. It
+// returns an error wrapping ErrCapacityExceeded if even the empty text
+// does not fit.
+//
+// The search is a bisection, which assumes a longer text never seals
+// shorter. Compression can break that by a character or two, so the
+// prefix found may be slightly shorter than the longest possible one.
+func (o FitOptions) Truncate(text string, seal func(text string) (string, error)) (string, error) {
+	runes := []rune(text)
+
+	try := func(n int) (string, bool, error) {
+		sealed, err := seal(string(runes[:n]))
+		if err != nil {
+			return "", false, err
+		}
+		if err := o.Check(sealed); err != nil {
+			return sealed, false, err
+		}
+		return sealed, true, nil
+	}
+
+	best, ok, err := try(0)
+	if !ok {
+		return "", err
+	}
+
+	lo, hi := 0, len(runes)+1
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		sealed, ok, err := try(mid)
+		switch {
+		case ok:
+			lo, best = mid, sealed
+		case errors.Is(err, ErrCapacityExceeded):
+			hi = mid
+		default:
+			return "", err
+		}
+	}
+
+	return best, nil
+}
@@ -0,0 +1,66 @@
+package bysquare
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
+)
+
+func TestFitOptionsCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		options FitOptions
+		length  int
+		wantErr error
+		invalid bool
+	}{
+		{name: "disabled", options: FitOptions{}, length: 5000},
+		{name: "fits", options: FitOptions{MaxVersion: 1, Level: qr.LevelM}, length: 20},
+		{name: "exceeds", options: FitOptions{MaxVersion: 1, Level: qr.LevelM}, length: 21, wantErr: ErrCapacityExceeded},
+		{name: "version too large", options: FitOptions{MaxVersion: 41}, length: 1, invalid: true},
+		{name: "invalid level", options: FitOptions{MaxVersion: 1, Level: qr.LevelH + 1}, length: 1, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Check(strings.Repeat("0", tt.length))
+			switch {
+			case tt.invalid:
+				if err == nil || errors.Is(err, ErrCapacityExceeded) {
+					t.Errorf("expected invalid options error, got %v", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFitOptionsTruncate(t *testing.T) {
+	options := FitOptions{MaxVersion: 1, Level: qr.LevelL}
+	seal := func(text string) (string, error) {
+		return "PREFIX" + text, nil
+	}
+
+	got, err := options.Truncate("čšťabcdefghijklmnopqrstuvwxyz", seal)
+	if err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if expected := "PREFIXčšťabcdefghijklm"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	options.Level = qr.LevelH
+	long := func(text string) (string, error) {
+		return strings.Repeat("0", 11) + text, nil
+	}
+	if _, err := options.Truncate("abc", long); !errors.Is(err, ErrCapacityExceeded) {
+		t.Errorf("expected ErrCapacityExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/invoice"
+	"github.com/xseman/bysquare/go/pkg/bysquare/pay"
+)
+
+// codec adapts the pay and invoice packages to raw JSON.
+type codec struct {
+	bysquareType uint8
+	// encode encodes the JSON data model with the vector's options and
+	// the reference compression.
+	encode func(input []byte, v Vector) (string, error)
+	// decode decodes qr, strictly when asked, and returns the data model
+	// as JSON.
+	decode func(qr string, strict bool) ([]byte, error)
+	// reencode decodes qr and encodes the model again as is, without
+	// deburring or validation, with the default compression.
+	reencode func(qr string, v Vector) (string, error)
+	// canonical round-trips a JSON data model through the model type, so
+	// documents compare independent of key order and omitted zeros.
+	canonical func(data []byte) ([]byte, error)
+}
+
+var codecs = map[string]codec{
+	"pay": {
+		bysquareType: 0x00,
+		encode: func(input []byte, v Vector) (string, error) {
+			var model pay.DataModel
+			if err := unmarshalStrict(input, &model); err != nil {
+				return "", err
+			}
+			opts := pay.DefaultEncodeOptions()
+			opts.Deburr = v.Deburr
+			opts.Version = v.Version
+			opts.Compression = bysquare.CompressionReference
+			return pay.Encode(model, opts)
+		},
+		decode: func(qr string, strict bool) ([]byte, error) {
+			model, err := pay.Decode(qr, pay.DecodeOptions{Strict: strict})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(model)
+		},
+		reencode: func(qr string, v Vector) (string, error) {
+			model, err := pay.Decode(qr)
+			if err != nil {
+				return "", err
+			}
+			return pay.Encode(model, pay.EncodeOptions{Version: v.Version})
+		},
+		canonical: func(data []byte) ([]byte, error) {
+			var model pay.DataModel
+			if err := unmarshalStrict(data, &model); err != nil {
+				return nil, err
+			}
+			return json.Marshal(model)
+		},
+	},
+	"invoice": {
+		bysquareType: 0x01,
+		encode: func(input []byte, v Vector) (string, error) {
+			var model invoice.DataModel
+			if err := unmarshalStrict(input, &model); err != nil {
+				return "", err
+			}
+			opts := invoice.DefaultEncodeOptions()
+			opts.Version = v.Version
+			opts.Compression = bysquare.CompressionReference
+			return invoice.Encode(&model, opts)
+		},
+		decode: func(qr string, strict bool) ([]byte, error) {
+			model, err := invoice.Decode(qr, invoice.DecodeOptions{Strict: strict})
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(model)
+		},
+		reencode: func(qr string, v Vector) (string, error) {
+			model, err := invoice.Decode(qr)
+			if err != nil {
+				return "", err
+			}
+			return invoice.Encode(model, invoice.EncodeOptions{Version: v.Version})
+		},
+		canonical: func(data []byte) ([]byte, error) {
+			var model invoice.DataModel
+			if err := unmarshalStrict(data, &model); err != nil {
+				return nil, err
+			}
+			return json.Marshal(model)
+		},
+	},
+}
+
+// Check runs v through the matching package and returns all mismatches
+// joined into one error:
+//
+//  1. input encodes to a string that opens to Payload
+//  2. that string equals QR, whichever implementation encoded it
+//  3. QR opens to Payload with the vector's header version
+//  4. QR strictly decodes to Decoded
+//  5. the model decoded from QR re-encodes to Payload
+//
+// Steps 1 and 2 use bysquare.CompressionReference, which compresses like
+// the TypeScript implementation; step 5 uses the default compression.
+// Decode-only vectors skip steps 1, 2 and 5 and decode without Strict.
+func Check(v Vector) error {
+	c, ok := codecs[v.Type]
+	if !ok {
+		return fmt.Errorf("unknown vector type %q", v.Type)
+	}
+
+	var errs []error
+	fail := func(layer string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %w", layer, err))
+	}
+	mismatch := func(layer string, expected, got any) {
+		errs = append(errs, fmt.Errorf("%s: expected %q, got %q", layer, expected, got))
+	}
+
+	if !v.DecodeOnly {
+		encoded, err := c.encode(v.Input, v)
+		if err != nil {
+			fail("encode", err)
+		} else {
+			_, payload, err := bysquare.Open(encoded, c.bysquareType)
+			switch {
+			case err != nil:
+				fail("encode", err)
+			case payload != v.Payload:
+				mismatch("encode payload", v.Payload, payload)
+			}
+			if encoded != v.QR {
+				mismatch("encode qr", v.QR, encoded)
+			}
+		}
+	}
+
+	header, payload, err := bysquare.Open(v.QR, c.bysquareType)
+	switch {
+	case err != nil:
+		fail("open", err)
+	case payload != v.Payload:
+		mismatch("open payload", v.Payload, payload)
+	case header.Version != uint8(v.Version):
+		mismatch("open version", fmt.Sprint(v.Version), fmt.Sprint(header.Version))
+	}
+
+	expected, err := c.canonical(v.Decoded)
+	if err != nil {
+		fail("decoded", err)
+		return errors.Join(errs...)
+	}
+	decoded, err := c.decode(v.QR, !v.DecodeOnly)
+	switch {
+	case err != nil:
+		fail("decode", err)
+	case !bytes.Equal(decoded, expected):
+		mismatch("decode", expected, decoded)
+	}
+
+	if v.DecodeOnly {
+		return errors.Join(errs...)
+	}
+
+	reencoded, err := c.reencode(v.QR, v)
+	if err == nil {
+		_, payload, err = bysquare.Open(reencoded, c.bysquareType)
+	}
+	switch {
+	case err != nil:
+		fail("reencode", err)
+	case payload != v.Payload:
+		mismatch("reencode payload", v.Payload, payload)
+	}
+
+	return errors.Join(errs...)
+}
+
+// unmarshalStrict rejects unknown keys, so a misspelled field in the
+// corpus fails instead of silently encoding as empty.
+func unmarshalStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,94 @@
+// Package conformance checks the pay and invoice packages against the
+// cross-implementation corpus shared with the TypeScript library.
+//
+// The corpus lives in conformance/ at the repository root, one directory
+// per format version. Every vector fixes one document at each layer of
+// the pipeline:
+//
+//	input JSON -> tab payload -> QR string -> decoded JSON
+//
+// The payload is the contract between implementations and must match byte
+// for byte. LZMA leaves the encoder free to choose among equally valid
+// streams, so the corpus fixes the one of the LZMA SDK encoder that the
+// TypeScript library runs. Encoding with bysquare.CompressionReference
+// reproduces every QR string exactly, whichever implementation produced it,
+// except for decode-only vectors, which keep QR strings of older encoders
+// that no longer re-encode to the same payload.
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+// FormatVersion is the corpus format this package reads.
+const FormatVersion = 1
+
+// Corpus is one file of the corpus.
+type Corpus struct {
+	// Format is the corpus format version, see FormatVersion.
+	Format  int      `json:"format"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vector is a single conformance case.
+type Vector struct {
+	Name string `json:"name"`
+	// Type is "pay" or "invoice".
+	Type string `json:"type"`
+	// Version is the header version the input is encoded with.
+	Version bysquare.Version `json:"version"`
+	// Deburr enables diacritics removal when encoding PAY by square.
+	Deburr bool `json:"deburr,omitempty"`
+	// Encoder names the implementation that produced QR, "go" or
+	// "typescript".
+	Encoder string `json:"encoder"`
+	// DecodeOnly marks a QR string that is not in the canonical layout of
+	// its version, such as the 1.0.0 codes of older TypeScript releases,
+	// which carry a beneficiary block. It is only opened and decoded
+	// without Strict, and Input is left out.
+	DecodeOnly bool `json:"decodeOnly,omitempty"`
+
+	// Input is the data model to encode, in the JSON form of this module.
+	Input json.RawMessage `json:"input,omitempty"`
+	// Payload is the tab-separated payload, without the CRC32.
+	Payload string `json:"payload"`
+	// QR is the base32hex QR string.
+	QR string `json:"qr"`
+	// Decoded is the data model QR decodes to.
+	Decoded json.RawMessage `json:"decoded"`
+}
+
+// Load reads every *.json file of fsys, in name order, and returns their
+// vectors. Files in a format other than FormatVersion are rejected.
+func Load(fsys fs.FS) ([]Vector, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var vectors []Vector
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var corpus Corpus
+		if err := json.Unmarshal(data, &corpus); err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+		}
+		if corpus.Format != FormatVersion {
+			return nil, fmt.Errorf("%s: unsupported corpus format %d", path.Base(name), corpus.Format)
+		}
+		vectors = append(vectors, corpus.Vectors...)
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,150 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+// corpusDir is the current corpus version, relative to this package.
+const corpusDir = "../../../../conformance/v1"
+
+func TestCorpus(t *testing.T) {
+	vectors, err := Load(os.DirFS(corpusDir))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(vectors) == 0 {
+		t.Fatal("expected vectors in corpus")
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range vectors {
+		if seen[v.Name] {
+			t.Errorf("duplicate vector name %q", v.Name)
+		}
+		seen[v.Name] = true
+
+		t.Run(v.Name, func(t *testing.T) {
+			if err := Check(v); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCorpusCoverage(t *testing.T) {
+	vectors, err := Load(os.DirFS(corpusDir))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	covered := make(map[string]bool)
+	for _, v := range vectors {
+		input := string(v.Input)
+		switch v.Type {
+		case "pay":
+			covered["pay version "+v.Version.String()] = true
+			if v.Encoder == "typescript" {
+				covered["typescript pay version "+v.Version.String()] = true
+				var model struct {
+					Payments []struct {
+						Type int `json:"type"`
+					} `json:"payments"`
+				}
+				if err := json.Unmarshal(v.Decoded, &model); err != nil {
+					t.Fatalf("%s: %v", v.Name, err)
+				}
+				for _, p := range model.Payments {
+					covered[fmt.Sprintf("typescript pay type %d", p.Type)] = true
+				}
+			}
+			for _, ext := range []string{"standingOrderExt", "directDebitExt"} {
+				if strings.Contains(input, ext) {
+					covered["pay "+ext] = true
+				}
+			}
+		case "invoice":
+			header, _, err := bysquare.Open(v.QR, 0x01)
+			if err != nil {
+				t.Fatalf("%s: %v", v.Name, err)
+			}
+			covered[fmt.Sprintf("invoice documentType %d", header.DocumentType)] = true
+			if v.Encoder == "typescript" {
+				covered[fmt.Sprintf("typescript invoice documentType %d", header.DocumentType)] = true
+			}
+		}
+		if v.Encoder == "typescript" {
+			covered["typescript qr"] = true
+		}
+	}
+
+	for _, want := range []string{
+		"pay version 1.0.0", "pay version 1.1.0", "pay version 1.2.0",
+		"pay standingOrderExt", "pay directDebitExt",
+		"invoice documentType 0", "invoice documentType 1", "invoice documentType 2",
+		"invoice documentType 3", "invoice documentType 4",
+		"typescript qr", "typescript pay version 1.0.0", "typescript pay version 1.1.0",
+		"typescript pay version 1.2.0",
+		"typescript pay type 1", "typescript pay type 2", "typescript pay type 4",
+		"typescript invoice documentType 0", "typescript invoice documentType 1",
+		"typescript invoice documentType 2", "typescript invoice documentType 3",
+		"typescript invoice documentType 4",
+	} {
+		if !covered[want] {
+			t.Errorf("corpus does not cover %s", want)
+		}
+	}
+}
+
+func TestCheckReportsMismatch(t *testing.T) {
+	vectors, err := Load(os.DirFS(corpusDir))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	v := vectors[0]
+	v.Payload += "x"
+	err = Check(v)
+	if err == nil {
+		t.Fatal("expected mismatch for altered payload")
+	}
+	for _, layer := range []string{"encode payload", "open payload"} {
+		if !strings.Contains(err.Error(), layer) {
+			t.Errorf("expected %q in %v", layer, err)
+		}
+	}
+}
+
+func TestCheckDecodeOnly(t *testing.T) {
+	vectors, err := Load(os.DirFS(corpusDir))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for _, v := range vectors {
+		if !v.DecodeOnly {
+			continue
+		}
+		v.DecodeOnly = false
+		if err := Check(v); err == nil || !strings.Contains(err.Error(), "decode") {
+			t.Errorf("%s: expected strict decode to fail, got %v", v.Name, err)
+		}
+		return
+	}
+	t.Fatal("expected a decode-only vector in corpus")
+}
+
+func TestLoadRejectsFormat(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.json": {Data: []byte(`{"format": 2, "vectors": []}`)},
+	}
+	if _, err := Load(fsys); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
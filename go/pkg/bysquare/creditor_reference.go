@@ -0,0 +1,84 @@
+package bysquare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCreditorReference is the longest reference part of an RF creditor
+// reference.
+const maxCreditorReference = 21
+
+// CreditorReference builds an ISO 11649 RF creditor reference from ref by
+// computing its check digits. Spaces are ignored and letters may be lower
+// case. Errors wrap ErrCreditorReference.
+//
+//	+--------+--------+-----------------------------------------+
+//	| Part   | Length | Characters                              |
+//	+--------+--------+-----------------------------------------+
+//	| Prefix | 2      | "RF"                                    |
+//	| Check  | 2      | digits, ISO 7064 MOD 97-10 as for IBANs |
+//	| Ref    | 1-21   | letters and digits                      |
+//	+--------+--------+-----------------------------------------+
+//
+// The result is the electronic form without spaces; FormatCreditorReference
+// gives the printed form.
+func CreditorReference(ref string) (string, error) {
+	ref = strings.ReplaceAll(strings.ToUpper(ref), " ", "")
+	if err := checkReference(ref); err != nil {
+		return "", err
+	}
+
+	check := 98 - mod97(ref+"RF00")
+	return fmt.Sprintf("RF%02d%s", check, ref), nil
+}
+
+// ValidateCreditorReference checks the structure and check digits of an RF
+// creditor reference. Spaces are ignored and letters may be lower case.
+// Errors wrap ErrCreditorReference.
+func ValidateCreditorReference(s string) error {
+	s = strings.ReplaceAll(strings.ToUpper(s), " ", "")
+	if !strings.HasPrefix(s, "RF") {
+		return fmt.Errorf("%w: %q must start with RF", ErrCreditorReference, s)
+	}
+	if len(s) < 4 || !isDigit(s[2]) || !isDigit(s[3]) {
+		return fmt.Errorf("%w: %q must have two check digits after RF", ErrCreditorReference, s)
+	}
+	if err := checkReference(s[4:]); err != nil {
+		return err
+	}
+	if mod97(s[4:]+s[:4]) != 1 {
+		return fmt.Errorf("%w: check digits of %q do not match", ErrCreditorReference, s)
+	}
+	return nil
+}
+
+// FormatCreditorReference returns the printed form of an RF creditor
+// reference, upper case in groups of four characters. The reference is
+// not validated.
+func FormatCreditorReference(s string) string {
+	s = strings.ReplaceAll(strings.ToUpper(s), " ", "")
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i += 4 {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(s[i:min(i+4, len(s))])
+	}
+	return sb.String()
+}
+
+// checkReference checks the reference part of a creditor reference, which
+// must already be upper case.
+func checkReference(ref string) error {
+	if ref == "" || len(ref) > maxCreditorReference {
+		return fmt.Errorf("%w: reference %q must have 1 to %d characters", ErrCreditorReference, ref, maxCreditorReference)
+	}
+	for i := 0; i < len(ref); i++ {
+		if !isDigit(ref[i]) && !isUpper(ref[i]) {
+			return fmt.Errorf("%w: reference %q may only hold letters and digits", ErrCreditorReference, ref)
+		}
+	}
+	return nil
+}
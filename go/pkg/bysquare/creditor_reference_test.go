@@ -0,0 +1,87 @@
+package bysquare
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreditorReference(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"539007547034", "RF18539007547034"},
+		{"5390 0754 7034", "RF18539007547034"},
+		{"1", "RF741"},
+		{"abc123", "RF47ABC123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := CreditorReference(tt.ref)
+			if err != nil {
+				t.Fatalf("CreditorReference() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if err := ValidateCreditorReference(got); err != nil {
+				t.Errorf("expected a valid reference, got %v", err)
+			}
+		})
+	}
+
+	for _, ref := range []string{"", "1234567890123456789012", "INV-1"} {
+		if _, err := CreditorReference(ref); !errors.Is(err, ErrCreditorReference) {
+			t.Errorf("%q: expected ErrCreditorReference, got %v", ref, err)
+		}
+	}
+}
+
+func TestValidateCreditorReference(t *testing.T) {
+	tests := []struct {
+		ref   string
+		valid bool
+	}{
+		{"RF18539007547034", true},
+		{"RF18 5390 0754 7034", true},
+		{"rf18539007547034", true},
+		{"RF19539007547034", false},
+		{"RF18539007547035", false},
+		{"RF18", false},
+		{"RFAB539007547034", false},
+		{"RF18-539007547034", false},
+		{"RF181234567890123456789012", false},
+		{"SK18539007547034", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			err := ValidateCreditorReference(tt.ref)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrCreditorReference) {
+				t.Errorf("expected ErrCreditorReference, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFormatCreditorReference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"RF18539007547034", "RF18 5390 0754 7034"},
+		{"rf18 5390 07547034", "RF18 5390 0754 7034"},
+		{"RF741", "RF74 1"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := FormatCreditorReference(tt.in); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package bysquare
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxDecimalExponent bounds the exponent ParseDecimal expands, so a short
+// input such as "1e999999999" cannot allocate a huge string.
+const maxDecimalExponent = 1000
+
+// Decimal is an exact decimal number such as an amount, a rate or a
+// quantity, kept as its decimal text. The empty Decimal is zero; encoders
+// leave optional zero fields empty, as for float64 before.
+//
+// Values are exact: ParseDecimal followed by String returns canonical input
+// unchanged, trailing zeros included. Decimal can be assigned from string
+// constants, e.g. Amount: "100.25".
+//
+//	"12.50" -> payload "12.5"
+//	"-0.10" -> payload "-0.1"
+//	"1e2"   -> "100"
+type Decimal string
+
+// ParseDecimal parses decimal text with an optional sign, fraction and
+// exponent. The result is in canonical form: no exponent, no leading zeros
+// in the integer part and no sign on zero. Trailing zeros in the fraction
+// are kept. The empty string parses as zero.
+func ParseDecimal(s string) (Decimal, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	invalid := fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+
+	rest := s
+	neg := false
+	if rest[0] == '+' || rest[0] == '-' {
+		neg = rest[0] == '-'
+		rest = rest[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(rest, "eE"); i >= 0 {
+		e, err := strconv.Atoi(rest[i+1:])
+		if err != nil || e < -maxDecimalExponent || e > maxDecimalExponent {
+			return "", invalid
+		}
+		exp = e
+		rest = rest[:i]
+	}
+
+	intPart, fracPart, _ := strings.Cut(rest, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return "", invalid
+	}
+
+	// Move the decimal point by the exponent.
+	digits := intPart + fracPart
+	point := len(intPart) + exp
+	switch {
+	case point <= 0:
+		intPart, fracPart = "", strings.Repeat("0", -point)+digits
+	case point >= len(digits):
+		intPart, fracPart = digits+strings.Repeat("0", point-len(digits)), ""
+	default:
+		intPart, fracPart = digits[:point], digits[point:]
+	}
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	var sb strings.Builder
+	if neg && strings.Trim(intPart+fracPart, "0") != "" {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(intPart)
+	if fracPart != "" {
+		sb.WriteByte('.')
+		sb.WriteString(fracPart)
+	}
+
+	return Decimal(sb.String()), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecimalFromFloat converts f to the shortest decimal that reads back as
+// f. It is the compatibility path for callers still holding float64
+// amounts. NaN and infinities give a Decimal that Validate rejects.
+func DecimalFromFloat(f float64) Decimal {
+	return Decimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Validate returns an error wrapping ErrInvalidDecimal if d is not decimal
+// text.
+func (d Decimal) Validate() error {
+	_, err := ParseDecimal(string(d))
+	return err
+}
+
+// String returns the decimal text, "0" for the empty Decimal.
+func (d Decimal) String() string {
+	if d == "" {
+		return "0"
+	}
+	return string(d)
+}
+
+// IsZero reports whether d is zero. Invalid text is not zero.
+func (d Decimal) IsZero() bool {
+	r, ok := d.rat()
+	return ok && r.Sign() == 0
+}
+
+// Cmp compares d and other by value and returns -1, 0 or +1. Invalid text
+// compares as zero.
+func (d Decimal) Cmp(other Decimal) int {
+	a, _ := d.rat()
+	b, _ := other.rat()
+	return a.Cmp(b)
+}
+
+// Float64 returns the nearest float64, or 0 for invalid text.
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat()
+	v, _ := f.Float64()
+	return v
+}
+
+func (d Decimal) rat() (*big.Rat, bool) {
+	p, err := ParseDecimal(string(d))
+	if err != nil || p == "" {
+		return new(big.Rat), err == nil
+	}
+	r, ok := new(big.Rat).SetString(string(p))
+	if !ok {
+		return new(big.Rat), false
+	}
+	return r, true
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	p, err := ParseDecimal(string(d))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	p, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = p
+	return nil
+}
+
+// MarshalJSON writes d as a JSON number, so documents stay compatible with
+// the float64 model and the TypeScript library.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return d.MarshalText()
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one. Numbers are
+// read from their text, so 0.1 stays exactly 0.1. null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		return d.UnmarshalText([]byte(s))
+	}
+	return d.UnmarshalText(data)
+}
+
+// Value implements driver.Valuer. The decimal text suits NUMERIC and
+// DECIMAL columns without loss.
+func (d Decimal) Value() (driver.Value, error) {
+	text, err := d.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Scan implements sql.Scanner for text, integer and float columns. NULL
+// scans as zero.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = ""
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+		return nil
+	case float64:
+		return d.UnmarshalText([]byte(DecimalFromFloat(v)))
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+}
+
+// FormatDecimal formats d for the payload: trailing fractional zeros are
+// dropped and zero is omitted. Invalid text is returned with tabs replaced,
+// like other text fields, so it cannot shift the fields that follow;
+// validation must run first to reject it.
+func FormatDecimal(d Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return FormatDecimalRequired(d)
+}
+
+// FormatDecimalRequired formats d like FormatDecimal but writes zero as
+// "0". Use for required numeric fields where 0 is a valid value.
+func FormatDecimalRequired(d Decimal) string {
+	p, err := ParseDecimal(string(d))
+	if err != nil {
+		return Sanitize(string(d))
+	}
+	s := p.String()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	return s
+}
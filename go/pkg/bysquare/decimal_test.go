@@ -0,0 +1,195 @@
+package bysquare
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Decimal
+		wantErr  bool
+	}{
+		{input: "", expected: ""},
+		{input: "100", expected: "100"},
+		{input: "100.50", expected: "100.50"},
+		{input: "0.10", expected: "0.10"},
+		{input: "-12.5", expected: "-12.5"},
+		{input: "+7", expected: "7"},
+		{input: "007.5", expected: "7.5"},
+		{input: ".5", expected: "0.5"},
+		{input: "5.", expected: "5"},
+		{input: "-0.00", expected: "0.00"},
+		{input: "1e2", expected: "100"},
+		{input: "1.5E-3", expected: "0.0015"},
+		{input: "123456789012345678901234567890.123456789", expected: "123456789012345678901234567890.123456789"},
+		{input: ".", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "1,5", wantErr: true},
+		{input: "NaN", wantErr: true},
+		{input: "Inf", wantErr: true},
+		{input: "0x10", wantErr: true},
+		{input: "1e", wantErr: true},
+		{input: "1e99999", wantErr: true},
+		{input: " 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDecimal(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDecimal) {
+					t.Errorf("expected ErrInvalidDecimal, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		input    Decimal
+		optional string
+		required string
+	}{
+		{input: "", optional: "", required: "0"},
+		{input: "0.00", optional: "", required: "0"},
+		{input: "100", optional: "100", required: "100"},
+		{input: "100.50", optional: "100.5", required: "100.5"},
+		{input: "-0.10", optional: "-0.1", required: "-0.1"},
+		{input: "0.1234567891", optional: "0.1234567891", required: "0.1234567891"},
+		{input: "1e3", optional: "1000", required: "1000"},
+		{input: "1\t2", optional: "1 2", required: "1 2"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDecimal(tt.input); got != tt.optional {
+			t.Errorf("FormatDecimal(%q): expected %q, got %q", tt.input, tt.optional, got)
+		}
+		if got := FormatDecimalRequired(tt.input); got != tt.required {
+			t.Errorf("FormatDecimalRequired(%q): expected %q, got %q", tt.input, tt.required, got)
+		}
+	}
+}
+
+func TestDecimalCompare(t *testing.T) {
+	if Decimal("0.20").Cmp("0.2") != 0 {
+		t.Error("expected 0.20 == 0.2")
+	}
+	if Decimal("1.5").Cmp("1") <= 0 {
+		t.Error("expected 1.5 > 1")
+	}
+	if Decimal("-3").Cmp("") >= 0 {
+		t.Error("expected -3 < 0")
+	}
+	if !Decimal("").IsZero() || !Decimal("0.000").IsZero() || Decimal("abc").IsZero() {
+		t.Error("unexpected IsZero result")
+	}
+	if got := Decimal("123.45").Float64(); got != 123.45 {
+		t.Errorf("expected 123.45, got %v", got)
+	}
+}
+
+func TestDecimalFromFloat(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected Decimal
+	}{
+		{0, "0"},
+		{0.1, "0.1"},
+		{123.45, "123.45"},
+		{1e21, "1000000000000000000000"},
+		{-0.000001, "-0.000001"},
+	}
+
+	for _, tt := range tests {
+		if got := DecimalFromFloat(tt.input); got != tt.expected {
+			t.Errorf("DecimalFromFloat(%v): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if DecimalFromFloat(math.NaN()).Validate() == nil {
+		t.Error("expected NaN to be invalid")
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type doc struct {
+		Amount   Decimal `json:"amount,omitempty"`
+		Required Decimal `json:"required"`
+	}
+
+	var d doc
+	if err := json.Unmarshal([]byte(`{"amount": 0.10, "required": "1e2"}`), &d); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if d.Amount != "0.10" || d.Required != "100" {
+		t.Errorf("unexpected values: %+v", d)
+	}
+
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if expected := `{"amount":0.10,"required":100}`; string(out) != expected {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+
+	out, err = json.Marshal(doc{})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if expected := `{"required":0}`; string(out) != expected {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount": "abc"}`), &d); !errors.Is(err, ErrInvalidDecimal) {
+		t.Errorf("expected ErrInvalidDecimal, got %v", err)
+	}
+	if _, err := json.Marshal(doc{Amount: "abc"}); err == nil {
+		t.Error("expected error marshalling invalid decimal")
+	}
+}
+
+func TestDecimalSQL(t *testing.T) {
+	tests := []struct {
+		src      any
+		expected Decimal
+	}{
+		{nil, ""},
+		{"12.30", "12.30"},
+		{[]byte("-4.5"), "-4.5"},
+		{int64(42), "42"},
+		{float64(0.1), "0.1"},
+	}
+
+	for _, tt := range tests {
+		var d Decimal
+		if err := d.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v): %v", tt.src, err)
+		}
+		if d != tt.expected {
+			t.Errorf("Scan(%v): expected %q, got %q", tt.src, tt.expected, d)
+		}
+	}
+
+	var d Decimal
+	if err := d.Scan(true); err == nil {
+		t.Error("expected error scanning bool")
+	}
+
+	v, err := Decimal("12.30").Value()
+	if err != nil || v != "12.30" {
+		t.Errorf("expected 12.30, got %v (%v)", v, err)
+	}
+}
@@ -0,0 +1,18 @@
+package bysquare
+
+// Document is a decoded BySquare document.
+//
+// The built-in implementations are *pay.DataModel and *invoice.DataModel.
+// Packages registering a Codec for another bysquareType provide their own.
+//
+//	switch doc := doc.(type) {
+//	case *pay.DataModel:
+//	case *invoice.DataModel:
+//	}
+type Document interface {
+	// BysquareType returns the bysquareType header nibble of the document,
+	// 0x00 for PAY by square and 0x01 for Invoice by square.
+	//
+	// @see 3.5.
+	BysquareType() uint8
+}
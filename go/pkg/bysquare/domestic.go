@@ -0,0 +1,147 @@
+package bysquare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DomesticAccount is a Slovak or Czech account number in the domestic form
+// used before IBAN, "prefix-number/bank code", e.g. "19-2000145399/0800".
+// Both countries share the layout and the check:
+//
+//	+-----------+--------+-----------------------------------------+
+//	| Part      | Digits | Weighted mod-11 check (right aligned)   |
+//	+-----------+--------+-----------------------------------------+
+//	| Prefix    | 0-6    | 10, 5, 8, 4, 2, 1                       |
+//	| Number    | 2-10   | 6, 3, 7, 9, 10, 5, 8, 4, 2, 1           |
+//	| Bank code | 4      | -                                       |
+//	+-----------+--------+-----------------------------------------+
+//
+// The IBAN holds the bank code, the prefix padded to 6 digits and the
+// number padded to 10 digits.
+type DomesticAccount struct {
+	// Country is "SK" or "CZ".
+	Country string
+	// Prefix and Number are kept without leading zeros; Prefix is empty
+	// for accounts without one.
+	Prefix   string
+	Number   string
+	BankCode string
+}
+
+var (
+	prefixWeights = []int{10, 5, 8, 4, 2, 1}
+	numberWeights = []int{6, 3, 7, 9, 10, 5, 8, 4, 2, 1}
+)
+
+// ParseDomesticAccount parses a domestic account number of country, "SK"
+// or "CZ". Spaces are ignored. Errors wrap ErrDomesticAccount and name the
+// part at fault.
+func ParseDomesticAccount(country, s string) (DomesticAccount, error) {
+	country = strings.ToUpper(country)
+	if country != "SK" && country != "CZ" {
+		return DomesticAccount{}, fmt.Errorf("%w: unsupported country %q", ErrDomesticAccount, country)
+	}
+
+	s = strings.ReplaceAll(s, " ", "")
+	account, bankCode, ok := strings.Cut(s, "/")
+	if !ok {
+		return DomesticAccount{}, fmt.Errorf("%w: missing bank code", ErrDomesticAccount)
+	}
+	prefix, number, ok := strings.Cut(account, "-")
+	if !ok {
+		prefix, number = "", account
+	}
+
+	a := DomesticAccount{
+		Country:  country,
+		Prefix:   strings.TrimLeft(prefix, "0"),
+		Number:   strings.TrimLeft(number, "0"),
+		BankCode: bankCode,
+	}
+	if err := a.validate(prefix, number); err != nil {
+		return DomesticAccount{}, err
+	}
+	return a, nil
+}
+
+// DomesticAccountFromIBAN converts a Slovak or Czech IBAN back to the
+// domestic form. The IBAN must pass ValidateIBAN and the mod-11 checks.
+func DomesticAccountFromIBAN(iban string) (DomesticAccount, error) {
+	if err := ValidateIBAN(iban); err != nil {
+		return DomesticAccount{}, err
+	}
+
+	iban = strings.ReplaceAll(strings.ToUpper(iban), " ", "")
+	country := iban[:2]
+	if country != "SK" && country != "CZ" {
+		return DomesticAccount{}, fmt.Errorf("%w: unsupported country %q", ErrDomesticAccount, country)
+	}
+
+	prefix, number := iban[8:14], iban[14:24]
+	a := DomesticAccount{
+		Country:  country,
+		Prefix:   strings.TrimLeft(prefix, "0"),
+		Number:   strings.TrimLeft(number, "0"),
+		BankCode: iban[4:8],
+	}
+	if err := a.validate(prefix, number); err != nil {
+		return DomesticAccount{}, err
+	}
+	return a, nil
+}
+
+// validate checks the raw prefix and number and the bank code of a.
+func (a DomesticAccount) validate(prefix, number string) error {
+	if len(a.BankCode) != 4 || !isDigits(a.BankCode) {
+		return fmt.Errorf("%w: bank code %q must have 4 digits", ErrDomesticAccount, a.BankCode)
+	}
+	if len(prefix) > 6 || prefix != "" && !isDigits(prefix) {
+		return fmt.Errorf("%w: prefix %q must have at most 6 digits", ErrDomesticAccount, prefix)
+	}
+	if len(number) > 10 || !isDigits(number) {
+		return fmt.Errorf("%w: number %q must have 2 to 10 digits", ErrDomesticAccount, number)
+	}
+	if nonZero := len(strings.ReplaceAll(number, "0", "")); nonZero < 2 {
+		return fmt.Errorf("%w: number %q must have at least 2 non-zero digits", ErrDomesticAccount, number)
+	}
+	if !mod11(a.Prefix, prefixWeights) {
+		return fmt.Errorf("%w: prefix %q fails the mod-11 check", ErrDomesticAccount, prefix)
+	}
+	if !mod11(a.Number, numberWeights) {
+		return fmt.Errorf("%w: number %q fails the mod-11 check", ErrDomesticAccount, number)
+	}
+	return nil
+}
+
+// IBAN returns the account as an IBAN.
+func (a DomesticAccount) IBAN() string {
+	bban := a.BankCode + pad(a.Prefix, 6) + pad(a.Number, 10)
+	check := 98 - mod97(bban+a.Country+"00")
+	return fmt.Sprintf("%s%02d%s", a.Country, check, bban)
+}
+
+// String returns the domestic form, "prefix-number/bank code", leaving out
+// an empty prefix.
+func (a DomesticAccount) String() string {
+	if a.Prefix == "" {
+		return a.Number + "/" + a.BankCode
+	}
+	return a.Prefix + "-" + a.Number + "/" + a.BankCode
+}
+
+// mod11 reports whether the digits of s, right aligned to the weights,
+// have a weighted sum divisible by 11.
+func mod11(s string, weights []int) bool {
+	sum := 0
+	offset := len(weights) - len(s)
+	for i := 0; i < len(s); i++ {
+		sum += int(s[i]-'0') * weights[offset+i]
+	}
+	return sum%11 == 0
+}
+
+// pad left-pads s with zeros to n digits.
+func pad(s string, n int) string {
+	return strings.Repeat("0", n-len(s)) + s
+}
@@ -0,0 +1,80 @@
+package bysquare
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDomesticAccount(t *testing.T) {
+	tests := []struct {
+		country  string
+		domestic string
+		iban     string
+		str      string
+	}{
+		{"CZ", "19-2000145399/0800", "CZ6508000000192000145399", "19-2000145399/0800"},
+		{"SK", "19-8742637541/1200", "SK3112000000198742637541", "19-8742637541/1200"},
+		{"sk", "000019-8742637541/1200", "SK3112000000198742637541", "19-8742637541/1200"},
+		{"SK", "2918599669/1100", "SK9611000000002918599669", "2918599669/1100"},
+		{"CZ", "123457 / 0100", "CZ7001000000000000123457", "123457/0100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domestic, func(t *testing.T) {
+			a, err := ParseDomesticAccount(tt.country, tt.domestic)
+			if err != nil {
+				t.Fatalf("ParseDomesticAccount() error: %v", err)
+			}
+			if got := a.IBAN(); got != tt.iban {
+				t.Errorf("expected IBAN %s, got %s", tt.iban, got)
+			}
+			if err := ValidateIBAN(a.IBAN()); err != nil {
+				t.Errorf("expected a valid IBAN, got %v", err)
+			}
+
+			back, err := DomesticAccountFromIBAN(tt.iban)
+			if err != nil {
+				t.Fatalf("DomesticAccountFromIBAN() error: %v", err)
+			}
+			if back != a {
+				t.Errorf("expected %+v, got %+v", a, back)
+			}
+			if got := back.String(); got != tt.str {
+				t.Errorf("expected %s, got %s", tt.str, got)
+			}
+		})
+	}
+}
+
+func TestParseDomesticAccountErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		country  string
+		domestic string
+	}{
+		{"unsupported country", "AT", "19-2000145399/0800"},
+		{"missing bank code", "CZ", "19-2000145399"},
+		{"short bank code", "CZ", "19-2000145399/800"},
+		{"long prefix", "CZ", "1234567-2000145399/0800"},
+		{"long number", "CZ", "12000145399/0800"},
+		{"letters", "CZ", "19-20001453A9/0800"},
+		{"one non-zero digit", "CZ", "0000000001/0800"},
+		{"prefix check", "CZ", "18-2000145399/0800"},
+		{"number check", "SK", "19-8742637542/1200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDomesticAccount(tt.country, tt.domestic); !errors.Is(err, ErrDomesticAccount) {
+				t.Errorf("expected ErrDomesticAccount, got %v", err)
+			}
+		})
+	}
+
+	if _, err := DomesticAccountFromIBAN("AT611904300234573201"); !errors.Is(err, ErrDomesticAccount) {
+		t.Errorf("expected ErrDomesticAccount for an Austrian IBAN, got %v", err)
+	}
+	if _, err := DomesticAccountFromIBAN("SK3112000000198742637542"); !errors.Is(err, ErrIBANChecksum) {
+		t.Errorf("expected ErrIBANChecksum, got %v", err)
+	}
+}
@@ -1,8 +1,147 @@
 package bysquare
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrInvalidBase32Hex indicates an invalid Base32Hex string.
 	ErrInvalidBase32Hex = errors.New("invalid base32hex string")
+
+	// ErrChecksumMismatch indicates that the CRC32 stored in the payload
+	// does not match the decompressed data, i.e. the QR string is corrupted.
+	ErrChecksumMismatch = errors.New("CRC32 checksum mismatch")
+
+	// ErrUnsupportedVersion indicates a header version newer than this
+	// implementation understands.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+
+	// ErrUnexpectedType indicates a header bysquareType that does not match
+	// the decoder, e.g. an Invoice by square string passed to pay.Decode.
+	ErrUnexpectedType = errors.New("unexpected bysquareType")
+
+	// ErrTruncatedPayload indicates that the data ends before all required
+	// bytes or fields were read.
+	ErrTruncatedPayload = errors.New("truncated payload")
+
+	// ErrInvalidHeader indicates a header field that does not fit in its
+	// 4-bit nibble.
+	ErrInvalidHeader = errors.New("invalid header")
+
+	// ErrPayloadTooLarge indicates a payload whose length does not fit the
+	// 2-byte length field.
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrTrailingData indicates compressed bytes left over after the
+	// declared payload size was decompressed.
+	ErrTrailingData = errors.New("trailing data")
+
+	// ErrNonCanonical indicates data that decodes but differs from what a
+	// conforming encoder produces. Only strict decoding reports it.
+	ErrNonCanonical = errors.New("non-canonical encoding")
+
+	// ErrInvalidDecimal indicates text that is not a decimal number.
+	ErrInvalidDecimal = errors.New("invalid decimal")
+
+	// ErrCapacityExceeded indicates an encoded string that needs a larger
+	// QR version than FitOptions.MaxVersion allows.
+	ErrCapacityExceeded = errors.New("QR capacity exceeded")
+
+	// ErrIBANCountry indicates an IBAN without a country code, or with
+	// one that has no registered IBAN format.
+	ErrIBANCountry = errors.New("unknown IBAN country")
+
+	// ErrIBANLength indicates an IBAN whose length differs from the one
+	// registered for its country.
+	ErrIBANLength = errors.New("wrong IBAN length")
+
+	// ErrIBANFormat indicates an IBAN with a character its country's
+	// BBAN structure does not allow at that position.
+	ErrIBANFormat = errors.New("malformed IBAN")
+
+	// ErrIBANChecksum indicates an IBAN whose check digits do not match.
+	ErrIBANChecksum = errors.New("wrong IBAN check digits")
+
+	// ErrDomesticAccount indicates a Slovak or Czech domestic account
+	// number that is malformed or fails its mod-11 check.
+	ErrDomesticAccount = errors.New("invalid domestic account number")
+
+	// ErrBICMismatch indicates a BIC that belongs to another bank than
+	// the IBAN it is given with.
+	ErrBICMismatch = errors.New("BIC does not match IBAN")
+
+	// ErrSymbol indicates a variable, constant or specific symbol that is
+	// not numeric or has too many digits.
+	ErrSymbol = errors.New("invalid payment symbol")
+
+	// ErrConstantSymbol indicates a well-formed constant symbol that is
+	// missing from the embedded list. Only the opt-in check of
+	// pay.ValidateOptions.ListedConstantSymbols reports it.
+	ErrConstantSymbol = errors.New("unknown constant symbol")
+
+	// ErrCreditorReference indicates a malformed ISO 11649 RF creditor
+	// reference or one whose check digits do not match.
+	ErrCreditorReference = errors.New("invalid creditor reference")
+
+	// ErrLenientStrict indicates decode options that request both lenient
+	// and strict decoding.
+	ErrLenientStrict = errors.New("lenient and strict decoding are mutually exclusive")
 )
+
+// FieldError reports a problem with a single field of the tab-separated
+// payload.
+//
+// It wraps the underlying cause, which is ErrTruncatedPayload for missing
+// fields, ErrInvalidDecimal for malformed amounts and a strconv error for
+// other malformed numbers.
+type FieldError struct {
+	// Index is the zero-based position of the field in the payload.
+	Index int
+	// Name is the path of the field in the data model, for example
+	// "payments[0].bankAccounts".
+	Name string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %d (%s): %v", e.Index, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Warnings lists the problems a lenient decode recovered from. A lenient
+// decode returns it as the error alongside a best-effort data model. The
+// entries can be matched with errors.Is and errors.As.
+//
+//	model, err := pay.Decode(qr, pay.DecodeOptions{Lenient: true})
+//	var warnings bysquare.Warnings
+//	if errors.As(err, &warnings) {
+//		// model holds the recovered data
+//	}
+type Warnings []error
+
+func (w Warnings) Error() string {
+	switch len(w) {
+	case 0:
+		return "no warnings"
+	case 1:
+		return w[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more warnings)", w[0], len(w)-1)
+	}
+}
+
+func (w Warnings) Unwrap() []error {
+	return w
+}
+
+// Err returns w as an error, or nil when it is empty.
+func (w Warnings) Err() error {
+	if len(w) == 0 {
+		return nil
+	}
+	return w
+}
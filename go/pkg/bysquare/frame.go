@@ -0,0 +1,205 @@
+package bysquare
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Frame is the binary envelope shared by every BySquare type.
+//
+//	+------------------+------------------+-----------------------------+
+//	|     2 bytes      |     2 bytes      |          Variable           |
+//	+------------------+------------------+-----------------------------+
+//	| Bysquare Header  | Payload Length   |         LZMA Body           |
+//	| (4 nibbles)      | (little-endian)  |  (compressed CRC+payload)   |
+//	+------------------+------------------+-----------------------------+
+//
+// Its text form is the base32hex QR string.
+//
+// @see 3.16.
+type Frame struct {
+	Header BysquareHeader
+	// Length is the declared size of the decompressed CRC32 and payload.
+	Length int
+	// Body is the raw LZMA stream without the 13-byte LZMA header.
+	Body []byte
+}
+
+// MarshalText encodes the frame as a base32hex QR string.
+func (f Frame) MarshalText() ([]byte, error) {
+	header, err := f.Header.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	length, err := EncodePayloadLength(f.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, 0, len(header)+len(length)+len(f.Body))
+	output = append(output, header...)
+	output = append(output, length...)
+	output = append(output, f.Body...)
+
+	return []byte(EncodeBase32Hex(output, false)), nil
+}
+
+// UnmarshalText decodes a base32hex QR string into the frame.
+func (f *Frame) UnmarshalText(text []byte) error {
+	bytes, err := DecodeBase32Hex(string(text), true)
+	if err != nil {
+		return fmt.Errorf("base32hex decode failed: %w", err)
+	}
+
+	if len(bytes) < 4 {
+		return fmt.Errorf("%w: need at least 4 bytes, got %d", ErrTruncatedPayload, len(bytes))
+	}
+
+	if err := f.Header.UnmarshalBinary(bytes[0:2]); err != nil {
+		return err
+	}
+	f.Length = int(binary.LittleEndian.Uint16(bytes[2:4]))
+	f.Body = bytes[4:]
+
+	return nil
+}
+
+// Compression selects the LZMA encoder of Seal. Both write valid streams
+// of the bysquare profile that every decoder reads.
+type Compression uint8
+
+const (
+	// CompressionSmallest compresses with CompressLZMA, which gives the
+	// shortest QR strings. It is the default.
+	CompressionSmallest Compression = iota
+	// CompressionReference compresses with CompressLZMAReference and
+	// reproduces the QR strings of the TypeScript implementation.
+	CompressionReference
+)
+
+// SealOptions configures Seal.
+type SealOptions struct {
+	// Compression selects the LZMA encoder.
+	Compression Compression
+}
+
+// Seal runs the shared encoding pipeline on a serialized payload and
+// returns the QR string:
+//
+//	payload -> CRC32 -> LZMA -> strip LZMA header -> frame -> base32hex
+//
+// @see 3.16.
+func Seal(header BysquareHeader, payload string, opts ...SealOptions) (string, error) {
+	var options SealOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	checked := AddChecksum(payload)
+	if _, err := EncodePayloadLength(len(checked)); err != nil {
+		return "", err
+	}
+
+	compress := CompressLZMA
+	if options.Compression == CompressionReference {
+		compress = CompressLZMAReference
+	}
+	compressed, err := compress(checked)
+	if err != nil {
+		return "", fmt.Errorf("LZMA compression failed: %w", err)
+	}
+
+	if len(compressed) < 13 {
+		return "", fmt.Errorf("compressed payload too short")
+	}
+
+	frame := Frame{
+		Header: header,
+		Length: len(checked),
+		Body:   compressed[13:],
+	}
+
+	text, err := frame.MarshalText()
+	if err != nil {
+		return "", err
+	}
+
+	return string(text), nil
+}
+
+// OpenOptions configures Open.
+type OpenOptions struct {
+	// Lenient reports checksum mismatches, unsupported versions and
+	// trailing data as Warnings, returned with the payload, instead of
+	// failing.
+	Lenient bool
+
+	// Limits bounds the input length and the decompressed size.
+	Limits Limits
+}
+
+// Open is the inverse of Seal. It decodes a QR string, checks that the
+// header carries the expected bysquareType and a supported version, and
+// returns the header with the checksum-verified payload.
+//
+// @see 3.16.
+func Open(qr string, bysquareType uint8, opts ...OpenOptions) (BysquareHeader, string, error) {
+	var options OpenOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if max := options.Limits.MaxInputLength; max > 0 && len(qr) > max {
+		return BysquareHeader{}, "", fmt.Errorf("%w: input length %d exceeds limit %d", ErrPayloadTooLarge, len(qr), max)
+	}
+
+	var frame Frame
+	if err := frame.UnmarshalText([]byte(qr)); err != nil {
+		return BysquareHeader{}, "", err
+	}
+
+	var warnings Warnings
+	warn := func(err error) error {
+		if !options.Lenient {
+			return err
+		}
+		warnings = append(warnings, err)
+		return nil
+	}
+
+	header := frame.Header
+	if header.BySquareType != bysquareType {
+		return header, "", fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedType, bysquareType, header.BySquareType)
+	}
+
+	if Version(header.Version) > Version120 {
+		if err := warn(fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)); err != nil {
+			return header, "", err
+		}
+	}
+
+	decompressed, err := DecompressLZMA(frame.Body, frame.Length, options.Limits)
+	if errors.Is(err, ErrTrailingData) {
+		err = warn(err)
+	}
+	if err != nil {
+		return header, "", fmt.Errorf("LZMA decompression failed: %w", err)
+	}
+
+	if len(decompressed) < 4 {
+		return header, "", fmt.Errorf("%w: decompressed data too short for checksum", ErrTruncatedPayload)
+	}
+
+	stored := binary.LittleEndian.Uint32(decompressed[:4])
+	payload := string(decompressed[4:])
+
+	computed := Crc32Checksum(payload)
+	if stored != computed {
+		if err := warn(fmt.Errorf("%w: stored=%d computed=%d", ErrChecksumMismatch, stored, computed)); err != nil {
+			return header, "", err
+		}
+	}
+
+	return header, payload, warnings.Err()
+}
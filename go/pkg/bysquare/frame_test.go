@@ -0,0 +1,212 @@
+package bysquare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestFrameTextRoundTrip(t *testing.T) {
+	frame := Frame{
+		Header: BysquareHeader{BySquareType: 0x01, Version: 0x02, DocumentType: 0x03, Reserved: 0x00},
+		Length: 300,
+		Body:   []byte{0xDE, 0xAD, 0xBE, 0xEF},
+	}
+
+	text, err := frame.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error: %v", err)
+	}
+
+	var decoded Frame
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText() error: %v", err)
+	}
+
+	if decoded.Header != frame.Header {
+		t.Errorf("expected header %+v, got %+v", frame.Header, decoded.Header)
+	}
+	if decoded.Length != frame.Length {
+		t.Errorf("expected length %d, got %d", frame.Length, decoded.Length)
+	}
+	if !bytes.Equal(decoded.Body, frame.Body) {
+		t.Errorf("expected body %x, got %x", frame.Body, decoded.Body)
+	}
+}
+
+func TestFrameMarshalTextInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		frame Frame
+	}{
+		{"header nibble overflow", Frame{Header: BysquareHeader{Version: 0x10}}},
+		{"negative length", Frame{Length: -1}},
+		{"length too large", Frame{Length: MaxCompressedSize}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.frame.MarshalText(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFrameUnmarshalTextInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"invalid base32hex", "!!!"},
+		{"too short", EncodeBase32Hex([]byte{0x00, 0x00}, false)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var frame Frame
+			if err := frame.UnmarshalText([]byte(tc.input)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	header := BysquareHeader{BySquareType: 0x01, Version: 0x00, DocumentType: 0x02}
+	payload := "INV-001\t20240101\tEUR"
+
+	qr, err := Seal(header, payload)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	gotHeader, gotPayload, err := Open(qr, 0x01)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if gotHeader != header {
+		t.Errorf("expected header %+v, got %+v", header, gotHeader)
+	}
+	if gotPayload != payload {
+		t.Errorf("expected payload %q, got %q", payload, gotPayload)
+	}
+}
+
+func TestSealReferenceCompression(t *testing.T) {
+	// Encoded by the TypeScript implementation, see typescript/src/cli_test.ts.
+	const typescriptQR = "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5KCH0BB12EJI6C2NFLCHS43I7E8NVVNCAMCF3GSRUMS4EK680FG7L2H6H9UDVLMR955998RVVVBUV000"
+
+	header, payload, err := Open(typescriptQR, 0x00)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+
+	qr, err := Seal(header, payload, SealOptions{Compression: CompressionReference})
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if qr != typescriptQR {
+		t.Errorf("expected %q, got %q", typescriptQR, qr)
+	}
+
+	smallest, err := Seal(header, payload)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if len(smallest) > len(qr) {
+		t.Errorf("expected the default compression to be at most %d characters, got %d", len(qr), len(smallest))
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	payload := "random-id\t1"
+
+	seal := func(header BysquareHeader) string {
+		qr, err := Seal(header, payload)
+		if err != nil {
+			t.Fatalf("Seal() error: %v", err)
+		}
+		return qr
+	}
+
+	// A frame whose stored checksum does not match its payload.
+	corrupted := func() string {
+		checked := AddChecksum(payload)
+		binary.LittleEndian.PutUint32(checked[:4], 0xFFFFFFFF)
+		compressed, err := CompressLZMA(checked)
+		if err != nil {
+			t.Fatalf("CompressLZMA() error: %v", err)
+		}
+		text, err := Frame{Length: len(checked), Body: compressed[13:]}.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() error: %v", err)
+		}
+		return string(text)
+	}
+
+	testCases := []struct {
+		name     string
+		qr       string
+		expected error
+	}{
+		{"wrong type", seal(BysquareHeader{BySquareType: 0x01}), ErrUnexpectedType},
+		{"unsupported version", seal(BysquareHeader{Version: 0x03}), ErrUnsupportedVersion},
+		{"checksum mismatch", corrupted(), ErrChecksumMismatch},
+		{"truncated", EncodeBase32Hex([]byte{0x00, 0x00, 0x01}, false), ErrTruncatedPayload},
+		{"invalid base32hex", "!!!", ErrInvalidBase32Hex},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := Open(tc.qr, 0x00)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestOpenLimits(t *testing.T) {
+	payload := "random-id\t1"
+	qr, err := Seal(BysquareHeader{}, payload)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	if _, _, err := Open(qr, 0x00, OpenOptions{Limits: Limits{MaxInputLength: len(qr) - 1}}); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("expected ErrPayloadTooLarge for long input, got %v", err)
+	}
+	if _, _, err := Open(qr, 0x00, OpenOptions{Limits: Limits{MaxPayloadSize: 4}}); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("expected ErrPayloadTooLarge for large payload, got %v", err)
+	}
+
+	// Append garbage to the LZMA body.
+	var frame Frame
+	if err := frame.UnmarshalText([]byte(qr)); err != nil {
+		t.Fatalf("UnmarshalText() error: %v", err)
+	}
+	frame.Body = append(frame.Body, 0x00, 0x01, 0x02)
+	text, err := frame.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error: %v", err)
+	}
+
+	if _, _, err := Open(string(text), 0x00); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("expected ErrTrailingData, got %v", err)
+	}
+
+	_, got, err := Open(string(text), 0x00, OpenOptions{Lenient: true})
+	var warnings Warnings
+	if !errors.As(err, &warnings) || !errors.Is(err, ErrTrailingData) {
+		t.Errorf("expected ErrTrailingData warning, got %v", err)
+	}
+	if got != payload {
+		t.Errorf("expected payload %q, got %q", payload, got)
+	}
+}
@@ -3,6 +3,7 @@ package bysquare
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -20,7 +21,7 @@ type BysquareHeader struct {
 	Reserved     uint8
 }
 
-// BuildBysquareHeader creates a 2-byte header.
+// MarshalBinary encodes the header as 2 bytes.
 //
 //	Byte 0                  Byte 1
 //	+----------+----------+----------+----------+
@@ -30,44 +31,70 @@ type BysquareHeader struct {
 //	| (0-15)   | (0-15)   | (0-15)   | (0-15)   |
 //	+----------+----------+----------+----------+
 //
+// It returns ErrInvalidHeader when a field does not fit in 4 bits.
+//
 // @see 3.5.
-func BuildBysquareHeader(bySquareType, version, docType, reserved uint8) []byte {
-	if bySquareType > 0x0F || version > 0x0F || docType > 0x0F || reserved > 0x0F {
-		panic("header values must be 4-bit (0-15)")
+func (h BysquareHeader) MarshalBinary() ([]byte, error) {
+	if h.BySquareType > 0x0F || h.Version > 0x0F || h.DocumentType > 0x0F || h.Reserved > 0x0F {
+		return nil, fmt.Errorf("%w: values must be 4-bit (0-15), got %d %d %d %d",
+			ErrInvalidHeader, h.BySquareType, h.Version, h.DocumentType, h.Reserved)
 	}
 
-	header := make([]byte, 2)
-	header[0] = (bySquareType << 4) | version
-	header[1] = (docType << 4) | reserved
+	return []byte{
+		(h.BySquareType << 4) | h.Version,
+		(h.DocumentType << 4) | h.Reserved,
+	}, nil
+}
 
-	return header
+// UnmarshalBinary decodes the header from the first 2 bytes of data. It
+// returns ErrTruncatedPayload when data is shorter.
+//
+// @see 3.5.
+func (h *BysquareHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("%w: header must be 2 bytes, got %d", ErrTruncatedPayload, len(data))
+	}
+
+	*h = BysquareHeader{
+		BySquareType: (data[0] >> 4) & 0x0F,
+		Version:      data[0] & 0x0F,
+		DocumentType: (data[1] >> 4) & 0x0F,
+		Reserved:     data[1] & 0x0F,
+	}
+	return nil
 }
 
-// ParseBysquareHeader extracts header fields from 2 bytes.
+// BuildBysquareHeader creates a 2-byte header. It panics when a value does
+// not fit in 4 bits.
 //
-//	Byte 0                  Byte 1
-//	+----------+----------+----------+----------+
-//	|   4 bit  |   4 bit  |   4 bit  |   4 bit  |
-//	+----------+----------+----------+----------+
-//	| BySqType | Version  | DocType  | Reserved |
-//	| (0-15)   | (0-15)   | (0-15)   | (0-15)   |
-//	+----------+----------+----------+----------+
+// Deprecated: Use BysquareHeader.MarshalBinary, which returns an error
+// instead.
 //
 // @see 3.5.
-func ParseBysquareHeader(header []byte) BysquareHeader {
-	if len(header) < 2 {
-		panic("header must be 2 bytes")
+func BuildBysquareHeader(bySquareType, version, docType, reserved uint8) []byte {
+	header, err := BysquareHeader{bySquareType, version, docType, reserved}.MarshalBinary()
+	if err != nil {
+		panic(err.Error())
 	}
+	return header
+}
 
-	return BysquareHeader{
-		BySquareType: (header[0] >> 4) & 0x0F,
-		Version:      header[0] & 0x0F,
-		DocumentType: (header[1] >> 4) & 0x0F,
-		Reserved:     header[1] & 0x0F,
+// ParseBysquareHeader extracts header fields from 2 bytes. It panics when
+// header is shorter.
+//
+// Deprecated: Use BysquareHeader.UnmarshalBinary, which returns an error
+// instead.
+//
+// @see 3.5.
+func ParseBysquareHeader(header []byte) BysquareHeader {
+	var h BysquareHeader
+	if err := h.UnmarshalBinary(header); err != nil {
+		panic(err.Error())
 	}
+	return h
 }
 
-// BuildPayloadLength creates a 2-byte little-endian length field.
+// EncodePayloadLength creates a 2-byte little-endian length field.
 //
 //	+---------------+---------------+
 //	|    Byte 0     |    Byte 1     |
@@ -75,17 +102,34 @@ func ParseBysquareHeader(header []byte) BysquareHeader {
 //	|      LSB      |      MSB      |
 //	+---------------+---------------+
 //	| Little-endian 16-bit unsigned |
-//	| max 2^17 = 131072             |
 //	+-------------------------------+
 //
+// The specification allows payloads up to 2^17 bytes, but the field only
+// holds 16 bits. Lengths that do not fit are rejected with
+// ErrPayloadTooLarge rather than silently truncated.
+//
 // @see 3.6.
-func BuildPayloadLength(length int) []byte {
-	if length >= MaxCompressedSize {
-		panic(fmt.Sprintf("payload length %d exceeds maximum %d", length, MaxCompressedSize))
+func EncodePayloadLength(length int) ([]byte, error) {
+	if length < 0 || length >= MaxCompressedSize || length > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: payload length %d does not fit the 16-bit length field", ErrPayloadTooLarge, length)
 	}
 
 	buf := make([]byte, 2)
 	binary.LittleEndian.PutUint16(buf, uint16(length))
+	return buf, nil
+}
+
+// BuildPayloadLength creates a 2-byte little-endian length field. It panics
+// when length does not fit.
+//
+// Deprecated: Use EncodePayloadLength, which returns an error instead.
+//
+// @see 3.6.
+func BuildPayloadLength(length int) []byte {
+	buf, err := EncodePayloadLength(length)
+	if err != nil {
+		panic(err.Error())
+	}
 	return buf
 }
 
@@ -98,6 +142,8 @@ func Sanitize(s string) string {
 
 // FormatFloat formats a float64 to string, omitting if zero.
 // Use for optional numeric fields where zero means "not set".
+//
+// Deprecated: amounts are Decimal values; use FormatDecimal.
 func FormatFloat(f float64) string {
 	if f == 0 {
 		return ""
@@ -110,6 +156,8 @@ func FormatFloat(f float64) string {
 
 // FormatFloatRequired formats a float64 to string, always producing output
 // even for zero. Use for required numeric fields where 0 is a valid value.
+//
+// Deprecated: amounts are Decimal values; use FormatDecimalRequired.
 func FormatFloatRequired(f float64) string {
 	s := fmt.Sprintf("%f", f)
 	s = strings.TrimRight(s, "0")
@@ -126,6 +174,8 @@ func ParseNumber(s string) (int, error) {
 }
 
 // ParseFloat parses a string to float64, returning 0 if empty or invalid.
+//
+// Deprecated: amounts are Decimal values; use ParseDecimal.
 func ParseFloat(s string) (float64, error) {
 	if s == "" {
 		return 0, nil
@@ -0,0 +1,63 @@
+package bysquare
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeaderBinaryRoundTrip(t *testing.T) {
+	header := BysquareHeader{BySquareType: 0x01, Version: 0x02, DocumentType: 0x03, Reserved: 0x04}
+
+	data, err := header.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %v", err)
+	}
+	if data[0] != 0x12 || data[1] != 0x34 {
+		t.Errorf("expected 1234, got %x", data)
+	}
+
+	var decoded BysquareHeader
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error: %v", err)
+	}
+	if decoded != header {
+		t.Errorf("expected %+v, got %+v", header, decoded)
+	}
+}
+
+func TestHeaderBinaryErrors(t *testing.T) {
+	if _, err := (BysquareHeader{DocumentType: 0x10}).MarshalBinary(); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+
+	var header BysquareHeader
+	if err := header.UnmarshalBinary([]byte{0x00}); !errors.Is(err, ErrTruncatedPayload) {
+		t.Errorf("expected ErrTruncatedPayload, got %v", err)
+	}
+}
+
+func TestEncodePayloadLength(t *testing.T) {
+	testCases := []struct {
+		length   int
+		expected []byte
+		err      error
+	}{
+		{0, []byte{0x00, 0x00}, nil},
+		{256, []byte{0x00, 0x01}, nil},
+		{65535, []byte{0xFF, 0xFF}, nil},
+		{65536, nil, ErrPayloadTooLarge},
+		{MaxCompressedSize, nil, ErrPayloadTooLarge},
+		{-1, nil, ErrPayloadTooLarge},
+	}
+
+	for _, tc := range testCases {
+		got, err := EncodePayloadLength(tc.length)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("length %d: expected error %v, got %v", tc.length, tc.err, err)
+			continue
+		}
+		if tc.err == nil && (got[0] != tc.expected[0] || got[1] != tc.expected[1]) {
+			t.Errorf("length %d: expected %x, got %x", tc.length, tc.expected, got)
+		}
+	}
+}
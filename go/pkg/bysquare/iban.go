@@ -0,0 +1,266 @@
+package bysquare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IBANFormat describes the IBAN of one country as published in the SWIFT
+// IBAN registry.
+//
+// BBAN uses the registry notation: each segment is a length, "!" for a
+// fixed length, and a character class.
+//
+//	+-------+---------------------------+
+//	| Class | Characters                |
+//	+-------+---------------------------+
+//	| n     | digits 0-9                |
+//	| a     | upper case letters A-Z    |
+//	| c     | letters and digits        |
+//	+-------+---------------------------+
+//
+// For example Slovakia is 24 characters long with BBAN "4!n6!n10!n": a
+// 4-digit bank code, a 6-digit account prefix and a 10-digit account
+// number.
+type IBANFormat struct {
+	Length int
+	BBAN   string
+}
+
+// ibanFormats covers every IBAN country of the SWIFT registry, including
+// all SEPA members.
+var ibanFormats = map[string]IBANFormat{
+	"AD": {24, "4!n4!n12!c"},
+	"AE": {23, "3!n16!n"},
+	"AL": {28, "8!n16!c"},
+	"AT": {20, "5!n11!n"},
+	"AZ": {28, "4!a20!c"},
+	"BA": {20, "3!n3!n8!n2!n"},
+	"BE": {16, "3!n7!n2!n"},
+	"BG": {22, "4!a4!n2!n8!c"},
+	"BH": {22, "4!a14!c"},
+	"BI": {27, "5!n5!n11!n2!n"},
+	"BR": {29, "8!n5!n10!n1!a1!c"},
+	"BY": {28, "4!c4!n16!c"},
+	"CH": {21, "5!n12!c"},
+	"CR": {22, "4!n14!n"},
+	"CY": {28, "3!n5!n16!c"},
+	"CZ": {24, "4!n6!n10!n"},
+	"DE": {22, "8!n10!n"},
+	"DJ": {27, "5!n5!n11!n2!n"},
+	"DK": {18, "4!n9!n1!n"},
+	"DO": {28, "4!c20!n"},
+	"EE": {20, "2!n14!n"},
+	"EG": {29, "4!n4!n17!n"},
+	"ES": {24, "4!n4!n1!n1!n10!n"},
+	"FI": {18, "3!n11!n"},
+	"FK": {18, "2!a12!n"},
+	"FO": {18, "4!n9!n1!n"},
+	"FR": {27, "5!n5!n11!c2!n"},
+	"GB": {22, "4!a6!n8!n"},
+	"GE": {22, "2!a16!n"},
+	"GI": {23, "4!a15!c"},
+	"GL": {18, "4!n9!n1!n"},
+	"GR": {27, "3!n4!n16!c"},
+	"GT": {28, "4!c20!c"},
+	"HR": {21, "7!n10!n"},
+	"HU": {28, "3!n4!n1!n15!n1!n"},
+	"IE": {22, "4!a6!n8!n"},
+	"IL": {23, "3!n3!n13!n"},
+	"IQ": {23, "4!a3!n12!n"},
+	"IS": {26, "4!n2!n6!n10!n"},
+	"IT": {27, "1!a5!n5!n12!c"},
+	"JO": {30, "4!a4!n18!c"},
+	"KW": {30, "4!a22!c"},
+	"KZ": {20, "3!n13!c"},
+	"LB": {28, "4!n20!c"},
+	"LC": {32, "4!a24!c"},
+	"LI": {21, "5!n12!c"},
+	"LT": {20, "5!n11!n"},
+	"LU": {20, "3!n13!c"},
+	"LV": {21, "4!a13!c"},
+	"LY": {25, "3!n3!n15!n"},
+	"MC": {27, "5!n5!n11!c2!n"},
+	"MD": {24, "2!c18!c"},
+	"ME": {22, "3!n13!n2!n"},
+	"MK": {19, "3!n10!c2!n"},
+	"MN": {20, "4!n12!n"},
+	"MR": {27, "5!n5!n11!n2!n"},
+	"MT": {31, "4!a5!n18!c"},
+	"MU": {30, "4!a2!n2!n12!n3!n3!a"},
+	"NI": {28, "4!a20!n"},
+	"NL": {18, "4!a10!n"},
+	"NO": {15, "4!n6!n1!n"},
+	"OM": {23, "3!n16!c"},
+	"PK": {24, "4!a16!c"},
+	"PL": {28, "8!n16!n"},
+	"PS": {29, "4!a21!c"},
+	"PT": {25, "4!n4!n11!n2!n"},
+	"QA": {29, "4!a21!c"},
+	"RO": {24, "4!a16!c"},
+	"RS": {22, "3!n13!n2!n"},
+	"RU": {33, "9!n5!n15!c"},
+	"SA": {24, "2!n18!c"},
+	"SC": {31, "4!a2!n2!n16!n3!a"},
+	"SD": {18, "2!n12!n"},
+	"SE": {24, "3!n16!n1!n"},
+	"SI": {19, "5!n8!n2!n"},
+	"SK": {24, "4!n6!n10!n"},
+	"SM": {27, "1!a5!n5!n12!c"},
+	"SO": {23, "4!n3!n12!n"},
+	"ST": {25, "4!n4!n11!n2!n"},
+	"SV": {28, "4!a20!n"},
+	"TL": {23, "3!n14!n2!n"},
+	"TN": {24, "2!n3!n13!n2!n"},
+	"TR": {26, "5!n1!n16!c"},
+	"UA": {29, "6!n19!c"},
+	"VA": {22, "3!n15!n"},
+	"VG": {24, "4!a16!n"},
+	"XK": {20, "4!n10!n2!n"},
+}
+
+// LookupIBANFormat returns the IBAN format of an ISO 3166-1 alpha-2
+// country code.
+func LookupIBANFormat(country string) (IBANFormat, bool) {
+	f, ok := ibanFormats[strings.ToUpper(country)]
+	return f, ok
+}
+
+// IBANError reports why an IBAN is invalid. Err is one of ErrIBANCountry,
+// ErrIBANLength, ErrIBANFormat or ErrIBANChecksum.
+type IBANError struct {
+	// Country is the country code of the IBAN.
+	Country string
+	// Offset is the position of the first offending character in the IBAN
+	// without spaces, or -1 when no single character is at fault.
+	Offset int
+	// Detail describes the problem for users, e.g. "SK IBAN has 24
+	// characters, got 23".
+	Detail string
+	Err    error
+}
+
+func (e *IBANError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Detail)
+}
+
+func (e *IBANError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateIBAN checks iban against the registered format of its country
+// and the ISO 7064 MOD 97-10 check digits. Spaces are ignored and letters
+// may be lower case. The returned *IBANError tells wrong length, malformed
+// BBAN and wrong check digits apart.
+func ValidateIBAN(iban string) error {
+	iban = strings.ReplaceAll(strings.ToUpper(iban), " ", "")
+
+	if len(iban) < 2 || !isUpper(iban[0]) || !isUpper(iban[1]) {
+		return &IBANError{Offset: 0, Detail: "IBAN must start with a country code", Err: ErrIBANCountry}
+	}
+
+	country := iban[:2]
+	format, ok := ibanFormats[country]
+	if !ok {
+		return &IBANError{Country: country, Offset: 0, Detail: fmt.Sprintf("%s does not use IBAN", country), Err: ErrIBANCountry}
+	}
+
+	if len(iban) != format.Length {
+		return &IBANError{
+			Country: country,
+			Offset:  -1,
+			Detail:  fmt.Sprintf("%s IBAN has %d characters, got %d", country, format.Length, len(iban)),
+			Err:     ErrIBANLength,
+		}
+	}
+
+	for i := 2; i < 4; i++ {
+		if !isDigit(iban[i]) {
+			return &IBANError{Country: country, Offset: i, Detail: fmt.Sprintf("character %d must be a digit", i+1), Err: ErrIBANFormat}
+		}
+	}
+
+	if i, class := format.mismatch(iban[4:]); i >= 0 {
+		return &IBANError{
+			Country: country,
+			Offset:  4 + i,
+			Detail:  fmt.Sprintf("character %d must be %s", 4+i+1, classNames[class]),
+			Err:     ErrIBANFormat,
+		}
+	}
+
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return &IBANError{Country: country, Offset: 2, Detail: "check digits do not match", Err: ErrIBANChecksum}
+	}
+
+	return nil
+}
+
+// classNames describes the BBAN character classes for error details.
+var classNames = map[byte]string{
+	'n': "a digit",
+	'a': "a letter",
+	'c': "a letter or digit",
+}
+
+// mismatch returns the index of the first character of bban outside the
+// format, with the expected class, or -1. bban must have the length of
+// the format.
+func (f IBANFormat) mismatch(bban string) (int, byte) {
+	spec := f.BBAN
+	pos := 0
+	for len(spec) > 0 {
+		n := 0
+		for len(spec) > 0 && isDigit(spec[0]) {
+			n = n*10 + int(spec[0]-'0')
+			spec = spec[1:]
+		}
+		spec = strings.TrimPrefix(spec, "!")
+		if len(spec) == 0 {
+			break
+		}
+		class := spec[0]
+		spec = spec[1:]
+
+		for ; n > 0 && pos < len(bban); n-- {
+			ch := bban[pos]
+			ok := false
+			switch class {
+			case 'n':
+				ok = isDigit(ch)
+			case 'a':
+				ok = isUpper(ch)
+			case 'c':
+				ok = isDigit(ch) || isUpper(ch)
+			}
+			if !ok {
+				return pos, class
+			}
+			pos++
+		}
+	}
+	return -1, 0
+}
+
+// mod97 returns s modulo 97, reading letters as 10 (A) to 35 (Z) per
+// ISO 7064.
+func mod97(s string) int {
+	remainder := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isUpper(ch) {
+			remainder = (remainder*100 + int(ch-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(ch-'0')) % 97
+		}
+	}
+	return remainder
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isUpper(ch byte) bool {
+	return ch >= 'A' && ch <= 'Z'
+}
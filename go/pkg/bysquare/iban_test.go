@@ -0,0 +1,98 @@
+package bysquare
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	valid := []string{
+		"SK3112000000198742637541",
+		"CZ6508000000192000145399",
+		"AT611904300234573201",
+		"BE68539007547034",
+		"CH9300762011623852957",
+		"FR1420041010050500013M02606",
+		"GB82WEST12345698765432",
+		"HU42117730161111101800000000",
+		"IT60X0542811101000000123456",
+		"MT84MALT011000012345MTLCAST001S",
+		"NL91ABNA0417164300",
+		"NO9386011117947",
+		"PL61109010140000071219812874",
+		"sk31 1200 0000 1987 4263 7541",
+	}
+	for _, iban := range valid {
+		if err := ValidateIBAN(iban); err != nil {
+			t.Errorf("expected %q valid, got %v", iban, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		iban   string
+		err    error
+		offset int
+	}{
+		{"empty", "", ErrIBANCountry, 0},
+		{"unknown country", "XX3112000000198742637541", ErrIBANCountry, 0},
+		{"SK too short", "SK311200000019874263754", ErrIBANLength, -1},
+		{"SK too long", "SK31120000001987426375410", ErrIBANLength, -1},
+		{"CZ letter in BBAN", "CZ65080000001920001453A9", ErrIBANFormat, 22},
+		{"letter in check digits", "SK3A12000000198742637541", ErrIBANFormat, 3},
+		{"GB digit in bank code", "GB82WE5T12345698765432", ErrIBANFormat, 6},
+		{"SK typo", "SK3112000000198742637542", ErrIBANChecksum, 2},
+		{"special characters", "SK31-1200-0000-1987-4263", ErrIBANFormat, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIBAN(tt.iban)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var ibanErr *IBANError
+			if !errors.As(err, &ibanErr) {
+				t.Fatalf("expected *IBANError, got %T", err)
+			}
+			if ibanErr.Offset != tt.offset {
+				t.Errorf("expected offset %d, got %d", tt.offset, ibanErr.Offset)
+			}
+		})
+	}
+
+	err := ValidateIBAN("SK311200000019874263754")
+	if !strings.Contains(err.Error(), "SK IBAN has 24 characters, got 23") {
+		t.Errorf("expected length detail, got %q", err)
+	}
+}
+
+func TestIBANFormats(t *testing.T) {
+	sepa := []string{
+		"AD", "AL", "AT", "BE", "BG", "CH", "CY", "CZ", "DE", "DK", "EE",
+		"ES", "FI", "FR", "GB", "GI", "GR", "HR", "HU", "IE", "IS", "IT",
+		"LI", "LT", "LU", "LV", "MC", "MD", "ME", "MK", "MT", "NL", "NO",
+		"PL", "PT", "RO", "RS", "SE", "SI", "SK", "SM", "VA",
+	}
+	for _, country := range sepa {
+		if _, ok := LookupIBANFormat(country); !ok {
+			t.Errorf("expected format for SEPA country %s", country)
+		}
+	}
+
+	for country, f := range ibanFormats {
+		total := 0
+		for _, segment := range strings.SplitAfter(f.BBAN, "!") {
+			n := 0
+			for _, ch := range segment {
+				if ch >= '0' && ch <= '9' {
+					n = n*10 + int(ch-'0')
+				}
+			}
+			total += n
+		}
+		if total != f.Length-4 {
+			t.Errorf("%s: BBAN %q has %d characters, expected %d", country, f.BBAN, total, f.Length-4)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bysquare
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HexBytes is a byte slice that marshals to text as lowercase hex.
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// HeaderInfo is a BysquareHeader with human-readable names.
+type HeaderInfo struct {
+	BysquareHeader
+	// TypeName is "PAY by square", "Invoice by square", or empty for
+	// unknown types.
+	TypeName string `json:"typeName,omitempty"`
+	// VersionName is the specification version, e.g. "1.2.0".
+	VersionName string `json:"versionName,omitempty"`
+	// DocumentTypeName names the document type within the bysquareType,
+	// e.g. "CreditNote".
+	DocumentTypeName string `json:"documentTypeName,omitempty"`
+}
+
+// InspectedField is one field of the tab-separated payload.
+type InspectedField struct {
+	Index int `json:"index"`
+	// Name is the path of the field in the data model, for example
+	// "payments[0].bankAccounts[0].iban". Fields beyond the layout of the
+	// bysquareType are named "unknown".
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Inspection describes every layer of a QR string.
+type Inspection struct {
+	// Raw holds the base32hex-decoded bytes: header, length and LZMA body.
+	Raw    HexBytes   `json:"raw"`
+	Header HeaderInfo `json:"header"`
+
+	// DeclaredLength is the payload length stored after the header.
+	DeclaredLength int `json:"declaredLength"`
+	// ActualLength is the length of the decompressed CRC32 and payload.
+	ActualLength int `json:"actualLength"`
+
+	// CompressedSize is the length of the LZMA body.
+	CompressedSize int `json:"compressedSize"`
+	// CompressionRatio is CompressedSize divided by ActualLength.
+	CompressionRatio float64 `json:"compressionRatio"`
+	// TrailingData is set when compressed bytes follow the LZMA stream.
+	TrailingData bool `json:"trailingData,omitempty"`
+
+	StoredCRC32   uint32 `json:"storedCrc32"`
+	ComputedCRC32 uint32 `json:"computedCrc32"`
+	ChecksumValid bool   `json:"checksumValid"`
+
+	Fields []InspectedField `json:"fields"`
+}
+
+// Inspect decodes a QR string layer by layer without interpreting the
+// payload as a data model, for diagnosing codes that fail to decode.
+//
+// Checksum mismatches, trailing data and unknown header values are
+// reported rather than rejected. A string that is not a valid frame returns
+// nil and the error; when a later layer cannot be decoded, Inspect returns
+// the layers read so far together with the error.
+//
+//	base32hex -> header + length -> LZMA -> CRC32 + payload -> fields
+//
+// @see 3.16.
+func Inspect(qr string) (*Inspection, error) {
+	var frame Frame
+	if err := frame.UnmarshalText([]byte(qr)); err != nil {
+		return nil, err
+	}
+
+	in := &Inspection{
+		Header:         describeHeader(frame.Header),
+		DeclaredLength: frame.Length,
+		CompressedSize: len(frame.Body),
+	}
+	in.Raw, _ = DecodeBase32Hex(qr, true)
+
+	decompressed, err := DecompressLZMA(frame.Body, frame.Length)
+	if errors.Is(err, ErrTrailingData) {
+		in.TrailingData = true
+		err = nil
+	}
+	if err != nil {
+		return in, fmt.Errorf("LZMA decompression failed: %w", err)
+	}
+
+	in.ActualLength = len(decompressed)
+	if in.ActualLength > 0 {
+		in.CompressionRatio = float64(in.CompressedSize) / float64(in.ActualLength)
+	}
+
+	if len(decompressed) < 4 {
+		return in, fmt.Errorf("%w: decompressed data too short for checksum", ErrTruncatedPayload)
+	}
+
+	payload := string(decompressed[4:])
+	in.StoredCRC32 = binary.LittleEndian.Uint32(decompressed[:4])
+	in.ComputedCRC32 = Crc32Checksum(payload)
+	in.ChecksumValid = in.StoredCRC32 == in.ComputedCRC32
+
+	values := strings.Split(payload, "\t")
+	names := fieldNames(frame.Header.BySquareType, values)
+	in.Fields = make([]InspectedField, len(values))
+	for i, v := range values {
+		in.Fields[i] = InspectedField{Index: i, Name: names[i], Value: v}
+	}
+
+	return in, nil
+}
+
+// describeHeader attaches names to the header nibbles.
+//
+// @see 3.5.
+func describeHeader(h BysquareHeader) HeaderInfo {
+	info := HeaderInfo{
+		BysquareHeader: h,
+		VersionName:    Version(h.Version).String(),
+	}
+
+	switch h.BySquareType {
+	case 0x00:
+		info.TypeName = "PAY by square"
+		if h.DocumentType == 0x00 {
+			info.DocumentTypeName = "Payment"
+		}
+	case 0x01:
+		info.TypeName = "Invoice by square"
+		documentTypes := []string{"Invoice", "ProformaInvoice", "CreditNote", "DebitNote", "AdvanceInvoice"}
+		if int(h.DocumentType) < len(documentTypes) {
+			info.DocumentTypeName = documentTypes[h.DocumentType]
+		}
+	}
+
+	return info
+}
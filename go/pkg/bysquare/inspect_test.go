@@ -0,0 +1,153 @@
+package bysquare
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInspectPay(t *testing.T) {
+	qr := "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+
+	in, err := Inspect(qr)
+	if err != nil {
+		t.Fatalf("Inspect() error: %v", err)
+	}
+
+	if in.Header.TypeName != "PAY by square" {
+		t.Errorf("expected type name 'PAY by square', got %q", in.Header.TypeName)
+	}
+	if in.Header.VersionName != "1.2.0" {
+		t.Errorf("expected version name '1.2.0', got %q", in.Header.VersionName)
+	}
+	if in.DeclaredLength != in.ActualLength {
+		t.Errorf("expected declared length %d to equal actual length %d", in.DeclaredLength, in.ActualLength)
+	}
+	if in.CompressedSize != len(in.Raw)-4 {
+		t.Errorf("expected compressed size %d, got %d", len(in.Raw)-4, in.CompressedSize)
+	}
+	if !in.ChecksumValid || in.StoredCRC32 != in.ComputedCRC32 {
+		t.Errorf("expected valid checksum, got stored=%d computed=%d", in.StoredCRC32, in.ComputedCRC32)
+	}
+
+	expected := map[string]string{
+		"invoiceId":                        "random-id",
+		"payments":                         "1",
+		"payments[0].amount":               "100",
+		"payments[0].bankAccounts[0].iban": "SK9611000000002918599669",
+		"payments[0].directDebitExt":       "0",
+	}
+	found := 0
+	for _, f := range in.Fields {
+		if want, ok := expected[f.Name]; ok {
+			found++
+			if f.Value != want {
+				t.Errorf("field %s: expected %q, got %q", f.Name, want, f.Value)
+			}
+		}
+		if f.Name == "unknown" {
+			t.Errorf("unexpected unknown field %d = %q", f.Index, f.Value)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d named fields, found %d", len(expected), found)
+	}
+}
+
+func TestInspectInvoiceLayout(t *testing.T) {
+	values := make([]string, 50)
+	values[36] = "2"
+	values[49] = "1"
+	qr, err := Seal(BysquareHeader{BySquareType: 0x01, DocumentType: 0x02}, strings.Join(values, "\t"))
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	in, err := Inspect(qr)
+	if err != nil {
+		t.Fatalf("Inspect() error: %v", err)
+	}
+
+	if in.Header.DocumentTypeName != "CreditNote" {
+		t.Errorf("expected document type name 'CreditNote', got %q", in.Header.DocumentTypeName)
+	}
+
+	testCases := map[int]string{
+		0:  "invoiceId",
+		9:  "supplierParty.partyName",
+		21: "supplierParty.contact.email",
+		26: "customerParty.partyIdentification",
+		36: "taxCategorySummaries",
+		42: "taxCategorySummaries[1].classifiedTaxCategory",
+		47: "monetarySummary.payableRoundingAmount",
+		49: "paymentMeans",
+	}
+	for index, name := range testCases {
+		if got := in.Fields[index].Name; got != name {
+			t.Errorf("field %d: expected %q, got %q", index, name, got)
+		}
+	}
+}
+
+func TestInspectReportsChecksumMismatch(t *testing.T) {
+	payload := "random-id\t0\textra"
+	checked := AddChecksum(payload)
+	binary.LittleEndian.PutUint32(checked[:4], 1)
+	compressed, err := CompressLZMA(checked)
+	if err != nil {
+		t.Fatalf("CompressLZMA() error: %v", err)
+	}
+	text, err := Frame{Length: len(checked), Body: compressed[13:]}.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error: %v", err)
+	}
+
+	in, err := Inspect(string(text))
+	if err != nil {
+		t.Fatalf("Inspect() error: %v", err)
+	}
+	if in.ChecksumValid {
+		t.Error("expected invalid checksum")
+	}
+	if in.StoredCRC32 != 1 {
+		t.Errorf("expected stored CRC32 1, got %d", in.StoredCRC32)
+	}
+	if got := in.Fields[2].Name; got != "unknown" {
+		t.Errorf("expected trailing field named 'unknown', got %q", got)
+	}
+}
+
+func TestInspectJSON(t *testing.T) {
+	qr, err := Seal(BysquareHeader{}, "id\t0")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	in, err := Inspect(qr)
+	if err != nil {
+		t.Fatalf("Inspect() error: %v", err)
+	}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+	if _, ok := out["raw"].(string); !ok {
+		t.Errorf("expected raw as hex string, got %T", out["raw"])
+	}
+	header := out["header"].(map[string]any)
+	if header["typeName"] != "PAY by square" {
+		t.Errorf("expected typeName 'PAY by square', got %v", header["typeName"])
+	}
+}
+
+func TestInspectInvalidInput(t *testing.T) {
+	if _, err := Inspect("!!!"); err == nil {
+		t.Error("expected error for invalid base32hex")
+	}
+}
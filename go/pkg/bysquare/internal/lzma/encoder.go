@@ -0,0 +1,134 @@
+// Package lzma implements a raw LZMA1 encoder for the bysquare profile:
+// lc=3, lp=0, pb=2 and a 2^17 dictionary.
+//
+// Payloads are a few hundred bytes, where every byte saved can shrink the
+// QR symbol by a version. The encoder therefore trades speed for size: it
+// parses the whole input optimally under the model prices and repeats the
+// parse with the prices learned by the previous pass, keeping the smallest
+// stream.
+package lzma
+
+// smallInput is the size up to which Encode runs more than one pass.
+const smallInput = 1 << 12
+
+// Encode compresses data and returns the LZMA body, without the 13-byte
+// header. The decoder must be told the uncompressed size; only empty input
+// is terminated with an end marker.
+func Encode(data []byte) []byte {
+	if len(data) == 0 {
+		// Some decoders, including the reference one, expect an end
+		// marker rather than an empty stream.
+		m := newModel()
+		e := newRangeEncoder(8)
+		e.encodeBit(&m.isMatch[0][0], 1)
+		e.encodeBit(&m.isRep[0], 0)
+		m.matchLen.encode(e, minMatchLen, 0)
+		m.encodeDistance(e, 0xFFFFFFFF, minMatchLen)
+		return e.flush()
+	}
+
+	// Passes with learned prices save a few bytes; the parse settles after
+	// the third.
+	passes, depth := 3, 1<<10
+	if len(data) > smallInput {
+		passes, depth = 1, 1<<6
+	}
+
+	matches := findMatches(data, depth)
+	prices := newModel()
+
+	var best []byte
+	for pass := 0; pass < passes; pass++ {
+		ops := parse(data, matches, prices)
+		out, learned := encodeOps(data, ops)
+		if best == nil || len(out) < len(best) {
+			best = out
+		}
+		prices = learned
+	}
+
+	return best
+}
+
+// encoder codes ops and tracks the state the parser needs.
+type encoder struct {
+	data  []byte
+	rc    *rangeEncoder
+	m     *model
+	state int
+	reps  [4]uint32
+	pos   int
+}
+
+func newEncoder(data []byte) *encoder {
+	return &encoder{data: data, rc: newRangeEncoder(len(data)), m: newModel()}
+}
+
+// encodeOps codes a parse and returns the stream together with the model
+// as it was left at the end of the stream.
+func encodeOps(data []byte, ops []op) ([]byte, *model) {
+	enc := newEncoder(data)
+	for _, o := range ops {
+		enc.encode(o)
+	}
+	return enc.rc.flush(), enc.m
+}
+
+func (enc *encoder) encode(o op) {
+	e, m, data, p := enc.rc, enc.m, enc.data, enc.pos
+	state, ps := enc.state, p&posMask
+
+	switch o.kind {
+	case opLiteral:
+		e.encodeBit(&m.isMatch[state][ps], 0)
+		var prevByte, matchByte byte
+		if p > 0 {
+			prevByte = data[p-1]
+		}
+		if int(enc.reps[0]) < p {
+			matchByte = data[p-int(enc.reps[0])-1]
+		}
+		m.encodeLiteral(e, data[p], prevByte, matchByte, state)
+		enc.state = nextLiteral(state)
+		enc.pos++
+
+	case opShortRep:
+		e.encodeBit(&m.isMatch[state][ps], 1)
+		e.encodeBit(&m.isRep[state], 1)
+		e.encodeBit(&m.isRepG0[state], 0)
+		e.encodeBit(&m.isRep0Long[state][ps], 0)
+		enc.state = nextShortRep(state)
+		enc.pos++
+
+	case opRep:
+		e.encodeBit(&m.isMatch[state][ps], 1)
+		e.encodeBit(&m.isRep[state], 1)
+		if o.rep == 0 {
+			e.encodeBit(&m.isRepG0[state], 0)
+			e.encodeBit(&m.isRep0Long[state][ps], 1)
+		} else {
+			e.encodeBit(&m.isRepG0[state], 1)
+			if o.rep == 1 {
+				e.encodeBit(&m.isRepG1[state], 0)
+			} else {
+				e.encodeBit(&m.isRepG1[state], 1)
+				e.encodeBit(&m.isRepG2[state], uint32(o.rep-2))
+			}
+			dist := enc.reps[o.rep]
+			copy(enc.reps[1:o.rep+1], enc.reps[:o.rep])
+			enc.reps[0] = dist
+		}
+		m.repLen.encode(e, o.length, ps)
+		enc.state = nextRep(state)
+		enc.pos += o.length
+
+	case opMatch:
+		e.encodeBit(&m.isMatch[state][ps], 1)
+		e.encodeBit(&m.isRep[state], 0)
+		m.matchLen.encode(e, o.length, ps)
+		m.encodeDistance(e, o.dist, o.length)
+		enc.reps = [4]uint32{o.dist, enc.reps[0], enc.reps[1], enc.reps[2]}
+		enc.state = nextMatch(state)
+		enc.pos += o.length
+	}
+}
@@ -0,0 +1,99 @@
+package lzma
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	xzlzma "github.com/ulikunitz/xz/lzma"
+)
+
+// decode decompresses body with the reference decoder.
+func decode(t testing.TB, body []byte, size int) []byte {
+	t.Helper()
+
+	header := []byte{0x5D, 0x00, 0x00, 0x02, 0x00}
+	header = binary.LittleEndian.AppendUint64(header, uint64(size))
+
+	input := bytes.NewReader(append(header, body...))
+	reader, err := xzlzma.NewReader(input)
+	if err != nil {
+		t.Fatalf("NewReader() error: %v", err)
+	}
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if input.Len() != 0 {
+		t.Fatalf("expected the whole stream to be consumed, %d bytes left", input.Len())
+	}
+	return out
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"single byte", []byte{0x42}},
+		{"pay payload", []byte("\x00\x00\x00\x00random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t1\tSK9611000000002918599669\t\t0\t0\tJohn Doe\t\t")},
+		{"repeated", bytes.Repeat([]byte("abc"), 1000)},
+		{"zeros", make([]byte, 1<<16-1)},
+		{"random", random},
+		{"text", []byte(strings.Repeat("Platba za faktúru č. 2024001\tSK9611000000002918599669\t", 40))},
+	}
+
+	encoders := []struct {
+		name   string
+		encode func([]byte) []byte
+	}{
+		{"Encode", Encode},
+		{"EncodeReference", EncodeReference},
+	}
+
+	for _, tc := range testCases {
+		for _, enc := range encoders {
+			t.Run(tc.name+"/"+enc.name, func(t *testing.T) {
+				body := enc.encode(tc.data)
+				got := decode(t, body, len(tc.data))
+				if !bytes.Equal(got, tc.data) {
+					t.Fatalf("round trip mismatch: expected %d bytes, got %d", len(tc.data), len(got))
+				}
+			})
+		}
+	}
+}
+
+func TestPosSlot(t *testing.T) {
+	testCases := []struct {
+		dist uint32
+		slot uint32
+	}{
+		{0, 0}, {3, 3}, {4, 4}, {5, 4}, {6, 5}, {7, 5}, {8, 6}, {12, 7}, {1 << 16, 32}, {1<<17 - 1, 33},
+	}
+	for _, tc := range testCases {
+		if got := posSlot(tc.dist); got != tc.slot {
+			t.Errorf("dist %d: expected slot %d, got %d", tc.dist, tc.slot, got)
+		}
+	}
+}
+
+func FuzzEncode(f *testing.F) {
+	f.Add([]byte("random-id\t1\t1\t100\tEUR"))
+	f.Add([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		got := decode(t, Encode(data), len(data))
+		if !bytes.Equal(got, data) {
+			t.Fatalf("round trip mismatch for %q", data)
+		}
+	})
+}
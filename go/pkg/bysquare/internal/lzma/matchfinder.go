@@ -0,0 +1,72 @@
+package lzma
+
+// dictSize is the dictionary size of the bysquare profile (2^17). Matches
+// never reach further back.
+const dictSize = 1 << 17
+
+// match is a candidate back-reference. dist is zero-based: 0 refers to the
+// previous byte.
+type match struct {
+	length int
+	dist   uint32
+}
+
+// findMatches lists, for every position of data, the matches of increasing
+// length found by a hash chain over 2-byte prefixes. Each entry is the
+// closest match of its length, which is the cheapest to code. The hash
+// table grows with the input, so tiny payloads do not pay for a large one.
+//
+// depth bounds the number of chain links followed per position.
+func findMatches(data []byte, depth int) [][]match {
+	n := len(data)
+	all := make([][]match, n)
+
+	bits := 8
+	for bits < 16 && 1<<bits < n {
+		bits++
+	}
+	head := make([]int32, 1<<bits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+
+	for p := 0; p+minMatchLen <= n; p++ {
+		key := (uint32(data[p])<<8 | uint32(data[p+1])) * 2654435761 >> (32 - bits)
+		limit := min(maxMatchLen, n-p)
+
+		best := 1
+		var found []match
+		for cand, steps := head[key], 0; cand >= 0 && steps < depth; cand, steps = prev[cand], steps+1 {
+			dist := p - int(cand)
+			if dist > dictSize {
+				break
+			}
+
+			l := matchLen(data, int(cand), p, limit)
+			if l > best {
+				best = l
+				found = append(found, match{length: l, dist: uint32(dist - 1)})
+				if l == limit || l >= niceLen {
+					break
+				}
+			}
+		}
+		all[p] = found
+
+		prev[p] = head[key]
+		head[key] = int32(p)
+	}
+
+	return all
+}
+
+// matchLen returns the length of the common prefix of data[a:] and
+// data[b:], up to limit bytes.
+func matchLen(data []byte, a, b, limit int) int {
+	l := 0
+	for l < limit && data[a+l] == data[b+l] {
+		l++
+	}
+	return l
+}
@@ -0,0 +1,256 @@
+package lzma
+
+import "math/bits"
+
+// Profile of the bysquare specification: lc=3, lp=0, pb=2.
+const (
+	lc = 3
+	pb = 2
+
+	numStates    = 12
+	numPosStates = 1 << pb
+	posMask      = numPosStates - 1
+
+	minMatchLen = 2
+	maxMatchLen = 273
+
+	numLenToPosStates = 4
+	numPosSlots       = 64
+	endPosModelIndex  = 14
+	numFullDistances  = 1 << (endPosModelIndex >> 1)
+	numAlignBits      = 4
+)
+
+// lenModel codes match lengths 2-273.
+//
+//	choice=0            low[posState]   3 bits   2-9
+//	choice=1 choice2=0  mid[posState]   3 bits   10-17
+//	choice=1 choice2=1  high            8 bits   18-273
+type lenModel struct {
+	choice  prob
+	choice2 prob
+	low     [numPosStates][1 << 3]prob
+	mid     [numPosStates][1 << 3]prob
+	high    [1 << 8]prob
+}
+
+func (m *lenModel) encode(e *rangeEncoder, length int, posState int) {
+	l := uint32(length - minMatchLen)
+	switch {
+	case l < 8:
+		e.encodeBit(&m.choice, 0)
+		e.encodeTree(m.low[posState][:], 3, l)
+	case l < 16:
+		e.encodeBit(&m.choice, 1)
+		e.encodeBit(&m.choice2, 0)
+		e.encodeTree(m.mid[posState][:], 3, l-8)
+	default:
+		e.encodeBit(&m.choice, 1)
+		e.encodeBit(&m.choice2, 1)
+		e.encodeTree(m.high[:], 8, l-16)
+	}
+}
+
+// prices fills t[ps][l] with the cost of length l at posState ps, for
+// lengths up to maxLen.
+func (m *lenModel) prices(t *[numPosStates][maxMatchLen + 1]uint32, maxLen int) {
+	choice0, choice1 := m.choice.price(0), m.choice.price(1)
+	mid := choice1 + m.choice2.price(0)
+	high := choice1 + m.choice2.price(1)
+
+	for ps := 0; ps < numPosStates; ps++ {
+		for l := uint32(0); l < 8; l++ {
+			t[ps][minMatchLen+l] = choice0 + treePrice(m.low[ps][:], 3, l)
+			t[ps][minMatchLen+8+l] = mid + treePrice(m.mid[ps][:], 3, l)
+		}
+	}
+	for length := minMatchLen + 16; length <= maxLen; length++ {
+		price := high + treePrice(m.high[:], 8, uint32(length-minMatchLen-16))
+		for ps := 0; ps < numPosStates; ps++ {
+			t[ps][length] = price
+		}
+	}
+}
+
+// model holds every adaptive probability of an LZMA stream.
+type model struct {
+	isMatch    [numStates][numPosStates]prob
+	isRep      [numStates]prob
+	isRepG0    [numStates]prob
+	isRepG1    [numStates]prob
+	isRepG2    [numStates]prob
+	isRep0Long [numStates][numPosStates]prob
+
+	literal [1 << lc][0x300]prob
+
+	posSlot [numLenToPosStates][numPosSlots]prob
+	// posSpecial is indexed from 1 like the trees; index 0 is unused.
+	posSpecial [1 + numFullDistances - endPosModelIndex]prob
+	align      [1 << numAlignBits]prob
+
+	matchLen lenModel
+	repLen   lenModel
+}
+
+func newModel() *model {
+	m := &model{}
+	m.reset()
+	return m
+}
+
+// reset sets every probability to one half.
+func (m *model) reset() {
+	for _, s := range [][]prob{
+		m.isRep[:], m.isRepG0[:], m.isRepG1[:], m.isRepG2[:],
+		m.posSpecial[:], m.align[:],
+		m.matchLen.high[:], m.repLen.high[:],
+	} {
+		fill(s)
+	}
+	for i := range m.isMatch {
+		fill(m.isMatch[i][:])
+		fill(m.isRep0Long[i][:])
+	}
+	for i := range m.literal {
+		fill(m.literal[i][:])
+	}
+	for i := range m.posSlot {
+		fill(m.posSlot[i][:])
+	}
+	for _, lm := range []*lenModel{&m.matchLen, &m.repLen} {
+		lm.choice, lm.choice2 = probInit, probInit
+		for ps := range lm.low {
+			fill(lm.low[ps][:])
+			fill(lm.mid[ps][:])
+		}
+	}
+}
+
+func fill(s []prob) {
+	for i := range s {
+		s[i] = probInit
+	}
+}
+
+// State transitions. States below 7 follow a literal.
+func nextLiteral(s int) int {
+	switch {
+	case s < 4:
+		return 0
+	case s < 10:
+		return s - 3
+	default:
+		return s - 6
+	}
+}
+
+func nextMatch(s int) int    { return choose(s < 7, 7, 10) }
+func nextRep(s int) int      { return choose(s < 7, 8, 11) }
+func nextShortRep(s int) int { return choose(s < 7, 9, 11) }
+
+func choose(cond bool, a, b int) int {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// posSlot returns the slot of a zero-based distance: the two most
+// significant bits and the bit length.
+func posSlot(dist uint32) uint32 {
+	if dist < 4 {
+		return dist
+	}
+	n := uint32(bits.Len32(dist) - 1)
+	return n<<1 | (dist>>(n-1))&1
+}
+
+func lenToPosState(length int) int {
+	return min(length-minMatchLen, numLenToPosStates-1)
+}
+
+// encodeLiteral codes b. After a match the byte at rep0 steers the
+// probabilities until the first differing bit.
+func (m *model) encodeLiteral(e *rangeEncoder, b, prevByte, matchByte byte, state int) {
+	probs := m.literal[prevByte>>(8-lc)][:]
+	sym := uint32(1)
+	i := 7
+	if state >= 7 {
+		for ; i >= 0; i-- {
+			matchBit := uint32(matchByte>>i) & 1
+			bit := uint32(b>>i) & 1
+			e.encodeBit(&probs[(1+matchBit)<<8+sym], bit)
+			sym = sym<<1 | bit
+			if matchBit != bit {
+				i--
+				break
+			}
+		}
+	}
+	for ; i >= 0; i-- {
+		bit := uint32(b>>i) & 1
+		e.encodeBit(&probs[sym], bit)
+		sym = sym<<1 | bit
+	}
+}
+
+func (m *model) literalPrice(b, prevByte, matchByte byte, state int) uint32 {
+	probs := m.literal[prevByte>>(8-lc)][:]
+	var price uint32
+	sym := uint32(1)
+	i := 7
+	if state >= 7 {
+		for ; i >= 0; i-- {
+			matchBit := uint32(matchByte>>i) & 1
+			bit := uint32(b>>i) & 1
+			price += probs[(1+matchBit)<<8+sym].price(bit)
+			sym = sym<<1 | bit
+			if matchBit != bit {
+				i--
+				break
+			}
+		}
+	}
+	for ; i >= 0; i-- {
+		bit := uint32(b>>i) & 1
+		price += probs[sym].price(bit)
+		sym = sym<<1 | bit
+	}
+	return price
+}
+
+// encodeDistance codes a zero-based match distance.
+func (m *model) encodeDistance(e *rangeEncoder, dist uint32, length int) {
+	slot := posSlot(dist)
+	e.encodeTree(m.posSlot[lenToPosState(length)][:], 6, slot)
+	if slot < 4 {
+		return
+	}
+
+	footerBits := int(slot>>1) - 1
+	base := (2 | slot&1) << footerBits
+	reduced := dist - base
+	if slot < endPosModelIndex {
+		e.encodeReverseTree(m.posSpecial[base-slot:], footerBits, reduced)
+		return
+	}
+	e.encodeDirectBits(reduced>>numAlignBits, footerBits-numAlignBits)
+	e.encodeReverseTree(m.align[:], numAlignBits, reduced&(1<<numAlignBits-1))
+}
+
+func (m *model) distancePrice(dist uint32, length int) uint32 {
+	slot := posSlot(dist)
+	price := treePrice(m.posSlot[lenToPosState(length)][:], 6, slot)
+	if slot < 4 {
+		return price
+	}
+
+	footerBits := int(slot>>1) - 1
+	base := (2 | slot&1) << footerBits
+	reduced := dist - base
+	if slot < endPosModelIndex {
+		return price + reverseTreePrice(m.posSpecial[base-slot:], footerBits, reduced)
+	}
+	return price + uint32(footerBits-numAlignBits)<<priceShift +
+		reverseTreePrice(m.align[:], numAlignBits, reduced&(1<<numAlignBits-1))
+}
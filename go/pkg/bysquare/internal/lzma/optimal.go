@@ -0,0 +1,161 @@
+package lzma
+
+import "math"
+
+// niceLen is the match length from which only the full length is tried.
+// Shorter splits of such a long match rarely pay off and cost a price
+// evaluation each.
+const niceLen = 64
+
+type opKind uint8
+
+const (
+	opLiteral opKind = iota
+	opShortRep
+	opRep
+	opMatch
+)
+
+// op is one step of a parse: a literal, a 1-byte repeat of rep0, a repeat
+// of reps[rep], or a new match.
+type op struct {
+	kind   opKind
+	rep    int
+	dist   uint32
+	length int
+}
+
+// node is a position reached by the parse, with the coder state after the
+// cheapest known way to get there.
+type node struct {
+	price uint32
+	state int
+	reps  [4]uint32
+	prev  int32
+	op    op
+}
+
+// parse finds the cheapest sequence of ops for data under the prices of m.
+//
+// It is a shortest-path search over positions: every position is reached
+// from an earlier one by a literal, a short rep, a rep of any length or a
+// match of any length. The coder state and rep distances of a position are
+// those of its cheapest predecessor, so prices that depend on them are
+// exact along the chosen path. Probabilities are taken as fixed, which is
+// the only approximation.
+func parse(data []byte, matches [][]match, m *model) []op {
+	n := len(data)
+
+	var lenPrices, repLenPrices [numPosStates][maxMatchLen + 1]uint32
+	maxLen := min(maxMatchLen, n)
+	m.matchLen.prices(&lenPrices, maxLen)
+	m.repLen.prices(&repLenPrices, maxLen)
+
+	nodes := make([]node, n+1)
+	for i := range nodes {
+		nodes[i].price = math.MaxUint32
+	}
+	nodes[0].price = 0
+
+	for p := 0; p < n; p++ {
+		cur := &nodes[p]
+		s, ps := cur.state, p&posMask
+
+		relax := func(q int, price uint32, o op, state int, reps [4]uint32) {
+			if price < nodes[q].price {
+				nodes[q] = node{price: price, state: state, reps: reps, prev: int32(p), op: o}
+			}
+		}
+
+		// Literal
+		var prevByte, matchByte byte
+		if p > 0 {
+			prevByte = data[p-1]
+		}
+		if rep0 := int(cur.reps[0]); rep0 < p {
+			matchByte = data[p-rep0-1]
+		}
+		price := cur.price + m.isMatch[s][ps].price(0) + m.literalPrice(data[p], prevByte, matchByte, s)
+		relax(p+1, price, op{kind: opLiteral}, nextLiteral(s), cur.reps)
+
+		matchBase := cur.price + m.isMatch[s][ps].price(1)
+		repBase := matchBase + m.isRep[s].price(1)
+
+		// Short rep
+		if rep0 := int(cur.reps[0]); rep0 < p && data[p] == data[p-rep0-1] {
+			price := repBase + m.isRepG0[s].price(0) + m.isRep0Long[s][ps].price(0)
+			relax(p+1, price, op{kind: opShortRep}, nextShortRep(s), cur.reps)
+		}
+
+		// Reps
+		limit := min(maxMatchLen, n-p)
+		for r := 0; r < 4; r++ {
+			dist := int(cur.reps[r])
+			if dist >= p {
+				continue
+			}
+			l := matchLen(data, p-dist-1, p, limit)
+			if l < minMatchLen {
+				continue
+			}
+
+			base := repBase
+			switch r {
+			case 0:
+				base += m.isRepG0[s].price(0) + m.isRep0Long[s][ps].price(1)
+			case 1:
+				base += m.isRepG0[s].price(1) + m.isRepG1[s].price(0)
+			case 2:
+				base += m.isRepG0[s].price(1) + m.isRepG1[s].price(1) + m.isRepG2[s].price(0)
+			default:
+				base += m.isRepG0[s].price(1) + m.isRepG1[s].price(1) + m.isRepG2[s].price(1)
+			}
+
+			reps := cur.reps
+			copy(reps[1:r+1], cur.reps[:r])
+			reps[0] = cur.reps[r]
+			from := minMatchLen
+			if l >= niceLen {
+				from = l
+			}
+			for length := from; length <= l; length++ {
+				relax(p+length, base+repLenPrices[ps][length], op{kind: opRep, rep: r, length: length}, nextRep(s), reps)
+			}
+		}
+
+		// Matches
+		base := matchBase + m.isRep[s].price(0)
+		length := minMatchLen
+		if k := len(matches[p]); k > 0 && matches[p][k-1].length >= niceLen {
+			length = matches[p][k-1].length
+		}
+		for _, mt := range matches[p] {
+			if mt.length < length {
+				continue
+			}
+
+			var distPrices [numLenToPosStates]uint32
+			for i := range distPrices {
+				distPrices[i] = m.distancePrice(mt.dist, i+minMatchLen)
+			}
+
+			reps := [4]uint32{mt.dist, cur.reps[0], cur.reps[1], cur.reps[2]}
+			for ; length <= mt.length; length++ {
+				price := base + lenPrices[ps][length] + distPrices[lenToPosState(length)]
+				relax(p+length, price, op{kind: opMatch, dist: mt.dist, length: length}, nextMatch(s), reps)
+			}
+		}
+	}
+
+	// Walk back from the end.
+	count := 0
+	for q := n; q > 0; q = int(nodes[q].prev) {
+		count++
+	}
+	ops := make([]op, count)
+	for q := n; q > 0; q = int(nodes[q].prev) {
+		count--
+		ops[count] = nodes[q].op
+	}
+	return ops
+}
@@ -0,0 +1,153 @@
+package lzma
+
+import "math"
+
+const (
+	probBits = 11
+	probInit = 1 << (probBits - 1)
+	probMax  = 1 << probBits
+	moveBits = 5
+
+	// priceShift is the fixed-point precision of prices: one bit costs
+	// 1 << priceShift.
+	priceShift = 6
+)
+
+// prob is an adaptive probability that the next bit is 0, in units of
+// 1/2048.
+type prob uint16
+
+// probPrices holds the cost of coding a 0 bit for every probability.
+var probPrices = func() [probMax]uint32 {
+	var t [probMax]uint32
+	for p := 1; p < probMax; p++ {
+		t[p] = uint32(-math.Log2(float64(p)/probMax)*(1<<priceShift) + 0.5)
+	}
+	return t
+}()
+
+// price returns the cost of coding bit with p, without updating p.
+func (p prob) price(bit uint32) uint32 {
+	if bit == 0 {
+		return probPrices[p]
+	}
+	return probPrices[probMax-p]
+}
+
+// rangeEncoder is the arithmetic coder of the LZMA SDK.
+type rangeEncoder struct {
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int
+	out       []byte
+}
+
+func newRangeEncoder(capacity int) *rangeEncoder {
+	return &rangeEncoder{rng: 0xFFFFFFFF, cacheSize: 1, out: make([]byte, 0, capacity)}
+}
+
+// encodeBit codes bit with p and adapts p towards it.
+func (e *rangeEncoder) encodeBit(p *prob, bit uint32) {
+	bound := (e.rng >> probBits) * uint32(*p)
+	if bit == 0 {
+		e.rng = bound
+		*p += (probMax - *p) >> moveBits
+	} else {
+		e.low += uint64(bound)
+		e.rng -= bound
+		*p -= *p >> moveBits
+	}
+	for e.rng < 1<<24 {
+		e.rng <<= 8
+		e.shiftLow()
+	}
+}
+
+// encodeDirectBits codes the low n bits of v, most significant first, with
+// a fixed probability of one half.
+func (e *rangeEncoder) encodeDirectBits(v uint32, n int) {
+	for n > 0 {
+		n--
+		e.rng >>= 1
+		if (v>>n)&1 == 1 {
+			e.low += uint64(e.rng)
+		}
+		for e.rng < 1<<24 {
+			e.rng <<= 8
+			e.shiftLow()
+		}
+	}
+}
+
+func (e *rangeEncoder) shiftLow() {
+	if uint32(e.low) < 0xFF000000 || e.low>>32 != 0 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for {
+			e.out = append(e.out, temp+carry)
+			temp = 0xFF
+			e.cacheSize--
+			if e.cacheSize == 0 {
+				break
+			}
+		}
+		e.cache = byte(uint32(e.low) >> 24)
+	}
+	e.cacheSize++
+	e.low = uint64(uint32(e.low) << 8)
+}
+
+// flush writes the remaining state. The stream has no end marker; the
+// decoder stops at the uncompressed size from the header.
+func (e *rangeEncoder) flush() []byte {
+	for i := 0; i < 5; i++ {
+		e.shiftLow()
+	}
+	return e.out
+}
+
+// encodeTree codes the low bits of v, most significant first, with the
+// binary tree of probabilities probs.
+func (e *rangeEncoder) encodeTree(probs []prob, bits int, v uint32) {
+	m := uint32(1)
+	for i := bits - 1; i >= 0; i-- {
+		bit := (v >> i) & 1
+		e.encodeBit(&probs[m], bit)
+		m = m<<1 | bit
+	}
+}
+
+// encodeReverseTree codes the low bits of v, least significant first.
+func (e *rangeEncoder) encodeReverseTree(probs []prob, bits int, v uint32) {
+	m := uint32(1)
+	for i := 0; i < bits; i++ {
+		bit := v & 1
+		v >>= 1
+		e.encodeBit(&probs[m], bit)
+		m = m<<1 | bit
+	}
+}
+
+func treePrice(probs []prob, bits int, v uint32) uint32 {
+	var price uint32
+	m := uint32(1)
+	for i := bits - 1; i >= 0; i-- {
+		bit := (v >> i) & 1
+		price += probs[m].price(bit)
+		m = m<<1 | bit
+	}
+	return price
+}
+
+func reverseTreePrice(probs []prob, bits int, v uint32) uint32 {
+	var price uint32
+	m := uint32(1)
+	for i := 0; i < bits; i++ {
+		bit := v & 1
+		v >>= 1
+		price += probs[m].price(bit)
+		m = m<<1 | bit
+	}
+	return price
+}
@@ -0,0 +1,1038 @@
+package lzma
+
+import "hash/crc32"
+
+// Settings of the reference encoder. They are those of lzma1, the LZMA
+// library of the TypeScript implementation: a bt4 match finder, 128 fast
+// bytes and the dictionary of the bysquare profile.
+const (
+	refFastBytes     = 128
+	refCutValue      = 16 + refFastBytes/2
+	refNumOpts       = 1 << 12
+	refHashMask      = 1<<16 - 1
+	refDistTableSize = 17 * 2
+	refLenTableSize  = refFastBytes + 1 - minMatchLen
+
+	hash2Size = 1 << 10
+	hash3Size = 1 << 16
+
+	infinityPrice = 0x0FFFFFFF
+)
+
+// MaxReferenceInput is the longest input EncodeReference accepts. Past the
+// dictionary the window of the LZMA SDK slides, which EncodeReference does
+// not reproduce.
+const MaxReferenceInput = dictSize
+
+// EncodeReference compresses data the way the encoder of the LZMA SDK
+// does, byte for byte, and returns the LZMA body without the 13-byte
+// header. The stream ends with an end marker.
+//
+// lzma1, and with it the TypeScript implementation, runs that encoder, so
+// the output matches QR strings produced there. It is larger than the
+// output of Encode. Inputs longer than MaxReferenceInput are not
+// supported.
+func EncodeReference(data []byte) []byte {
+	z := newRefEncoder(data)
+	z.run()
+	return z.rc.flush()
+}
+
+// refProbPrices is the price table of the LZMA SDK. It approximates the
+// logarithm piecewise over probabilities reduced to 9 bits.
+var refProbPrices = func() [probMax >> 2]uint32 {
+	const numBits = probBits - 2
+
+	var t [probMax >> 2]uint32
+	for i := numBits - 1; i >= 0; i-- {
+		start, end := 1<<(numBits-i-1), 1<<(numBits-i)
+		for j := start; j < end; j++ {
+			t[j] = uint32(i<<priceShift + ((end-j)<<priceShift)>>(numBits-i-1))
+		}
+	}
+	return t
+}()
+
+func refPrice(p prob, bit uint32) uint32 {
+	if bit == 0 {
+		return refProbPrices[p>>2]
+	}
+	return refProbPrices[(probMax-p)>>2]
+}
+
+func refTreePrice(probs []prob, bits int, v uint32) uint32 {
+	var price uint32
+	m := uint32(1)
+	for i := bits - 1; i >= 0; i-- {
+		bit := (v >> i) & 1
+		price += refPrice(probs[m], bit)
+		m = m<<1 | bit
+	}
+	return price
+}
+
+func refReverseTreePrice(probs []prob, bits int, v uint32) uint32 {
+	var price uint32
+	m := uint32(1)
+	for i := 0; i < bits; i++ {
+		bit := v & 1
+		v >>= 1
+		price += refPrice(probs[m], bit)
+		m = m<<1 | bit
+	}
+	return price
+}
+
+func (m *model) refLiteralPrice(b, prevByte, matchByte byte, matched bool) uint32 {
+	probs := m.literal[prevByte>>(8-lc)][:]
+	var price uint32
+	sym := uint32(1)
+	i := 7
+	if matched {
+		for ; i >= 0; i-- {
+			matchBit := uint32(matchByte>>i) & 1
+			bit := uint32(b>>i) & 1
+			price += refPrice(probs[(1+matchBit)<<8+sym], bit)
+			sym = sym<<1 | bit
+			if matchBit != bit {
+				i--
+				break
+			}
+		}
+	}
+	for ; i >= 0; i-- {
+		bit := uint32(b>>i) & 1
+		price += refPrice(probs[sym], bit)
+		sym = sym<<1 | bit
+	}
+	return price
+}
+
+// lenPriceTable caches the prices of a length model per posState and
+// refreshes them after as many lengths as the table holds were coded.
+type lenPriceTable struct {
+	prices   [numPosStates][refLenTableSize]uint32
+	counters [numPosStates]int
+}
+
+func (t *lenPriceTable) update(lm *lenModel, ps int) {
+	choice0, choice1 := refPrice(lm.choice, 0), refPrice(lm.choice, 1)
+	mid := choice1 + refPrice(lm.choice2, 0)
+	high := choice1 + refPrice(lm.choice2, 1)
+
+	for i := uint32(0); i < refLenTableSize; i++ {
+		switch {
+		case i < 8:
+			t.prices[ps][i] = choice0 + refTreePrice(lm.low[ps][:], 3, i)
+		case i < 16:
+			t.prices[ps][i] = mid + refTreePrice(lm.mid[ps][:], 3, i-8)
+		default:
+			t.prices[ps][i] = high + refTreePrice(lm.high[:], 8, i-16)
+		}
+	}
+	t.counters[ps] = refLenTableSize
+}
+
+func (t *lenPriceTable) price(length, ps int) uint32 {
+	return t.prices[ps][length-minMatchLen]
+}
+
+// encode codes length and refreshes the prices of ps when they are due.
+func (t *lenPriceTable) encode(lm *lenModel, e *rangeEncoder, length, ps int) {
+	lm.encode(e, length, ps)
+	t.counters[ps]--
+	if t.counters[ps] == 0 {
+		t.update(lm, ps)
+	}
+}
+
+// binTree is the bt4 match finder of the LZMA SDK over an input that is
+// held in memory and fits the dictionary, so the cyclic buffer never
+// wraps. Positions are stored one-based; zero marks an empty slot.
+type binTree struct {
+	data  []byte
+	pos   int
+	son   []uint32
+	hash  map[uint32]uint32
+	hash2 map[uint32]uint32
+	hash3 map[uint32]uint32
+}
+
+func newBinTree(data []byte) *binTree {
+	return &binTree{
+		data:  data,
+		son:   make([]uint32, 2*(len(data)+1)),
+		hash:  make(map[uint32]uint32),
+		hash2: make(map[uint32]uint32),
+		hash3: make(map[uint32]uint32),
+	}
+}
+
+// hashes returns the 2-, 3- and 4-byte hashes of the current position.
+func (bt *binTree) hashes() (h2, h3, h4 uint32) {
+	d, p := bt.data, bt.pos
+	tmp := crc32.IEEETable[d[p]] ^ uint32(d[p+1])
+	h2 = tmp & (hash2Size - 1)
+	tmp ^= uint32(d[p+2]) << 8
+	h3 = tmp & (hash3Size - 1)
+	h4 = (tmp ^ crc32.IEEETable[d[p+3]]<<5) & refHashMask
+	return h2, h3, h4
+}
+
+// getMatches appends the matches of increasing length at the current
+// position to dist as (length, distance) pairs, inserts the position and
+// advances.
+func (bt *binTree) getMatches(dist []uint32) []uint32 {
+	d, p := bt.data, bt.pos
+	lenLimit := min(refFastBytes, len(d)-p)
+	if lenLimit < 4 {
+		bt.pos++
+		return dist
+	}
+
+	cur := uint32(p + 1)
+	maxLen := 1
+	h2, h3, h4 := bt.hashes()
+	curMatch := bt.hash[h4]
+	curMatch2, curMatch3 := bt.hash2[h2], bt.hash3[h3]
+	bt.hash2[h2], bt.hash3[h3] = cur, cur
+
+	if curMatch2 > 0 && d[curMatch2-1] == d[p] {
+		maxLen = 2
+		dist = append(dist, 2, cur-curMatch2-1)
+	}
+	if curMatch3 > 0 && d[curMatch3-1] == d[p] {
+		if curMatch3 == curMatch2 {
+			dist = dist[:len(dist)-2]
+		}
+		maxLen = 3
+		dist = append(dist, 3, cur-curMatch3-1)
+		curMatch2 = curMatch3
+	}
+	if len(dist) != 0 && curMatch2 == curMatch {
+		dist = dist[:len(dist)-2]
+		maxLen = 1
+	}
+	bt.hash[h4] = cur
+
+	ptr0, ptr1 := 2*p+1, 2*p
+	len0, len1 := 0, 0
+	for count := refCutValue; ; count-- {
+		if curMatch == 0 || count == 0 {
+			bt.son[ptr0], bt.son[ptr1] = 0, 0
+			break
+		}
+
+		delta := cur - curMatch
+		cyclic := 2 * (p - int(delta))
+		m := int(curMatch - 1)
+		length := min(len0, len1)
+		if d[m+length] == d[p+length] {
+			for length++; length != lenLimit; length++ {
+				if d[m+length] != d[p+length] {
+					break
+				}
+			}
+			if maxLen < length {
+				maxLen = length
+				dist = append(dist, uint32(length), delta-1)
+				if length == lenLimit {
+					bt.son[ptr1], bt.son[ptr0] = bt.son[cyclic], bt.son[cyclic+1]
+					break
+				}
+			}
+		}
+
+		if d[m+length] < d[p+length] {
+			bt.son[ptr1] = curMatch
+			ptr1 = cyclic + 1
+			curMatch = bt.son[ptr1]
+			len1 = length
+		} else {
+			bt.son[ptr0] = curMatch
+			ptr0 = cyclic
+			curMatch = bt.son[ptr0]
+			len0 = length
+		}
+	}
+
+	bt.pos++
+	return dist
+}
+
+// skip inserts n positions without reporting matches.
+func (bt *binTree) skip(n int) {
+	for ; n > 0; n-- {
+		d, p := bt.data, bt.pos
+		lenLimit := min(refFastBytes, len(d)-p)
+		if lenLimit < 4 {
+			bt.pos++
+			continue
+		}
+
+		cur := uint32(p + 1)
+		h2, h3, h4 := bt.hashes()
+		bt.hash2[h2], bt.hash3[h3] = cur, cur
+		curMatch := bt.hash[h4]
+		bt.hash[h4] = cur
+
+		ptr0, ptr1 := 2*p+1, 2*p
+		len0, len1 := 0, 0
+		for count := refCutValue; ; count-- {
+			if curMatch == 0 || count == 0 {
+				bt.son[ptr0], bt.son[ptr1] = 0, 0
+				break
+			}
+
+			cyclic := 2 * (p - int(cur-curMatch))
+			m := int(curMatch - 1)
+			length := min(len0, len1)
+			if d[m+length] == d[p+length] {
+				for length++; length != lenLimit; length++ {
+					if d[m+length] != d[p+length] {
+						break
+					}
+				}
+				if length == lenLimit {
+					bt.son[ptr1], bt.son[ptr0] = bt.son[cyclic], bt.son[cyclic+1]
+					break
+				}
+			}
+
+			if d[m+length] < d[p+length] {
+				bt.son[ptr1] = curMatch
+				ptr1 = cyclic + 1
+				curMatch = bt.son[ptr1]
+				len1 = length
+			} else {
+				bt.son[ptr0] = curMatch
+				ptr0 = cyclic
+				curMatch = bt.son[ptr0]
+				len0 = length
+			}
+		}
+
+		bt.pos++
+	}
+}
+
+// byteAt returns the byte at offset i from the match finder position.
+func (bt *binTree) byteAt(i int) byte {
+	return bt.data[bt.pos+i]
+}
+
+// matchLen returns how many bytes from offset i match the bytes dist+1
+// before them, up to limit.
+func (bt *binTree) matchLen(i int, dist uint32, limit int) int {
+	p := bt.pos + i
+	limit = min(limit, len(bt.data)-p)
+	back := p - int(dist) - 1
+	n := 0
+	for n < limit && bt.data[p+n] == bt.data[back+n] {
+		n++
+	}
+	return n
+}
+
+func (bt *binTree) available() int {
+	return len(bt.data) - bt.pos
+}
+
+// Back references of the optimum table: a literal, a rep index below 4 or
+// a distance plus 4.
+const (
+	backLiteral = 0xFFFFFFFF
+	numReps     = 4
+)
+
+// optimal is an entry of the optimum table of the LZMA SDK: the cheapest
+// known way to reach a position of the block being parsed.
+type optimal struct {
+	state int
+	price uint32
+
+	posPrev  int
+	backPrev uint32
+
+	// prev1IsChar marks a literal before the step; prev2 a rep or match
+	// before that literal, at posPrev2 with backPrev2.
+	prev1IsChar bool
+	prev2       bool
+	posPrev2    int
+	backPrev2   uint32
+
+	backs [numReps]uint32
+}
+
+func (o *optimal) makeAsChar() {
+	o.backPrev = backLiteral
+	o.prev1IsChar = false
+}
+
+func (o *optimal) makeAsShortRep() {
+	o.backPrev = 0
+	o.prev1IsChar = false
+}
+
+func (o *optimal) isShortRep() bool {
+	return o.backPrev == 0
+}
+
+// refEncoder is the encoder of the LZMA SDK for the reference settings.
+type refEncoder struct {
+	mf *binTree
+	rc *rangeEncoder
+	m  *model
+
+	state    int
+	reps     [numReps]uint32
+	prevByte byte
+	nowPos   int
+
+	// additionalOffset is how far the match finder is ahead of nowPos.
+	additionalOffset int
+
+	optimum         [refNumOpts]optimal
+	optimumEnd      int
+	optimumCur      int
+	longestMatchLen int
+	longestMatch    bool
+	backRes         uint32
+
+	matches  []uint32
+	curReps  [numReps]uint32
+	repLens  [numReps]int
+	lenTable lenPriceTable
+	repTable lenPriceTable
+
+	posSlotPrices   [numLenToPosStates][refDistTableSize]uint32
+	distancePrices  [numLenToPosStates][numFullDistances]uint32
+	alignPrices     [1 << numAlignBits]uint32
+	matchPriceCount int
+	alignPriceCount int
+}
+
+func newRefEncoder(data []byte) *refEncoder {
+	z := &refEncoder{
+		mf:      newBinTree(data),
+		rc:      newRangeEncoder(len(data)),
+		m:       newModel(),
+		matches: make([]uint32, 0, 2*(maxMatchLen+1)),
+	}
+	for ps := 0; ps < numPosStates; ps++ {
+		z.lenTable.update(&z.m.matchLen, ps)
+		z.repTable.update(&z.m.repLen, ps)
+	}
+	z.fillDistancePrices()
+	z.fillAlignPrices()
+	return z
+}
+
+func (z *refEncoder) fillDistancePrices() {
+	var special [numFullDistances]uint32
+	for i := uint32(4); i < numFullDistances; i++ {
+		slot := posSlot(i)
+		footerBits := int(slot>>1) - 1
+		base := (2 | slot&1) << footerBits
+		special[i] = refReverseTreePrice(z.m.posSpecial[base-slot:], footerBits, i-base)
+	}
+
+	for lps := 0; lps < numLenToPosStates; lps++ {
+		slots := &z.posSlotPrices[lps]
+		for slot := uint32(0); slot < refDistTableSize; slot++ {
+			slots[slot] = refTreePrice(z.m.posSlot[lps][:], 6, slot)
+		}
+		for slot := uint32(endPosModelIndex); slot < refDistTableSize; slot++ {
+			slots[slot] += (slot>>1 - 1 - numAlignBits) << priceShift
+		}
+
+		for i := uint32(0); i < numFullDistances; i++ {
+			if i < 4 {
+				z.distancePrices[lps][i] = slots[i]
+			} else {
+				z.distancePrices[lps][i] = slots[posSlot(i)] + special[i]
+			}
+		}
+	}
+	z.matchPriceCount = 0
+}
+
+func (z *refEncoder) fillAlignPrices() {
+	for i := range z.alignPrices {
+		z.alignPrices[i] = refReverseTreePrice(z.m.align[:], numAlignBits, uint32(i))
+	}
+	z.alignPriceCount = 0
+}
+
+// readMatches runs the match finder at its position and returns the
+// longest match length, extended past the fast bytes when reached.
+func (z *refEncoder) readMatches() int {
+	z.matches = z.mf.getMatches(z.matches[:0])
+	longest := 0
+	if n := len(z.matches); n > 0 {
+		longest = int(z.matches[n-2])
+		if longest == refFastBytes {
+			longest += z.mf.matchLen(longest-1, z.matches[n-1], maxMatchLen-longest)
+		}
+	}
+	z.additionalOffset++
+	return longest
+}
+
+func (z *refEncoder) movePos(n int) {
+	if n > 0 {
+		z.additionalOffset += n
+		z.mf.skip(n)
+	}
+}
+
+func (z *refEncoder) pureRepPrice(rep, state, ps int) uint32 {
+	m := z.m
+	if rep == 0 {
+		return refPrice(m.isRepG0[state], 0) + refPrice(m.isRep0Long[state][ps], 1)
+	}
+	price := refPrice(m.isRepG0[state], 1)
+	if rep == 1 {
+		return price + refPrice(m.isRepG1[state], 0)
+	}
+	return price + refPrice(m.isRepG1[state], 1) + refPrice(m.isRepG2[state], uint32(rep-2))
+}
+
+func (z *refEncoder) repPrice(rep, length, state, ps int) uint32 {
+	return z.repTable.price(length, ps) + z.pureRepPrice(rep, state, ps)
+}
+
+func (z *refEncoder) shortRepPrice(state, ps int) uint32 {
+	return refPrice(z.m.isRepG0[state], 0) + refPrice(z.m.isRep0Long[state][ps], 0)
+}
+
+func (z *refEncoder) posLenPrice(dist uint32, length, ps int) uint32 {
+	lps := lenToPosState(length)
+	var price uint32
+	if dist < numFullDistances {
+		price = z.distancePrices[lps][dist]
+	} else {
+		price = z.posSlotPrices[lps][posSlot(dist)] + z.alignPrices[dist&(1<<numAlignBits-1)]
+	}
+	return price + z.lenTable.price(length, ps)
+}
+
+// backward walks the parse from cur back to the start of the block,
+// linking the entries forward, and returns the length of the first step.
+func (z *refEncoder) backward(cur int) int {
+	opt := &z.optimum
+	z.optimumEnd = cur
+	posMem := opt[cur].posPrev
+	backMem := opt[cur].backPrev
+	for {
+		if opt[cur].prev1IsChar {
+			opt[posMem].makeAsChar()
+			opt[posMem].posPrev = posMem - 1
+			if opt[cur].prev2 {
+				opt[posMem-1].prev1IsChar = false
+				opt[posMem-1].posPrev = opt[cur].posPrev2
+				opt[posMem-1].backPrev = opt[cur].backPrev2
+			}
+		}
+		posPrev, backCur := posMem, backMem
+		backMem = opt[posPrev].backPrev
+		posMem = opt[posPrev].posPrev
+		opt[posPrev].backPrev = backCur
+		opt[posPrev].posPrev = cur
+		cur = posPrev
+		if cur == 0 {
+			break
+		}
+	}
+	z.backRes = opt[0].backPrev
+	z.optimumCur = opt[0].posPrev
+	return z.optimumCur
+}
+
+// getOptimum returns the length of the next step, with its back
+// reference in backRes. It parses up to refNumOpts bytes ahead and hands
+// out the steps of that parse on later calls.
+func (z *refEncoder) getOptimum(position int) int {
+	opt := &z.optimum
+	if z.optimumEnd != z.optimumCur {
+		length := opt[z.optimumCur].posPrev - z.optimumCur
+		z.backRes = opt[z.optimumCur].backPrev
+		z.optimumCur = opt[z.optimumCur].posPrev
+		return length
+	}
+	z.optimumEnd, z.optimumCur = 0, 0
+
+	var lenMain int
+	if z.longestMatch {
+		lenMain = z.longestMatchLen
+		z.longestMatch = false
+	} else {
+		lenMain = z.readMatches()
+	}
+	numPairs := len(z.matches)
+
+	if z.mf.available()+1 < 2 {
+		z.backRes = backLiteral
+		return 1
+	}
+
+	repMax := 0
+	for i := 0; i < numReps; i++ {
+		z.curReps[i] = z.reps[i]
+		z.repLens[i] = z.mf.matchLen(-1, z.curReps[i], maxMatchLen)
+		if z.repLens[i] > z.repLens[repMax] {
+			repMax = i
+		}
+	}
+	if z.repLens[repMax] >= refFastBytes {
+		z.backRes = uint32(repMax)
+		length := z.repLens[repMax]
+		z.movePos(length - 1)
+		return length
+	}
+	if lenMain >= refFastBytes {
+		z.backRes = z.matches[numPairs-1] + numReps
+		z.movePos(lenMain - 1)
+		return lenMain
+	}
+
+	curByte := z.mf.byteAt(-1)
+	matchByte := z.mf.byteAt(-int(z.reps[0]) - 2)
+	if lenMain < 2 && curByte != matchByte && z.repLens[repMax] < 2 {
+		z.backRes = backLiteral
+		return 1
+	}
+
+	m := z.m
+	state := z.state
+	opt[0].state = state
+	ps := position & posMask
+	opt[1].price = refPrice(m.isMatch[state][ps], 0) +
+		m.refLiteralPrice(curByte, z.prevByte, matchByte, state >= 7)
+	opt[1].makeAsChar()
+
+	matchPrice := refPrice(m.isMatch[state][ps], 1)
+	repMatchPrice := matchPrice + refPrice(m.isRep[state], 1)
+	if matchByte == curByte {
+		shortRepPrice := repMatchPrice + z.shortRepPrice(state, ps)
+		if shortRepPrice < opt[1].price {
+			opt[1].price = shortRepPrice
+			opt[1].makeAsShortRep()
+		}
+	}
+
+	lenEnd := max(lenMain, z.repLens[repMax])
+	if lenEnd < 2 {
+		z.backRes = opt[1].backPrev
+		return 1
+	}
+
+	opt[1].posPrev = 0
+	opt[0].backs = z.curReps
+	for length := lenEnd; length >= 2; length-- {
+		opt[length].price = infinityPrice
+	}
+
+	for i := 0; i < numReps; i++ {
+		if z.repLens[i] < 2 {
+			continue
+		}
+		price := repMatchPrice + z.pureRepPrice(i, state, ps)
+		for length := z.repLens[i]; length >= 2; length-- {
+			p := price + z.repTable.price(length, ps)
+			if o := &opt[length]; p < o.price {
+				o.price = p
+				o.posPrev = 0
+				o.backPrev = uint32(i)
+				o.prev1IsChar = false
+			}
+		}
+	}
+
+	normalMatchPrice := matchPrice + refPrice(m.isRep[state], 0)
+	length := 2
+	if z.repLens[0] >= 2 {
+		length = z.repLens[0] + 1
+	}
+	if length <= lenMain {
+		offs := 0
+		for length > int(z.matches[offs]) {
+			offs += 2
+		}
+		for ; ; length++ {
+			dist := z.matches[offs+1]
+			p := normalMatchPrice + z.posLenPrice(dist, length, ps)
+			if o := &opt[length]; p < o.price {
+				o.price = p
+				o.posPrev = 0
+				o.backPrev = dist + numReps
+				o.prev1IsChar = false
+			}
+			if length == int(z.matches[offs]) {
+				offs += 2
+				if offs == numPairs {
+					break
+				}
+			}
+		}
+	}
+
+	for cur := 1; ; cur++ {
+		if cur == lenEnd {
+			return z.backward(cur)
+		}
+
+		newLen := z.readMatches()
+		numPairs = len(z.matches)
+		if newLen >= refFastBytes {
+			z.longestMatchLen = newLen
+			z.longestMatch = true
+			return z.backward(cur)
+		}
+
+		position++
+		posPrev := opt[cur].posPrev
+		var state int
+		if opt[cur].prev1IsChar {
+			posPrev--
+			if opt[cur].prev2 {
+				state = opt[opt[cur].posPrev2].state
+				if opt[cur].backPrev2 < numReps {
+					state = nextRep(state)
+				} else {
+					state = nextMatch(state)
+				}
+			} else {
+				state = opt[posPrev].state
+			}
+			state = nextLiteral(state)
+		} else {
+			state = opt[posPrev].state
+		}
+
+		if posPrev == cur-1 {
+			if opt[cur].isShortRep() {
+				state = nextShortRep(state)
+			} else {
+				state = nextLiteral(state)
+			}
+		} else {
+			var back uint32
+			if opt[cur].prev1IsChar && opt[cur].prev2 {
+				posPrev = opt[cur].posPrev2
+				back = opt[cur].backPrev2
+				state = nextRep(state)
+			} else {
+				back = opt[cur].backPrev
+				if back < numReps {
+					state = nextRep(state)
+				} else {
+					state = nextMatch(state)
+				}
+			}
+
+			prev := opt[posPrev].backs
+			switch {
+			case back < numReps:
+				z.curReps[0] = prev[back]
+				j := 1
+				for i := uint32(0); i < numReps; i++ {
+					if i != back {
+						z.curReps[j] = prev[i]
+						j++
+					}
+				}
+			default:
+				z.curReps = [numReps]uint32{back - numReps, prev[0], prev[1], prev[2]}
+			}
+		}
+		opt[cur].state = state
+		opt[cur].backs = z.curReps
+
+		curPrice := opt[cur].price
+		curByte = z.mf.byteAt(-1)
+		matchByte = z.mf.byteAt(-int(z.curReps[0]) - 2)
+		ps = position & posMask
+
+		curAnd1Price := curPrice + refPrice(m.isMatch[state][ps], 0) +
+			m.refLiteralPrice(curByte, z.mf.byteAt(-2), matchByte, state >= 7)
+
+		next := &opt[cur+1]
+		nextIsChar := false
+		if curAnd1Price < next.price {
+			next.price = curAnd1Price
+			next.posPrev = cur
+			next.makeAsChar()
+			nextIsChar = true
+		}
+
+		matchPrice = curPrice + refPrice(m.isMatch[state][ps], 1)
+		repMatchPrice = matchPrice + refPrice(m.isRep[state], 1)
+		if matchByte == curByte && !(next.posPrev < cur && next.backPrev == 0) {
+			shortRepPrice := repMatchPrice + z.shortRepPrice(state, ps)
+			if shortRepPrice <= next.price {
+				next.price = shortRepPrice
+				next.posPrev = cur
+				next.makeAsShortRep()
+				nextIsChar = true
+			}
+		}
+
+		availableFull := min(refNumOpts-1-cur, z.mf.available()+1)
+		if availableFull < 2 {
+			continue
+		}
+		available := min(availableFull, refFastBytes)
+
+		if !nextIsChar && matchByte != curByte {
+			// A literal followed by a rep0 match.
+			lenTest2 := z.mf.matchLen(0, z.curReps[0], min(availableFull-1, refFastBytes))
+			if lenTest2 >= 2 {
+				state2 := nextLiteral(state)
+				psNext := (position + 1) & posMask
+				nextRepMatchPrice := curAnd1Price + refPrice(m.isMatch[state2][psNext], 1) +
+					refPrice(m.isRep[state2], 1)
+				offset := cur + 1 + lenTest2
+				for lenEnd < offset {
+					lenEnd++
+					opt[lenEnd].price = infinityPrice
+				}
+				p := nextRepMatchPrice + z.repPrice(0, lenTest2, state2, psNext)
+				if o := &opt[offset]; p < o.price {
+					o.price = p
+					o.posPrev = cur + 1
+					o.backPrev = 0
+					o.prev1IsChar = true
+					o.prev2 = false
+				}
+			}
+		}
+
+		startLen := 2
+		for rep := 0; rep < numReps; rep++ {
+			lenTest := z.mf.matchLen(-1, z.curReps[rep], available)
+			if lenTest < 2 {
+				continue
+			}
+
+			for l := lenTest; l >= 2; l-- {
+				for lenEnd < cur+l {
+					lenEnd++
+					opt[lenEnd].price = infinityPrice
+				}
+				p := repMatchPrice + z.repPrice(rep, l, state, ps)
+				if o := &opt[cur+l]; p < o.price {
+					o.price = p
+					o.posPrev = cur
+					o.backPrev = uint32(rep)
+					o.prev1IsChar = false
+				}
+			}
+
+			if rep == 0 {
+				startLen = lenTest + 1
+			}
+
+			// The rep, a literal and a rep0 match.
+			if lenTest < availableFull {
+				limit := min(availableFull-1-lenTest, refFastBytes)
+				lenTest2 := z.mf.matchLen(lenTest, z.curReps[rep], limit)
+				if lenTest2 >= 2 {
+					state2 := nextRep(state)
+					psNext := (position + lenTest) & posMask
+					curAndLenCharPrice := repMatchPrice + z.repPrice(rep, lenTest, state, ps) +
+						refPrice(m.isMatch[state2][psNext], 0) +
+						m.refLiteralPrice(z.mf.byteAt(lenTest-1), z.mf.byteAt(lenTest-2),
+							z.mf.byteAt(lenTest-1-int(z.curReps[rep]+1)), true)
+					state2 = nextLiteral(state2)
+					psNext = (position + lenTest + 1) & posMask
+					nextMatchPrice := curAndLenCharPrice + refPrice(m.isMatch[state2][psNext], 1)
+					nextRepMatchPrice := nextMatchPrice + refPrice(m.isRep[state2], 1)
+
+					offset := lenTest + 1 + lenTest2
+					for lenEnd < cur+offset {
+						lenEnd++
+						opt[lenEnd].price = infinityPrice
+					}
+					p := nextRepMatchPrice + z.repPrice(0, lenTest2, state2, psNext)
+					if o := &opt[cur+offset]; p < o.price {
+						o.price = p
+						o.posPrev = cur + lenTest + 1
+						o.backPrev = 0
+						o.prev1IsChar = true
+						o.prev2 = true
+						o.posPrev2 = cur
+						o.backPrev2 = uint32(rep)
+					}
+				}
+			}
+		}
+
+		if newLen > available {
+			newLen = available
+			numPairs = 0
+			for newLen > int(z.matches[numPairs]) {
+				numPairs += 2
+			}
+			z.matches[numPairs] = uint32(newLen)
+			numPairs += 2
+		}
+		if newLen < startLen {
+			continue
+		}
+
+		normalMatchPrice = matchPrice + refPrice(m.isRep[state], 0)
+		for lenEnd < cur+newLen {
+			lenEnd++
+			opt[lenEnd].price = infinityPrice
+		}
+		offs := 0
+		for startLen > int(z.matches[offs]) {
+			offs += 2
+		}
+
+		for lenTest := startLen; ; lenTest++ {
+			curBack := z.matches[offs+1]
+			p := normalMatchPrice + z.posLenPrice(curBack, lenTest, ps)
+			if o := &opt[cur+lenTest]; p < o.price {
+				o.price = p
+				o.posPrev = cur
+				o.backPrev = curBack + numReps
+				o.prev1IsChar = false
+			}
+			if lenTest != int(z.matches[offs]) {
+				continue
+			}
+
+			// The match, a literal and a rep0 match.
+			if lenTest < availableFull {
+				limit := min(availableFull-1-lenTest, refFastBytes)
+				lenTest2 := z.mf.matchLen(lenTest, curBack, limit)
+				if lenTest2 >= 2 {
+					state2 := nextMatch(state)
+					psNext := (position + lenTest) & posMask
+					curAndLenCharPrice := p + refPrice(m.isMatch[state2][psNext], 0) +
+						m.refLiteralPrice(z.mf.byteAt(lenTest-1), z.mf.byteAt(lenTest-2),
+							z.mf.byteAt(lenTest-int(curBack+1)-1), true)
+					state2 = nextLiteral(state2)
+					psNext = (position + lenTest + 1) & posMask
+					nextMatchPrice := curAndLenCharPrice + refPrice(m.isMatch[state2][psNext], 1)
+					nextRepMatchPrice := nextMatchPrice + refPrice(m.isRep[state2], 1)
+
+					offset := lenTest + 1 + lenTest2
+					for lenEnd < cur+offset {
+						lenEnd++
+						opt[lenEnd].price = infinityPrice
+					}
+					p = nextRepMatchPrice + z.repPrice(0, lenTest2, state2, psNext)
+					if o := &opt[cur+offset]; p < o.price {
+						o.price = p
+						o.posPrev = cur + lenTest + 1
+						o.backPrev = 0
+						o.prev1IsChar = true
+						o.prev2 = true
+						o.posPrev2 = cur
+						o.backPrev2 = curBack + numReps
+					}
+				}
+			}
+			offs += 2
+			if offs == numPairs {
+				break
+			}
+		}
+	}
+}
+
+// run codes the whole input followed by the end marker.
+func (z *refEncoder) run() {
+	e, m, mf := z.rc, z.m, z.mf
+
+	if mf.available() > 0 {
+		z.readMatches()
+		e.encodeBit(&m.isMatch[z.state][0], 0)
+		curByte := mf.byteAt(-z.additionalOffset)
+		m.encodeLiteral(e, curByte, 0, 0, z.state)
+		z.state = nextLiteral(z.state)
+		z.prevByte = curByte
+		z.additionalOffset--
+		z.nowPos++
+	}
+
+	for mf.available() > 0 || z.additionalOffset > 0 {
+		length := z.getOptimum(z.nowPos)
+		back := z.backRes
+		ps := z.nowPos & posMask
+
+		switch {
+		case length == 1 && back == backLiteral:
+			e.encodeBit(&m.isMatch[z.state][ps], 0)
+			curByte := mf.byteAt(-z.additionalOffset)
+			var matchByte byte
+			if z.state >= 7 {
+				matchByte = mf.byteAt(-int(z.reps[0]) - 1 - z.additionalOffset)
+			}
+			m.encodeLiteral(e, curByte, z.prevByte, matchByte, z.state)
+			z.prevByte = curByte
+			z.state = nextLiteral(z.state)
+
+		case back < numReps:
+			e.encodeBit(&m.isMatch[z.state][ps], 1)
+			e.encodeBit(&m.isRep[z.state], 1)
+			if back == 0 {
+				e.encodeBit(&m.isRepG0[z.state], 0)
+				e.encodeBit(&m.isRep0Long[z.state][ps], uint32(choose(length == 1, 0, 1)))
+			} else {
+				e.encodeBit(&m.isRepG0[z.state], 1)
+				if back == 1 {
+					e.encodeBit(&m.isRepG1[z.state], 0)
+				} else {
+					e.encodeBit(&m.isRepG1[z.state], 1)
+					e.encodeBit(&m.isRepG2[z.state], back-2)
+				}
+			}
+			if length == 1 {
+				z.state = nextShortRep(z.state)
+			} else {
+				z.repTable.encode(&m.repLen, e, length, ps)
+				z.state = nextRep(z.state)
+			}
+			dist := z.reps[back]
+			copy(z.reps[1:back+1], z.reps[:back])
+			z.reps[0] = dist
+			z.prevByte = mf.byteAt(length - 1 - z.additionalOffset)
+
+		default:
+			e.encodeBit(&m.isMatch[z.state][ps], 1)
+			e.encodeBit(&m.isRep[z.state], 0)
+			z.state = nextMatch(z.state)
+			z.lenTable.encode(&m.matchLen, e, length, ps)
+			dist := back - numReps
+			m.encodeDistance(e, dist, length)
+			if posSlot(dist) >= endPosModelIndex {
+				z.alignPriceCount++
+			}
+			z.reps = [numReps]uint32{dist, z.reps[0], z.reps[1], z.reps[2]}
+			z.matchPriceCount++
+			z.prevByte = mf.byteAt(length - 1 - z.additionalOffset)
+		}
+
+		z.additionalOffset -= length
+		z.nowPos += length
+		if z.additionalOffset == 0 {
+			if z.matchPriceCount >= 1<<7 {
+				z.fillDistancePrices()
+			}
+			if z.alignPriceCount >= 1<<numAlignBits {
+				z.fillAlignPrices()
+			}
+		}
+	}
+
+	// End marker: a match with the largest distance.
+	ps := z.nowPos & posMask
+	e.encodeBit(&m.isMatch[z.state][ps], 1)
+	e.encodeBit(&m.isRep[z.state], 0)
+	m.matchLen.encode(e, minMatchLen, ps)
+	m.encodeDistance(e, 0xFFFFFFFF, minMatchLen)
+}
@@ -0,0 +1,48 @@
+package invoice
+
+import (
+	"fmt"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+// codecNames names the registered codec of every document type.
+var codecNames = map[InvoiceDocumentType]string{
+	InvoiceDocumentTypeInvoice:         "invoice",
+	InvoiceDocumentTypeProformaInvoice: "proforma-invoice",
+	InvoiceDocumentTypeCreditNote:      "credit-note",
+	InvoiceDocumentTypeDebitNote:       "debit-note",
+	InvoiceDocumentTypeAdvanceInvoice:  "advance-invoice",
+}
+
+func init() {
+	schema := bysquare.JSONSchema(DataModel{})
+
+	for documentType, name := range codecNames {
+		bysquare.RegisterCodec(bysquare.Codec{
+			Name:         name,
+			BysquareType: 0x01,
+			DocumentType: uint8(documentType),
+			New:          func() bysquare.Document { return &DataModel{DocumentType: documentType} },
+			Encode: func(doc bysquare.Document) (string, error) {
+				model, ok := doc.(*DataModel)
+				if !ok {
+					return "", fmt.Errorf("%w: %T is not a *invoice.DataModel", bysquare.ErrUnexpectedType, doc)
+				}
+				if model.DocumentType != documentType {
+					return "", fmt.Errorf("%w: documentType %d, codec %q encodes %d",
+						bysquare.ErrUnexpectedType, model.DocumentType, name, documentType)
+				}
+				return Encode(model)
+			},
+			Decode: func(qr string) (bysquare.Document, error) {
+				model, err := Decode(qr)
+				if err != nil {
+					return nil, err
+				}
+				return model, nil
+			},
+			Schema: schema,
+		})
+	}
+}
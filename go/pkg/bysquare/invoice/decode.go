@@ -1,8 +1,9 @@
 package invoice
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
@@ -10,75 +11,108 @@ import (
 
 // deserialize parses a tab-separated intermediate format into DataModel.
 //
-// Field order follows the specification (40 + N*5 fields).
-func deserialize(tabString string, documentType InvoiceDocumentType) (*DataModel, error) {
-	data := strings.Split(tabString, "	")
+// Field order follows the specification (40 + N*5 fields). Missing trailing
+// fields read as empty, fields past the end are kept in DataModel.Extra. In
+// lenient mode the first missing field and every malformed number are
+// returned as problems instead of failing. In strict mode the same problems,
+// and any trailing fields, are returned wrapped in bysquare.ErrNonCanonical.
+func deserialize(tabString string, documentType InvoiceDocumentType, options DecodeOptions) (*DataModel, []error, error) {
+	data := strings.Split(tabString, "\t")
 	i := 0
 
-	next := func() string {
+	var problems []error
+	report := func(err *bysquare.FieldError) error {
+		switch {
+		case options.Lenient:
+			problems = append(problems, err)
+		case options.Strict:
+			err.Err = fmt.Errorf("%w: %w", bysquare.ErrNonCanonical, err.Err)
+			problems = append(problems, err)
+		default:
+			return err
+		}
+		return nil
+	}
+
+	truncated := false
+	next := func(name string) string {
 		if i < len(data) {
 			v := data[i]
 			i++
 			return v
 		}
+		if !truncated {
+			truncated = true
+			// Missing trailing fields are tolerated by default.
+			_ = report(&bysquare.FieldError{Index: len(data), Name: name, Err: bysquare.ErrTruncatedPayload})
+		}
 		i++
 		return ""
 	}
 
-	nextString := func() string {
-		v := next()
-		if v == "" {
-			return ""
-		}
-		return v
+	nextString := func(name string) string {
+		return next(name)
 	}
 
-	nextFloat := func() (float64, error) {
-		return bysquare.ParseFloat(next())
+	nextDecimal := func(name string) (bysquare.Decimal, error) {
+		index := i
+		v, err := bysquare.ParseDecimal(next(name))
+		if err != nil {
+			return "", report(&bysquare.FieldError{Index: index, Name: name, Err: err})
+		}
+		return v, nil
 	}
 
-	nextInt := func() (int, error) {
-		return bysquare.ParseNumber(next())
+	nextInt := func(name string) (int, error) {
+		index := i
+		v, err := bysquare.ParseNumber(next(name))
+		if err != nil {
+			return 0, report(&bysquare.FieldError{Index: index, Name: name, Err: err})
+		}
+		if v < 0 {
+			return 0, report(&bysquare.FieldError{Index: index, Name: name, Err: fmt.Errorf("negative value %d", v)})
+		}
+		return v, nil
 	}
 
 	model := &DataModel{}
 	model.DocumentType = documentType
 
 	// Core fields (9)
-	model.InvoiceID = nextString()
-	model.IssueDate = nextString()
-	model.TaxPointDate = nextString()
-	model.OrderID = nextString()
-	model.DeliveryNoteID = nextString()
-	model.LocalCurrencyCode = nextString()
-	model.ForeignCurrencyCode = nextString()
+	model.InvoiceID = nextString("invoiceId")
+	model.IssueDate = nextString("issueDate")
+	model.TaxPointDate = nextString("taxPointDate")
+	model.OrderID = nextString("orderId")
+	model.DeliveryNoteID = nextString("deliveryNoteId")
+	model.LocalCurrencyCode = nextString("localCurrencyCode")
+	model.ForeignCurrencyCode = nextString("foreignCurrencyCode")
 
 	var err error
-	model.CurrRate, err = nextFloat()
+	model.CurrRate, err = nextDecimal("currRate")
 	if err != nil {
-		return nil, fmt.Errorf("invalid currRate: %w", err)
+		return nil, nil, err
 	}
-	model.ReferenceCurrRate, err = nextFloat()
+	model.ReferenceCurrRate, err = nextDecimal("referenceCurrRate")
 	if err != nil {
-		return nil, fmt.Errorf("invalid referenceCurrRate: %w", err)
+		return nil, nil, err
 	}
 
 	// Supplier party (13 fields)
-	model.SupplierParty.PartyName = nextString()
-	model.SupplierParty.CompanyTaxID = nextString()
-	model.SupplierParty.CompanyVatID = nextString()
-	model.SupplierParty.CompanyRegisterID = nextString()
-
-	model.SupplierParty.PostalAddress.StreetName = nextString()
-	model.SupplierParty.PostalAddress.BuildingNumber = nextString()
-	model.SupplierParty.PostalAddress.CityName = nextString()
-	model.SupplierParty.PostalAddress.PostalZone = nextString()
-	model.SupplierParty.PostalAddress.State = nextString()
-	model.SupplierParty.PostalAddress.Country = nextString()
-
-	contactName := nextString()
-	contactTelephone := nextString()
-	contactEmail := nextString()
+	model.SupplierParty.PartyName = nextString("supplierParty.partyName")
+	model.SupplierParty.CompanyTaxID = nextString("supplierParty.companyTaxId")
+	model.SupplierParty.CompanyVatID = nextString("supplierParty.companyVatId")
+	model.SupplierParty.CompanyRegisterID = nextString("supplierParty.companyRegisterId")
+
+	model.SupplierParty.PostalAddress.StreetName = nextString("supplierParty.postalAddress.streetName")
+	model.SupplierParty.PostalAddress.BuildingNumber = nextString("supplierParty.postalAddress.buildingNumber")
+	model.SupplierParty.PostalAddress.CityName = nextString("supplierParty.postalAddress.cityName")
+	model.SupplierParty.PostalAddress.PostalZone = nextString("supplierParty.postalAddress.postalZone")
+	model.SupplierParty.PostalAddress.State = nextString("supplierParty.postalAddress.state")
+	model.SupplierParty.PostalAddress.Country = nextString("supplierParty.postalAddress.country")
+
+	contactName := nextString("supplierParty.contact.name")
+	contactTelephone := nextString("supplierParty.contact.telephone")
+	contactEmail := nextString("supplierParty.contact.email")
 	if contactName != "" || contactTelephone != "" || contactEmail != "" {
 		model.SupplierParty.Contact = &Contact{
 			Name:      contactName,
@@ -88,32 +122,32 @@ func deserialize(tabString string, documentType InvoiceDocumentType) (*DataModel
 	}
 
 	// Customer party (5 fields)
-	model.CustomerParty.PartyName = nextString()
-	model.CustomerParty.CompanyTaxID = nextString()
-	model.CustomerParty.CompanyVatID = nextString()
-	model.CustomerParty.CompanyRegisterID = nextString()
-	model.CustomerParty.PartyIdentification = nextString()
+	model.CustomerParty.PartyName = nextString("customerParty.partyName")
+	model.CustomerParty.CompanyTaxID = nextString("customerParty.companyTaxId")
+	model.CustomerParty.CompanyVatID = nextString("customerParty.companyVatId")
+	model.CustomerParty.CompanyRegisterID = nextString("customerParty.companyRegisterId")
+	model.CustomerParty.PartyIdentification = nextString("customerParty.partyIdentification")
 
 	// Invoice detail
-	numLines, err := nextInt()
+	numLines, err := nextInt("numberOfInvoiceLines")
 	if err != nil {
-		return nil, fmt.Errorf("invalid numberOfInvoiceLines: %w", err)
+		return nil, nil, err
 	}
 	if numLines > 0 {
 		model.NumberOfInvoiceLines = &numLines
 	}
-	model.InvoiceDescription = nextString()
+	model.InvoiceDescription = nextString("invoiceDescription")
 
 	// Single invoice line (7 fields)
-	lineOrderID := nextString()
-	lineDeliveryNoteID := nextString()
-	lineItemName := nextString()
-	lineItemEanCode := nextString()
-	linePeriodFrom := nextString()
-	linePeriodTo := nextString()
-	lineQuantity, err := nextFloat()
+	lineOrderID := nextString("singleInvoiceLine.orderLineId")
+	lineDeliveryNoteID := nextString("singleInvoiceLine.deliveryNoteLineId")
+	lineItemName := nextString("singleInvoiceLine.itemName")
+	lineItemEanCode := nextString("singleInvoiceLine.itemEanCode")
+	linePeriodFrom := nextString("singleInvoiceLine.periodFromDate")
+	linePeriodTo := nextString("singleInvoiceLine.periodToDate")
+	lineQuantity, err := nextDecimal("singleInvoiceLine.invoicedQuantity")
 	if err != nil {
-		return nil, fmt.Errorf("invalid invoicedQuantity: %w", err)
+		return nil, nil, err
 	}
 
 	hasSingleLine := lineOrderID != "" ||
@@ -122,7 +156,7 @@ func deserialize(tabString string, documentType InvoiceDocumentType) (*DataModel
 		lineItemEanCode != "" ||
 		linePeriodFrom != "" ||
 		linePeriodTo != "" ||
-		lineQuantity != 0
+		!lineQuantity.IsZero()
 
 	if hasSingleLine {
 		model.SingleInvoiceLine = &SingleInvoiceLine{
@@ -137,53 +171,87 @@ func deserialize(tabString string, documentType InvoiceDocumentType) (*DataModel
 	}
 
 	// Tax category summaries
-	taxCount, err := nextInt()
+	taxCount, err := nextInt("taxCategorySummaries")
 	if err != nil {
-		return nil, fmt.Errorf("invalid tax category count: %w", err)
+		return nil, nil, err
 	}
 
-	model.TaxCategorySummaries = make([]TaxCategorySummary, taxCount)
-	for t := 0; t < taxCount; t++ {
-		model.TaxCategorySummaries[t].ClassifiedTaxCategory, err = nextFloat()
+	model.TaxCategorySummaries = make([]TaxCategorySummary, min(taxCount, len(data)))
+	for t := range model.TaxCategorySummaries {
+		model.TaxCategorySummaries[t].ClassifiedTaxCategory, err = nextDecimal(fmt.Sprintf("taxCategorySummaries[%d].classifiedTaxCategory", t))
 		if err != nil {
-			return nil, fmt.Errorf("invalid classifiedTaxCategory[%d]: %w", t, err)
+			return nil, nil, err
 		}
-		model.TaxCategorySummaries[t].TaxExclusiveAmount, err = nextFloat()
+		model.TaxCategorySummaries[t].TaxExclusiveAmount, err = nextDecimal(fmt.Sprintf("taxCategorySummaries[%d].taxExclusiveAmount", t))
 		if err != nil {
-			return nil, fmt.Errorf("invalid taxExclusiveAmount[%d]: %w", t, err)
+			return nil, nil, err
 		}
-		model.TaxCategorySummaries[t].TaxAmount, err = nextFloat()
+		model.TaxCategorySummaries[t].TaxAmount, err = nextDecimal(fmt.Sprintf("taxCategorySummaries[%d].taxAmount", t))
 		if err != nil {
-			return nil, fmt.Errorf("invalid taxAmount[%d]: %w", t, err)
+			return nil, nil, err
 		}
-		model.TaxCategorySummaries[t].AlreadyClaimedTaxExclusiveAmount, err = nextFloat()
+		model.TaxCategorySummaries[t].AlreadyClaimedTaxExclusiveAmount, err = nextDecimal(fmt.Sprintf("taxCategorySummaries[%d].alreadyClaimedTaxExclusiveAmount", t))
 		if err != nil {
-			return nil, fmt.Errorf("invalid alreadyClaimedTaxExclusiveAmount[%d]: %w", t, err)
+			return nil, nil, err
 		}
-		model.TaxCategorySummaries[t].AlreadyClaimedTaxAmount, err = nextFloat()
+		model.TaxCategorySummaries[t].AlreadyClaimedTaxAmount, err = nextDecimal(fmt.Sprintf("taxCategorySummaries[%d].alreadyClaimedTaxAmount", t))
 		if err != nil {
-			return nil, fmt.Errorf("invalid alreadyClaimedTaxAmount[%d]: %w", t, err)
+			return nil, nil, err
 		}
 	}
 
 	// Monetary summary (2 fields)
-	model.MonetarySummary.PayableRoundingAmount, err = nextFloat()
+	model.MonetarySummary.PayableRoundingAmount, err = nextDecimal("monetarySummary.payableRoundingAmount")
 	if err != nil {
-		return nil, fmt.Errorf("invalid payableRoundingAmount: %w", err)
+		return nil, nil, err
 	}
-	model.MonetarySummary.PaidDepositsAmount, err = nextFloat()
+	model.MonetarySummary.PaidDepositsAmount, err = nextDecimal("monetarySummary.paidDepositsAmount")
 	if err != nil {
-		return nil, fmt.Errorf("invalid paidDepositsAmount: %w", err)
+		return nil, nil, err
 	}
 
 	// Payment means bitmask
-	pm, err := nextInt()
+	pm, err := nextInt("paymentMeans")
 	if err != nil {
-		return nil, fmt.Errorf("invalid paymentMeans: %w", err)
+		return nil, nil, err
 	}
 	model.PaymentMeans = uint8(pm)
 
-	return model, nil
+	if i < len(data) {
+		if options.Strict {
+			report(&bysquare.FieldError{Index: i, Name: "unknown", Err: fmt.Errorf("%d trailing fields", len(data)-i)})
+		}
+		model.Extra = slices.Clone(data[i:])
+	}
+
+	return model, problems, nil
+}
+
+// DecodeOptions configures the decoding process.
+type DecodeOptions struct {
+	// Lenient recovers as much data as possible from damaged or
+	// non-conforming codes. Checksum mismatches, unsupported versions,
+	// missing fields and malformed numbers are collected as
+	// bysquare.Warnings, which Decode returns as the error together with
+	// the best-effort model.
+	Lenient bool
+
+	// Strict rejects anything a conforming encoder would not produce:
+	// a non-zero reserved nibble, malformed or negative numbers, and
+	// missing or trailing fields. Every violation is reported as a
+	// bysquare.FieldError matching bysquare.ErrNonCanonical, joined into
+	// one error. Strict cannot be combined with Lenient.
+	Strict bool
+
+	// Limits bounds the resources spent on untrusted input, such as codes
+	// from a public scan endpoint. The zero value applies the limits of
+	// the specification.
+	Limits bysquare.Limits
+}
+
+// DefaultDecodeOptions returns default decoding options.
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{}
 }
 
 // Decode decodes a QR string into an invoice DataModel.
@@ -193,44 +261,39 @@ func deserialize(tabString string, documentType InvoiceDocumentType) (*DataModel
 // AdvanceInvoice).
 //
 // @see 3.16.
-func Decode(qr string) (*DataModel, error) {
-	bytes, err := bysquare.DecodeBase32Hex(qr, true)
-	if err != nil {
-		return nil, fmt.Errorf("base32hex decode failed: %w", err)
+func Decode(qr string, opts ...DecodeOptions) (*DataModel, error) {
+	options := DefaultDecodeOptions()
+	if len(opts) > 0 {
+		options = opts[0]
 	}
 
-	if len(bytes) < 4 {
-		return nil, fmt.Errorf("input too short: need at least 4 bytes, got %d", len(bytes))
+	if options.Lenient && options.Strict {
+		return nil, bysquare.ErrLenientStrict
 	}
 
-	header := bysquare.ParseBysquareHeader(bytes[:2])
-
-	if header.BySquareType != 0x01 {
-		return nil, fmt.Errorf("expected bysquareType 1 (Invoice), got %d", header.BySquareType)
+	header, payload, err := bysquare.Open(qr, 0x01, bysquare.OpenOptions{Lenient: options.Lenient, Limits: options.Limits})
+	var warnings bysquare.Warnings
+	if err != nil && !errors.As(err, &warnings) {
+		return nil, err
 	}
 
-	if bysquare.Version(header.Version) > bysquare.Version120 {
-		return nil, fmt.Errorf("unsupported version: %d", header.Version)
+	var violations []error
+	if options.Strict && header.Reserved != 0 {
+		violations = append(violations, fmt.Errorf("%w: reserved nibble is %d", bysquare.ErrNonCanonical, header.Reserved))
 	}
 
-	payloadLength := int(binary.LittleEndian.Uint16(bytes[2:4]))
-
-	decompressed, err := bysquare.DecompressLZMA(bytes[4:], payloadLength)
+	model, problems, err := deserialize(payload, InvoiceDocumentType(header.DocumentType), options)
 	if err != nil {
-		return nil, fmt.Errorf("LZMA decompression failed: %w", err)
-	}
-
-	if len(decompressed) < 4 {
-		return nil, fmt.Errorf("decompressed data too short for checksum")
+		return nil, err
 	}
 
-	checksum := binary.LittleEndian.Uint32(decompressed[:4])
-	body := string(decompressed[4:])
-
-	computed := bysquare.Crc32Checksum(body)
-	if checksum != computed {
-		return nil, fmt.Errorf("CRC32 checksum mismatch: stored=%d computed=%d", checksum, computed)
+	if options.Strict {
+		if violations = append(violations, problems...); len(violations) > 0 {
+			return nil, errors.Join(violations...)
+		}
+		return model, nil
 	}
 
-	return deserialize(body, InvoiceDocumentType(header.DocumentType))
+	warnings = append(warnings, problems...)
+	return model, warnings.Err()
 }
@@ -1,7 +1,12 @@
 package invoice
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
 )
 
 func TestDecodeInvalidInput(t *testing.T) {
@@ -36,8 +41,8 @@ func TestRoundTrip(t *testing.T) {
 		DeliveryNoteID:      "DN-456",
 		LocalCurrencyCode:   "EUR",
 		ForeignCurrencyCode: "USD",
-		CurrRate:            1.1,
-		ReferenceCurrRate:   1,
+		CurrRate:            "1.1",
+		ReferenceCurrRate:   "1",
 		SupplierParty: SupplierParty{
 			Party: Party{
 				PartyName:         "Dodavatel s.r.o.",
@@ -72,16 +77,16 @@ func TestRoundTrip(t *testing.T) {
 		InvoiceDescription:   "Monthly services",
 		TaxCategorySummaries: []TaxCategorySummary{
 			{
-				ClassifiedTaxCategory:            0.20,
-				TaxExclusiveAmount:               1_000,
-				TaxAmount:                        200,
-				AlreadyClaimedTaxExclusiveAmount: 100,
-				AlreadyClaimedTaxAmount:          20,
+				ClassifiedTaxCategory:            "0.20",
+				TaxExclusiveAmount:               "1000",
+				TaxAmount:                        "200",
+				AlreadyClaimedTaxExclusiveAmount: "100",
+				AlreadyClaimedTaxAmount:          "20",
 			},
 		},
 		MonetarySummary: MonetarySummary{
-			PayableRoundingAmount: 0.01,
-			PaidDepositsAmount:    50,
+			PayableRoundingAmount: "0.01",
+			PaidDepositsAmount:    "50",
 		},
 		PaymentMeans: uint8(PaymentMeanMoneyTransfer | PaymentMeanCreditCard),
 	}
@@ -159,12 +164,12 @@ func TestRoundTripWithSingleInvoiceLine(t *testing.T) {
 			ItemName:         "Service XYZ",
 			PeriodFromDate:   "20240101",
 			PeriodToDate:     "20240131",
-			InvoicedQuantity: 10,
+			InvoicedQuantity: "10",
 		},
 		TaxCategorySummaries: []TaxCategorySummary{{
-			ClassifiedTaxCategory: 0.20,
-			TaxExclusiveAmount:    500,
-			TaxAmount:             100,
+			ClassifiedTaxCategory: "0.20",
+			TaxExclusiveAmount:    "500",
+			TaxAmount:             "100",
 		}},
 	}
 
@@ -184,7 +189,194 @@ func TestRoundTripWithSingleInvoiceLine(t *testing.T) {
 	if decoded.SingleInvoiceLine.ItemName != "Service XYZ" {
 		t.Errorf("ItemName: got %q, want %q", decoded.SingleInvoiceLine.ItemName, "Service XYZ")
 	}
-	if decoded.SingleInvoiceLine.InvoicedQuantity != 10 {
+	if decoded.SingleInvoiceLine.InvoicedQuantity != "10" {
 		t.Errorf("InvoicedQuantity: got %v, want 10", decoded.SingleInvoiceLine.InvoicedQuantity)
 	}
 }
+
+func TestDecodeTypedErrors(t *testing.T) {
+	payQR := "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+	if _, err := Decode(payQR); !errors.Is(err, bysquare.ErrUnexpectedType) {
+		t.Errorf("expected ErrUnexpectedType, got %v", err)
+	}
+
+	// currRate is field 7.
+	fields := make([]string, 45)
+	fields[7] = "abc"
+	qr, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01}, strings.Join(fields, "\t"))
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	_, err = Decode(qr)
+	var fieldErr *bysquare.FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *bysquare.FieldError, got %v", err)
+	}
+	if fieldErr.Index != 7 || fieldErr.Name != "currRate" {
+		t.Errorf("expected field 7 (currRate), got %d (%s)", fieldErr.Index, fieldErr.Name)
+	}
+}
+
+func TestDecodeLenient(t *testing.T) {
+	seal := func(fields []string) string {
+		qr, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01}, strings.Join(fields, "\t"))
+		if err != nil {
+			t.Fatalf("Seal() error: %v", err)
+		}
+		return qr
+	}
+
+	t.Run("malformed number", func(t *testing.T) {
+		fields := make([]string, 45)
+		fields[0] = "INV-001"
+		fields[7] = "abc"
+
+		model, err := Decode(seal(fields), DecodeOptions{Lenient: true})
+		var fieldErr *bysquare.FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected *bysquare.FieldError warning, got %v", err)
+		}
+		if fieldErr.Name != "currRate" {
+			t.Errorf("expected name currRate, got %q", fieldErr.Name)
+		}
+		if model == nil || model.InvoiceID != "INV-001" {
+			t.Errorf("expected recovered InvoiceID INV-001, got %+v", model)
+		}
+	})
+
+	t.Run("short field list", func(t *testing.T) {
+		qr := seal([]string{"INV-001", "20240101"})
+
+		model, err := Decode(qr)
+		if err != nil {
+			t.Fatalf("strict: expected missing trailing fields to be tolerated, got %v", err)
+		}
+
+		model, err = Decode(qr, DecodeOptions{Lenient: true})
+		if !errors.Is(err, bysquare.ErrTruncatedPayload) {
+			t.Fatalf("expected ErrTruncatedPayload warning, got %v", err)
+		}
+
+		var warnings bysquare.Warnings
+		if !errors.As(err, &warnings) || len(warnings) != 1 {
+			t.Fatalf("expected 1 warning, got %v", err)
+		}
+
+		var fieldErr *bysquare.FieldError
+		if !errors.As(warnings[0], &fieldErr) || fieldErr.Index != 2 || fieldErr.Name != "taxPointDate" {
+			t.Errorf("expected field 2 (taxPointDate), got %v", warnings[0])
+		}
+		if model.IssueDate != "20240101" {
+			t.Errorf("expected IssueDate 20240101, got %q", model.IssueDate)
+		}
+	})
+}
+
+func TestDecodeStrict(t *testing.T) {
+	model := &DataModel{
+		DocumentType:      InvoiceDocumentTypeInvoice,
+		InvoiceID:         "INV-001",
+		IssueDate:         "20240101",
+		LocalCurrencyCode: "EUR",
+		SupplierParty: SupplierParty{
+			Party:         Party{PartyName: "Supplier"},
+			PostalAddress: PostalAddress{StreetName: "Main", CityName: "Bratislava", PostalZone: "81101", Country: "SVK"},
+		},
+		CustomerParty:        CustomerParty{Party: Party{PartyName: "Customer"}},
+		TaxCategorySummaries: []TaxCategorySummary{{ClassifiedTaxCategory: "0.2", TaxExclusiveAmount: "100", TaxAmount: "20"}},
+		PaymentMeans:         1,
+	}
+	canonical, err := Encode(model, EncodeOptions{Validate: false})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if _, err := Decode(canonical, DecodeOptions{Strict: true}); err != nil {
+		t.Fatalf("expected canonical code to pass, got %v", err)
+	}
+
+	// Malformed currRate, trailing field and a non-zero reserved nibble.
+	fields := make([]string, 46)
+	fields[7] = "abc"
+	fields[45] = "extra"
+	qr, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01, Reserved: 0x02}, strings.Join(fields, "\t"))
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	_, err = Decode(qr, DecodeOptions{Strict: true})
+	var violations interface{ Unwrap() []error }
+	if !errors.As(err, &violations) {
+		t.Fatalf("expected joined violations, got %v", err)
+	}
+	if got := violations.Unwrap(); len(got) != 3 {
+		t.Fatalf("expected 3 violations, got %d: %v", len(got), err)
+	}
+	for _, v := range violations.Unwrap() {
+		if !errors.Is(v, bysquare.ErrNonCanonical) {
+			t.Errorf("expected ErrNonCanonical, got %v", v)
+		}
+	}
+
+	short, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01}, "INV-001\t20240101")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if _, err := Decode(short, DecodeOptions{Strict: true}); !errors.Is(err, bysquare.ErrTruncatedPayload) {
+		t.Errorf("expected ErrTruncatedPayload, got %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	fields := make([]string, 45)
+	fields[0] = "INV-001"
+	fields[34] = "1"
+	seed, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01}, strings.Join(fields, "\t"))
+	if err != nil {
+		f.Fatalf("Seal() error: %v", err)
+	}
+
+	f.Add(seed)
+	f.Add("")
+	f.Add("1000")
+	f.Add("!!!")
+
+	f.Fuzz(func(t *testing.T, qr string) {
+		for _, opts := range []DecodeOptions{{}, {Lenient: true}, {Strict: true}} {
+			Decode(qr, opts)
+		}
+	})
+}
+
+func TestDecodeExtra(t *testing.T) {
+	model := minimalInvoice()
+	model.Extra = []string{"vendor", "", "42"}
+
+	qr, err := Encode(model)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	_, payload, err := bysquare.Open(qr, 0x01)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if !strings.HasSuffix(payload, "\tvendor\t\t42") {
+		t.Errorf("expected extra fields at the end, got %q", payload)
+	}
+
+	decoded, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if !slices.Equal(decoded.Extra, model.Extra) {
+		t.Errorf("expected extra %q, got %q", model.Extra, decoded.Extra)
+	}
+
+	reencoded, err := Encode(decoded, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if _, got, _ := bysquare.Open(reencoded, 0x01); got != payload {
+		t.Errorf("expected payload %q, got %q", payload, got)
+	}
+}
@@ -1,6 +1,7 @@
 package invoice
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -20,6 +21,7 @@ import (
 //   - Fields 37..36+N*5: per summary (5 fields each)
 //   - Monetary summary (2 fields)
 //   - Payment means bitmask
+//   - DataModel.Extra, unchanged
 func serialize(data *DataModel) string {
 	fields := make([]string, 0, 64)
 
@@ -27,8 +29,8 @@ func serialize(data *DataModel) string {
 		fields = append(fields, s)
 	}
 
-	pushFloat := func(f float64) {
-		push(bysquare.FormatFloat(f))
+	pushDecimal := func(d bysquare.Decimal) {
+		push(bysquare.FormatDecimal(d))
 	}
 
 	// Core fields (9)
@@ -39,8 +41,8 @@ func serialize(data *DataModel) string {
 	push(bysquare.Sanitize(data.DeliveryNoteID))
 	push(bysquare.Sanitize(data.LocalCurrencyCode))
 	push(bysquare.Sanitize(data.ForeignCurrencyCode))
-	pushFloat(data.CurrRate)
-	pushFloat(data.ReferenceCurrRate)
+	pushDecimal(data.CurrRate)
+	pushDecimal(data.ReferenceCurrRate)
 
 	// Supplier party (13 fields)
 	sp := data.SupplierParty
@@ -92,7 +94,7 @@ func serialize(data *DataModel) string {
 		push(bysquare.Sanitize(line.ItemEanCode))
 		push(bysquare.Sanitize(line.PeriodFromDate))
 		push(bysquare.Sanitize(line.PeriodToDate))
-		pushFloat(line.InvoicedQuantity)
+		pushDecimal(line.InvoicedQuantity)
 	} else {
 		for i := 0; i < 7; i++ {
 			push("")
@@ -104,17 +106,17 @@ func serialize(data *DataModel) string {
 	for _, tcs := range data.TaxCategorySummaries {
 		// classifiedTaxCategory, taxExclusiveAmount, taxAmount are required
 		// fields where 0 is a valid value, so always serialize them (not
-		// FormatFloat which returns "" for zero).
-		push(bysquare.FormatFloatRequired(tcs.ClassifiedTaxCategory))
-		push(bysquare.FormatFloatRequired(tcs.TaxExclusiveAmount))
-		push(bysquare.FormatFloatRequired(tcs.TaxAmount))
-		pushFloat(tcs.AlreadyClaimedTaxExclusiveAmount)
-		pushFloat(tcs.AlreadyClaimedTaxAmount)
+		// FormatDecimal which returns "" for zero).
+		push(bysquare.FormatDecimalRequired(tcs.ClassifiedTaxCategory))
+		push(bysquare.FormatDecimalRequired(tcs.TaxExclusiveAmount))
+		push(bysquare.FormatDecimalRequired(tcs.TaxAmount))
+		pushDecimal(tcs.AlreadyClaimedTaxExclusiveAmount)
+		pushDecimal(tcs.AlreadyClaimedTaxAmount)
 	}
 
 	// Monetary summary (2 fields)
-	pushFloat(data.MonetarySummary.PayableRoundingAmount)
-	pushFloat(data.MonetarySummary.PaidDepositsAmount)
+	pushDecimal(data.MonetarySummary.PayableRoundingAmount)
+	pushDecimal(data.MonetarySummary.PaidDepositsAmount)
 
 	// Payment means bitmask
 	if data.PaymentMeans != 0 {
@@ -123,11 +125,37 @@ func serialize(data *DataModel) string {
 		push("")
 	}
 
+	for _, v := range data.Extra {
+		push(bysquare.Sanitize(v))
+	}
+
 	return strings.Join(fields, "	")
 }
 
 // EncodeOptions configures invoice encoding behavior.
 type EncodeOptions struct {
+	// Deburr removes diacritics from the text fields selected by
+	// DeburrFields. Off by default, matching the TypeScript library.
+	Deburr bool
+
+	// DeburrFields selects the fields Deburr rewrites. Zero selects
+	// DefaultDeburrFields.
+	DeburrFields Field
+
+	// Transliterator rewrites text fields when Deburr is set. Nil selects
+	// bysquare.DefaultTransliterator.
+	Transliterator bysquare.Transliterator
+
+	// Repertoire restricts the characters of every text field. Zero
+	// allows any character.
+	Repertoire bysquare.Repertoire
+
+	// RepertoirePolicy selects what happens to characters outside
+	// Repertoire: the zero value rejects them during validation, the
+	// others rewrite them using Transliterator, or
+	// bysquare.ExtendedTransliterator when it is nil.
+	RepertoirePolicy bysquare.RepertoirePolicy
+
 	// Validate the data model before encoding.
 	Validate bool
 
@@ -136,8 +164,39 @@ type EncodeOptions struct {
 	// The official app only recognizes headers with version=0 and performs
 	// strict equality matching, so version 1.0.0 is the only compatible value.
 	Version bysquare.Version
+
+	// Compression selects the LZMA encoder. The zero value gives the
+	// shortest QR strings; bysquare.CompressionReference reproduces those
+	// of the TypeScript implementation.
+	Compression bysquare.Compression
+
+	// Fit bounds the QR version of the output. The zero value does not
+	// limit it.
+	Fit bysquare.FitOptions
 }
 
+// Field selects text fields for diacritics removal. Values can be combined
+// with bitwise OR.
+type Field uint16
+
+const (
+	FieldInvoiceID Field = 1 << iota
+	FieldSupplierPartyName
+	// FieldSupplierPostalAddress selects the street, building number, city
+	// and state of the supplier.
+	FieldSupplierPostalAddress
+	FieldSupplierContactName
+	FieldCustomerPartyName
+	FieldInvoiceDescription
+	FieldItemName
+
+	// DefaultDeburrFields selects every free-text field except the invoice
+	// identifier.
+	DefaultDeburrFields = AllFields &^ FieldInvoiceID
+	// AllFields selects every free-text field.
+	AllFields = FieldItemName<<1 - 1
+)
+
 // DefaultEncodeOptions returns sensible defaults for encoding.
 func DefaultEncodeOptions() EncodeOptions {
 	return EncodeOptions{
@@ -151,41 +210,176 @@ func DefaultEncodeOptions() EncodeOptions {
 // Uses bysquareType=1 and the documentType from DataModel to build
 // the header. The binary pipeline is shared with PAY by square:
 // serialize -> CRC32 -> LZMA -> header + length -> base32hex.
+// With opt.Fit set the result must also fit the configured QR version,
+// see bysquare.FitPolicy.
 //
 // @see 3.16.
 func Encode(model *DataModel, opts ...EncodeOptions) (string, error) {
+	result, err := EncodeDetailed(model, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.QR, nil
+}
+
+// EncodeResult is the output of EncodeDetailed.
+type EncodeResult struct {
+	// QR is the encoded string.
+	QR string
+	// Changes lists the text fields rewritten by EncodeOptions.Deburr and
+	// RepertoirePolicy, in the order they were applied.
+	Changes bysquare.Changes
+}
+
+// EncodeDetailed encodes the data model like Encode and returns the QR
+// string together with the rewritten fields.
+func EncodeDetailed(model *DataModel, opts ...EncodeOptions) (*EncodeResult, error) {
 	opt := DefaultEncodeOptions()
 	if len(opts) > 0 {
 		opt = opts[0]
 	}
 
+	model, header, changes, err := prepare(model, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := seal(model, header, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncodeResult{QR: qr, Changes: changes}, nil
+}
+
+// seal encodes the prepared model and applies opt.Fit.
+func seal(model *DataModel, header bysquare.BysquareHeader, opt EncodeOptions) (string, error) {
+	qr, err := bysquare.Seal(header, serialize(model), bysquare.SealOptions{Compression: opt.Compression})
+	if err != nil {
+		return "", err
+	}
+
+	err = opt.Fit.Check(qr)
+	if err == nil {
+		return qr, nil
+	}
+	if opt.Fit.Policy != bysquare.FitTruncate || !errors.Is(err, bysquare.ErrCapacityExceeded) {
+		return "", err
+	}
+
+	// Truncate a copy so the caller's model stays untouched.
+	truncated := *model
+	return opt.Fit.Truncate(model.InvoiceDescription, func(description string) (string, error) {
+		truncated.InvoiceDescription = description
+		return bysquare.Seal(header, serialize(&truncated), bysquare.SealOptions{Compression: opt.Compression})
+	})
+}
+
+// Plan reports the QR length of the encoded model, the minimal QR version
+// for every error correction level and the fields that cost the most
+// characters. Deburr, Validate, Version and Compression apply as in Encode;
+// Fit is ignored, so the plan describes the untruncated output.
+func Plan(model *DataModel, opts ...EncodeOptions) (*bysquare.Plan, error) {
+	opt := DefaultEncodeOptions()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	model, header, _, err := prepare(model, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return bysquare.PlanPayload(header, serialize(model), bysquare.SealOptions{Compression: opt.Compression})
+}
+
+// prepare applies deburring, the repertoire policy and validation and
+// returns the model to encode with its header and the rewritten fields.
+// Rewriting works on a copy, the caller's model stays untouched.
+func prepare(model *DataModel, opt EncodeOptions) (*DataModel, bysquare.BysquareHeader, bysquare.Changes, error) {
+	coerce := opt.Repertoire != bysquare.RepertoireAny && opt.RepertoirePolicy != bysquare.RepertoireReject
+	if opt.Deburr || coerce {
+		model = detach(model)
+	}
+
+	var changes bysquare.Changes
+	if opt.Deburr {
+		changes = Deburr(model, opt)
+	}
+
+	if coerce {
+		var t bysquare.Transliterator
+		if opt.RepertoirePolicy == bysquare.RepertoireTransliterate {
+			t = opt.Transliterator
+			if t == nil {
+				t = bysquare.ExtendedTransliterator
+			}
+		}
+		walkText(model, func(_ Field, path string, s *string) {
+			if coerced := opt.Repertoire.Coerce(*s, t); coerced != *s {
+				changes = append(changes, bysquare.Change{Path: path, Before: *s, After: coerced})
+				*s = coerced
+			}
+		})
+	}
+
 	if opt.Validate {
 		if err := ValidateDataModel(model); err != nil {
-			return "", err
+			return nil, bysquare.BysquareHeader{}, nil, err
+		}
+		if err := ValidateRepertoire(model, opt.Repertoire); err != nil {
+			return nil, bysquare.BysquareHeader{}, nil, err
 		}
 	}
 
-	payloadTabbed := serialize(model)
-	payloadChecked := bysquare.AddChecksum(payloadTabbed)
+	return model, bysquare.BysquareHeader{
+		BySquareType: 0x01,
+		Version:      uint8(opt.Version),
+		DocumentType: uint8(model.DocumentType),
+	}, changes, nil
+}
 
-	payloadCompressed, err := bysquare.CompressLZMA(payloadChecked)
-	if err != nil {
-		return "", fmt.Errorf("LZMA compression failed: %w", err)
+// Deburr rewrites the fields selected by opt.DeburrFields with
+// opt.Transliterator in place and returns the fields it changed, so
+// applications can show users what was altered. opt.Deburr is ignored.
+// Encode applies the same rewrite to a copy of its model; EncodeDetailed
+// reports the changes in EncodeResult.Changes.
+func Deburr(model *DataModel, opts ...EncodeOptions) bysquare.Changes {
+	opt := DefaultEncodeOptions()
+	if len(opts) > 0 {
+		opt = opts[0]
 	}
 
-	if len(payloadCompressed) < 13 {
-		return "", fmt.Errorf("compressed payload too short")
+	fields := opt.DeburrFields
+	if fields == 0 {
+		fields = DefaultDeburrFields
+	}
+	t := opt.Transliterator
+	if t == nil {
+		t = bysquare.DefaultTransliterator
 	}
-	lzmaBody := payloadCompressed[13:]
 
-	bysquareType := uint8(0x01)
-	header := bysquare.BuildBysquareHeader(bysquareType, uint8(opt.Version), uint8(model.DocumentType), 0x00)
-	lengthBytes := bysquare.BuildPayloadLength(len(payloadChecked))
+	var changes bysquare.Changes
+	walkText(model, func(field Field, path string, s *string) {
+		if fields&field != 0 {
+			changes.Apply(t, path, s)
+		}
+	})
 
-	output := make([]byte, 0, len(header)+len(lengthBytes)+len(lzmaBody))
-	output = append(output, header...)
-	output = append(output, lengthBytes...)
-	output = append(output, lzmaBody...)
+	return changes
+}
 
-	return bysquare.EncodeBase32Hex(output, false), nil
+// detach returns a copy of model with its own contact and invoice line, so
+// rewriting text leaves the caller's data untouched.
+func detach(model *DataModel) *DataModel {
+	c := *model
+	if c.SupplierParty.Contact != nil {
+		contact := *c.SupplierParty.Contact
+		c.SupplierParty.Contact = &contact
+	}
+	if c.SingleInvoiceLine != nil {
+		line := *c.SingleInvoiceLine
+		c.SingleInvoiceLine = &line
+	}
+	return &c
 }
@@ -1,8 +1,14 @@
 package invoice
 
 import (
+	"errors"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
 )
 
 func minimalInvoice() *DataModel {
@@ -31,9 +37,9 @@ func minimalInvoice() *DataModel {
 		},
 		NumberOfInvoiceLines: &numLines,
 		TaxCategorySummaries: []TaxCategorySummary{{
-			ClassifiedTaxCategory: 0.20,
-			TaxExclusiveAmount:    1_000,
-			TaxAmount:             200,
+			ClassifiedTaxCategory: "0.20",
+			TaxExclusiveAmount:    "1000",
+			TaxAmount:             "200",
 		}},
 	}
 }
@@ -72,8 +78,8 @@ func TestSerializeFieldCount(t *testing.T) {
 func TestSerializeMultipleTaxCategories(t *testing.T) {
 	model := minimalInvoice()
 	model.TaxCategorySummaries = []TaxCategorySummary{
-		{ClassifiedTaxCategory: 0.20, TaxExclusiveAmount: 800, TaxAmount: 160},
-		{ClassifiedTaxCategory: 0.10, TaxExclusiveAmount: 200, TaxAmount: 20},
+		{ClassifiedTaxCategory: "0.20", TaxExclusiveAmount: "800", TaxAmount: "160"},
+		{ClassifiedTaxCategory: "0.10", TaxExclusiveAmount: "200", TaxAmount: "20"},
 	}
 
 	result := serialize(model)
@@ -154,3 +160,136 @@ func TestEncodeSkipValidation(t *testing.T) {
 		t.Errorf("expected no error when validation is skipped, got: %v", err)
 	}
 }
+
+func TestEncodeFit(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; sb.Len() < 400; i++ {
+		sb.WriteString(strconv.Itoa(i * 7919 % 10007))
+		sb.WriteByte(' ')
+	}
+	description := sb.String()
+
+	model := minimalInvoice()
+	model.InvoiceDescription = description
+
+	plan, err := Plan(model)
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	if plan.Fields[0].Name != "invoiceDescription" {
+		t.Errorf("expected invoiceDescription to cost the most, got %+v", plan.Fields[0])
+	}
+	version := plan.Version(qr.LevelQ)
+
+	opt := DefaultEncodeOptions()
+	opt.Fit = bysquare.FitOptions{MaxVersion: version - 1, Level: qr.LevelQ}
+	if _, err := Encode(model, opt); !errors.Is(err, bysquare.ErrCapacityExceeded) {
+		t.Errorf("expected ErrCapacityExceeded, got %v", err)
+	}
+
+	opt.Fit.Policy = bysquare.FitTruncate
+	result, err := Encode(model, opt)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if model.InvoiceDescription != description {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	decoded, err := Decode(result)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	got := decoded.InvoiceDescription
+	if len(got) >= len(description) || !strings.HasPrefix(description, got) {
+		t.Errorf("expected a shorter prefix of the description, got %q", got)
+	}
+}
+
+func TestEncodeDeburr(t *testing.T) {
+	model := minimalInvoice()
+	model.InvoiceID = "FA-č1"
+	model.SupplierParty.PartyName = "Dodávateľ s.r.o."
+	model.SupplierParty.PostalAddress.CityName = "Košice"
+	model.SupplierParty.Contact = &Contact{Name: "Jana Malá", Email: "jana@example.com"}
+	model.InvoiceDescription = "Služby"
+
+	opt := DefaultEncodeOptions()
+	opt.Deburr = true
+
+	result, err := Encode(model, opt)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if model.SupplierParty.Contact.Name != "Jana Malá" {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	decoded, err := Decode(result)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if decoded.SupplierParty.PartyName != "Dodavatel s.r.o." {
+		t.Errorf("expected deburred party name, got %q", decoded.SupplierParty.PartyName)
+	}
+	if decoded.SupplierParty.Contact.Name != "Jana Mala" {
+		t.Errorf("expected deburred contact name, got %q", decoded.SupplierParty.Contact.Name)
+	}
+	if decoded.InvoiceID != "FA-č1" {
+		t.Errorf("expected invoice ID kept by default, got %q", decoded.InvoiceID)
+	}
+
+	detailed, err := EncodeDetailed(model, opt)
+	if err != nil {
+		t.Fatalf("EncodeDetailed() error: %v", err)
+	}
+	if detailed.QR != result {
+		t.Errorf("expected %q, got %q", result, detailed.QR)
+	}
+	var paths []string
+	for _, c := range detailed.Changes {
+		paths = append(paths, c.Path)
+	}
+	if expected := []string{"supplierParty.partyName", "supplierParty.postalAddress.cityName", "supplierParty.contact.name", "invoiceDescription"}; !slices.Equal(paths, expected) {
+		t.Errorf("expected changed fields %q, got %q", expected, paths)
+	}
+
+	changes := Deburr(model, EncodeOptions{DeburrFields: FieldInvoiceID | FieldSupplierPostalAddress})
+	expected := bysquare.Changes{
+		{Path: "invoiceId", Before: "FA-č1", After: "FA-c1"},
+		{Path: "supplierParty.postalAddress.cityName", Before: "Košice", After: "Kosice"},
+	}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+}
+
+func TestEncodeRepertoire(t *testing.T) {
+	model := minimalInvoice()
+	model.SupplierParty.PartyName = "Ștefan s.r.l."
+
+	opt := DefaultEncodeOptions()
+	opt.Repertoire = bysquare.RepertoireLatin2
+
+	var repErr *bysquare.RepertoireError
+	if _, err := Encode(model, opt); !errors.As(err, &repErr) {
+		t.Fatalf("expected RepertoireError, got %v", err)
+	}
+
+	opt.RepertoirePolicy = bysquare.RepertoireTransliterate
+	result, err := Encode(model, opt)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if model.SupplierParty.PartyName != "Ștefan s.r.l." {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	decoded, err := Decode(result)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if decoded.SupplierParty.PartyName != "Stefan s.r.l." {
+		t.Errorf("expected transliterated party name, got %q", decoded.SupplierParty.PartyName)
+	}
+}
@@ -4,6 +4,10 @@
 // to the Slovak Banking Association specification.
 package invoice
 
+import (
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
 // InvoiceDocumentType represents the document type within bysquareType=1.
 type InvoiceDocumentType uint8
 
@@ -72,29 +76,29 @@ type CustomerParty struct {
 
 // SingleInvoiceLine represents a single invoice line item.
 type SingleInvoiceLine struct {
-	OrderLineID        string  `json:"orderLineId,omitempty"`
-	DeliveryNoteLineID string  `json:"deliveryNoteLineId,omitempty"`
-	ItemName           string  `json:"itemName,omitempty"`
-	ItemEanCode        string  `json:"itemEanCode,omitempty"`
-	PeriodFromDate     string  `json:"periodFromDate,omitempty"`
-	PeriodToDate       string  `json:"periodToDate,omitempty"`
-	InvoicedQuantity   float64 `json:"invoicedQuantity,omitempty"`
+	OrderLineID        string           `json:"orderLineId,omitempty"`
+	DeliveryNoteLineID string           `json:"deliveryNoteLineId,omitempty"`
+	ItemName           string           `json:"itemName,omitempty"`
+	ItemEanCode        string           `json:"itemEanCode,omitempty"`
+	PeriodFromDate     string           `json:"periodFromDate,omitempty"`
+	PeriodToDate       string           `json:"periodToDate,omitempty"`
+	InvoicedQuantity   bysquare.Decimal `json:"invoicedQuantity,omitempty"`
 }
 
 // TaxCategorySummary represents a tax category with amounts.
 type TaxCategorySummary struct {
 	// ClassifiedTaxCategory is a decimal in range [0, 1] representing the tax rate.
-	ClassifiedTaxCategory            float64 `json:"classifiedTaxCategory"`
-	TaxExclusiveAmount               float64 `json:"taxExclusiveAmount"`
-	TaxAmount                        float64 `json:"taxAmount"`
-	AlreadyClaimedTaxExclusiveAmount float64 `json:"alreadyClaimedTaxExclusiveAmount,omitempty"`
-	AlreadyClaimedTaxAmount          float64 `json:"alreadyClaimedTaxAmount,omitempty"`
+	ClassifiedTaxCategory            bysquare.Decimal `json:"classifiedTaxCategory"`
+	TaxExclusiveAmount               bysquare.Decimal `json:"taxExclusiveAmount"`
+	TaxAmount                        bysquare.Decimal `json:"taxAmount"`
+	AlreadyClaimedTaxExclusiveAmount bysquare.Decimal `json:"alreadyClaimedTaxExclusiveAmount,omitempty"`
+	AlreadyClaimedTaxAmount          bysquare.Decimal `json:"alreadyClaimedTaxAmount,omitempty"`
 }
 
 // MonetarySummary represents monetary summary information.
 type MonetarySummary struct {
-	PayableRoundingAmount float64 `json:"payableRoundingAmount,omitempty"`
-	PaidDepositsAmount    float64 `json:"paidDepositsAmount,omitempty"`
+	PayableRoundingAmount bysquare.Decimal `json:"payableRoundingAmount,omitempty"`
+	PaidDepositsAmount    bysquare.Decimal `json:"paidDepositsAmount,omitempty"`
 }
 
 // DataModel represents the complete invoice data structure.
@@ -107,8 +111,8 @@ type DataModel struct {
 	DeliveryNoteID       string               `json:"deliveryNoteId,omitempty"`
 	LocalCurrencyCode    string               `json:"localCurrencyCode"`
 	ForeignCurrencyCode  string               `json:"foreignCurrencyCode,omitempty"`
-	CurrRate             float64              `json:"currRate,omitempty"`
-	ReferenceCurrRate    float64              `json:"referenceCurrRate,omitempty"`
+	CurrRate             bysquare.Decimal     `json:"currRate,omitempty"`
+	ReferenceCurrRate    bysquare.Decimal     `json:"referenceCurrRate,omitempty"`
 	SupplierParty        SupplierParty        `json:"supplierParty"`
 	CustomerParty        CustomerParty        `json:"customerParty"`
 	NumberOfInvoiceLines *int                 `json:"numberOfInvoiceLines,omitempty"`
@@ -117,4 +121,13 @@ type DataModel struct {
 	TaxCategorySummaries []TaxCategorySummary `json:"taxCategorySummaries"`
 	MonetarySummary      MonetarySummary      `json:"monetarySummary"`
 	PaymentMeans         uint8                `json:"paymentMeans,omitempty"`
+	// Extra holds the fields after the end of the known layout, added by
+	// a newer specification revision or a vendor. Decode fills it and
+	// Encode writes it back unchanged, so re-encoding loses nothing.
+	Extra []string `json:"extra,omitempty"`
+}
+
+// BysquareType returns 0x01, the bysquareType of Invoice by square.
+func (m *DataModel) BysquareType() uint8 {
+	return 0x01
 }
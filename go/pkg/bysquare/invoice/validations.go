@@ -43,6 +43,16 @@ func validateDate(value string, path string) error {
 	return nil
 }
 
+func validateDecimal(value bysquare.Decimal, path string) error {
+	if value.Validate() != nil {
+		return &ValidationError{
+			Message: "invalid decimal number",
+			Path:    path,
+		}
+	}
+	return nil
+}
+
 // ValidateDataModel validates the complete invoice data model.
 func ValidateDataModel(model *DataModel) error {
 	if err := validateRequired(model.InvoiceID, "invoiceId"); err != nil {
@@ -69,9 +79,16 @@ func ValidateDataModel(model *DataModel) error {
 	}
 
 	// Foreign currency group validation
+	if err := validateDecimal(model.CurrRate, "currRate"); err != nil {
+		return err
+	}
+	if err := validateDecimal(model.ReferenceCurrRate, "referenceCurrRate"); err != nil {
+		return err
+	}
+
 	hasForeign := model.ForeignCurrencyCode != ""
-	hasCurrRate := model.CurrRate != 0
-	hasRefRate := model.ReferenceCurrRate != 0
+	hasCurrRate := !model.CurrRate.IsZero()
+	hasRefRate := !model.ReferenceCurrRate.IsZero()
 
 	if hasForeign != hasCurrRate || hasForeign != hasRefRate {
 		return &ValidationError{
@@ -153,6 +170,10 @@ func ValidateDataModel(model *DataModel) error {
 				Path:    "singleInvoiceLine.periodFromDate",
 			}
 		}
+		if err := validateDecimal(line.InvoicedQuantity, "singleInvoiceLine.invoicedQuantity"); err != nil {
+			return err
+		}
+
 		if hasFrom && hasTo {
 			if err := validateDate(line.PeriodFromDate, "singleInvoiceLine.periodFromDate"); err != nil {
 				return err
@@ -178,13 +199,101 @@ func ValidateDataModel(model *DataModel) error {
 	}
 
 	for idx, summary := range model.TaxCategorySummaries {
-		if summary.ClassifiedTaxCategory < 0 || summary.ClassifiedTaxCategory > 1 {
+		path := fmt.Sprintf("taxCategorySummaries[%d]", idx)
+		for _, f := range []struct {
+			value bysquare.Decimal
+			name  string
+		}{
+			{summary.ClassifiedTaxCategory, "classifiedTaxCategory"},
+			{summary.TaxExclusiveAmount, "taxExclusiveAmount"},
+			{summary.TaxAmount, "taxAmount"},
+			{summary.AlreadyClaimedTaxExclusiveAmount, "alreadyClaimedTaxExclusiveAmount"},
+			{summary.AlreadyClaimedTaxAmount, "alreadyClaimedTaxAmount"},
+		} {
+			if err := validateDecimal(f.value, path+"."+f.name); err != nil {
+				return err
+			}
+		}
+
+		if summary.ClassifiedTaxCategory.Cmp("0") < 0 || summary.ClassifiedTaxCategory.Cmp("1") > 0 {
 			return &ValidationError{
 				Message: "classifiedTaxCategory must be a number in range [0, 1]",
-				Path:    fmt.Sprintf("taxCategorySummaries[%d].classifiedTaxCategory", idx),
+				Path:    path + ".classifiedTaxCategory",
 			}
 		}
 	}
 
+	if err := validateDecimal(model.MonetarySummary.PayableRoundingAmount, "monetarySummary.payableRoundingAmount"); err != nil {
+		return err
+	}
+	if err := validateDecimal(model.MonetarySummary.PaidDepositsAmount, "monetarySummary.paidDepositsAmount"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateRepertoire checks every text field of model against repertoire
+// and reports all characters outside it in one *bysquare.RepertoireError.
+// bysquare.RepertoireAny accepts any model.
+func ValidateRepertoire(model *DataModel, repertoire bysquare.Repertoire) error {
+	if repertoire == bysquare.RepertoireAny {
+		return nil
+	}
+
+	var invalid []bysquare.InvalidRune
+	walkText(model, func(_ Field, path string, s *string) {
+		invalid = append(invalid, repertoire.Check(path, *s)...)
+	})
+	if len(invalid) > 0 {
+		return &bysquare.RepertoireError{Repertoire: repertoire, Runes: invalid}
+	}
 	return nil
 }
+
+// walkText calls fn for every text field of model in payload order, with
+// the Field selecting it for deburring or zero.
+func walkText(model *DataModel, fn func(field Field, path string, s *string)) {
+	fn(FieldInvoiceID, "invoiceId", &model.InvoiceID)
+	fn(0, "issueDate", &model.IssueDate)
+	fn(0, "taxPointDate", &model.TaxPointDate)
+	fn(0, "orderId", &model.OrderID)
+	fn(0, "deliveryNoteId", &model.DeliveryNoteID)
+	fn(0, "localCurrencyCode", &model.LocalCurrencyCode)
+	fn(0, "foreignCurrencyCode", &model.ForeignCurrencyCode)
+
+	sp := &model.SupplierParty
+	fn(FieldSupplierPartyName, "supplierParty.partyName", &sp.PartyName)
+	fn(0, "supplierParty.companyTaxId", &sp.CompanyTaxID)
+	fn(0, "supplierParty.companyVatId", &sp.CompanyVatID)
+	fn(0, "supplierParty.companyRegisterId", &sp.CompanyRegisterID)
+	fn(FieldSupplierPostalAddress, "supplierParty.postalAddress.streetName", &sp.PostalAddress.StreetName)
+	fn(FieldSupplierPostalAddress, "supplierParty.postalAddress.buildingNumber", &sp.PostalAddress.BuildingNumber)
+	fn(FieldSupplierPostalAddress, "supplierParty.postalAddress.cityName", &sp.PostalAddress.CityName)
+	fn(0, "supplierParty.postalAddress.postalZone", &sp.PostalAddress.PostalZone)
+	fn(FieldSupplierPostalAddress, "supplierParty.postalAddress.state", &sp.PostalAddress.State)
+	fn(0, "supplierParty.postalAddress.country", &sp.PostalAddress.Country)
+	if c := sp.Contact; c != nil {
+		fn(FieldSupplierContactName, "supplierParty.contact.name", &c.Name)
+		fn(0, "supplierParty.contact.telephone", &c.Telephone)
+		fn(0, "supplierParty.contact.email", &c.Email)
+	}
+
+	cp := &model.CustomerParty
+	fn(FieldCustomerPartyName, "customerParty.partyName", &cp.PartyName)
+	fn(0, "customerParty.companyTaxId", &cp.CompanyTaxID)
+	fn(0, "customerParty.companyVatId", &cp.CompanyVatID)
+	fn(0, "customerParty.companyRegisterId", &cp.CompanyRegisterID)
+	fn(0, "customerParty.partyIdentification", &cp.PartyIdentification)
+
+	fn(FieldInvoiceDescription, "invoiceDescription", &model.InvoiceDescription)
+
+	if line := model.SingleInvoiceLine; line != nil {
+		fn(0, "singleInvoiceLine.orderLineId", &line.OrderLineID)
+		fn(0, "singleInvoiceLine.deliveryNoteLineId", &line.DeliveryNoteLineID)
+		fn(FieldItemName, "singleInvoiceLine.itemName", &line.ItemName)
+		fn(0, "singleInvoiceLine.itemEanCode", &line.ItemEanCode)
+		fn(0, "singleInvoiceLine.periodFromDate", &line.PeriodFromDate)
+		fn(0, "singleInvoiceLine.periodToDate", &line.PeriodToDate)
+	}
+}
@@ -1,7 +1,11 @@
 package invoice
 
 import (
+	"errors"
+	"slices"
 	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
 )
 
 func TestValidateDataModel(t *testing.T) {
@@ -75,7 +79,7 @@ func TestValidateDataModel(t *testing.T) {
 			name: "tax category out of range",
 			model: func() *DataModel {
 				m := minimalInvoice()
-				m.TaxCategorySummaries[0].ClassifiedTaxCategory = 1.5
+				m.TaxCategorySummaries[0].ClassifiedTaxCategory = "1.5"
 				return m
 			}(),
 			wantErr: true,
@@ -94,8 +98,8 @@ func TestValidateDataModel(t *testing.T) {
 			model: func() *DataModel {
 				m := minimalInvoice()
 				m.ForeignCurrencyCode = "USD"
-				m.CurrRate = 1.1
-				m.ReferenceCurrRate = 1
+				m.CurrRate = "1.1"
+				m.ReferenceCurrRate = "1"
 				return m
 			}(),
 			wantErr: false,
@@ -133,3 +137,29 @@ func TestValidateDataModel(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRepertoire(t *testing.T) {
+	model := minimalInvoice()
+	model.SupplierParty.PartyName = "Dodávateľ s.r.o."
+	model.InvoiceDescription = "Služby ✓"
+
+	if err := ValidateRepertoire(model, bysquare.RepertoirePrintable); err != nil {
+		t.Fatalf("expected no error for printable text, got %v", err)
+	}
+
+	model.SupplierParty.Contact = &Contact{Name: "Jana\tMalá"}
+
+	err := ValidateRepertoire(model, bysquare.RepertoireLatin2)
+	var repErr *bysquare.RepertoireError
+	if !errors.As(err, &repErr) {
+		t.Fatalf("expected RepertoireError, got %v", err)
+	}
+
+	expected := []bysquare.InvalidRune{
+		{Path: "supplierParty.contact.name", Offset: 4, Rune: '\t'},
+		{Path: "invoiceDescription", Offset: 7, Rune: '✓'},
+	}
+	if !slices.Equal(repErr.Runes, expected) {
+		t.Errorf("expected %v, got %v", expected, repErr.Runes)
+	}
+}
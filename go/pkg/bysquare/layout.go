@@ -0,0 +1,144 @@
+package bysquare
+
+import "fmt"
+
+// fieldNames returns the data model path of every payload field for the
+// given bysquareType. Counts and extension flags are read from the values
+// themselves, so the names follow the actual layout even for malformed
+// payloads. Fields past the end of the layout, or of an unknown type, are
+// named "unknown".
+func fieldNames(bysquareType uint8, values []string) []string {
+	var names []string
+	switch bysquareType {
+	case 0x00:
+		names = payFieldNames(values)
+	case 0x01:
+		names = invoiceFieldNames(values)
+	}
+
+	if len(names) > len(values) {
+		names = names[:len(values)]
+	}
+	for len(names) < len(values) {
+		names = append(names, "unknown")
+	}
+	return names
+}
+
+// layoutCount reads a count field, treating malformed values as zero.
+func layoutCount(values []string, i int) int {
+	if i >= len(values) {
+		return 0
+	}
+	n, err := ParseNumber(values[i])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// payFieldNames walks the PAY by square layout.
+//
+//	invoiceId, payments count
+//	per payment: 9 fields, bank accounts count, 2 fields per account,
+//	             standing order flag [+4], direct debit flag [+10]
+//	per payment: beneficiary name, street, city
+func payFieldNames(values []string) []string {
+	names := []string{"invoiceId", "payments"}
+	payments := layoutCount(values, 1)
+
+	for i := 0; i < payments && len(names) < len(values); i++ {
+		p := fmt.Sprintf("payments[%d].", i)
+		for _, f := range []string{
+			"type", "amount", "currencyCode", "paymentDueDate",
+			"variableSymbol", "constantSymbol", "specificSymbol",
+			"originatorsReferenceInformation", "paymentNote",
+		} {
+			names = append(names, p+f)
+		}
+
+		names = append(names, p+"bankAccounts")
+		accounts := layoutCount(values, len(names)-1)
+		for j := 0; j < accounts && len(names) < len(values); j++ {
+			a := fmt.Sprintf("%sbankAccounts[%d].", p, j)
+			names = append(names, a+"iban", a+"bic")
+		}
+
+		names = append(names, p+"standingOrderExt")
+		if len(names) <= len(values) && values[len(names)-1] == "1" {
+			s := p + "standingOrderExt."
+			names = append(names, s+"day", s+"month", s+"periodicity", s+"lastDate")
+		}
+
+		names = append(names, p+"directDebitExt")
+		if len(names) <= len(values) && values[len(names)-1] == "1" {
+			d := p + "directDebitExt."
+			for _, f := range []string{
+				"directDebitScheme", "directDebitType", "variableSymbol",
+				"specificSymbol", "originatorsReferenceInformation", "mandateId",
+				"creditorId", "contractId", "maxAmount", "validTillDate",
+			} {
+				names = append(names, d+f)
+			}
+		}
+	}
+
+	for i := 0; i < payments && len(names) < len(values); i++ {
+		b := fmt.Sprintf("payments[%d].beneficiary.", i)
+		names = append(names, b+"name", b+"street", b+"city")
+	}
+
+	return names
+}
+
+// invoiceFieldNames walks the Invoice by square layout (40 + N*5 fields).
+func invoiceFieldNames(values []string) []string {
+	names := []string{
+		"invoiceId", "issueDate", "taxPointDate", "orderId", "deliveryNoteId",
+		"localCurrencyCode", "foreignCurrencyCode", "currRate", "referenceCurrRate",
+	}
+
+	for _, f := range []string{
+		"partyName", "companyTaxId", "companyVatId", "companyRegisterId",
+		"postalAddress.streetName", "postalAddress.buildingNumber",
+		"postalAddress.cityName", "postalAddress.postalZone",
+		"postalAddress.state", "postalAddress.country",
+		"contact.name", "contact.telephone", "contact.email",
+	} {
+		names = append(names, "supplierParty."+f)
+	}
+
+	for _, f := range []string{
+		"partyName", "companyTaxId", "companyVatId", "companyRegisterId",
+		"partyIdentification",
+	} {
+		names = append(names, "customerParty."+f)
+	}
+
+	names = append(names, "numberOfInvoiceLines", "invoiceDescription")
+
+	for _, f := range []string{
+		"orderLineId", "deliveryNoteLineId", "itemName", "itemEanCode",
+		"periodFromDate", "periodToDate", "invoicedQuantity",
+	} {
+		names = append(names, "singleInvoiceLine."+f)
+	}
+
+	names = append(names, "taxCategorySummaries")
+	summaries := layoutCount(values, len(names)-1)
+	for i := 0; i < summaries && len(names) < len(values); i++ {
+		s := fmt.Sprintf("taxCategorySummaries[%d].", i)
+		for _, f := range []string{
+			"classifiedTaxCategory", "taxExclusiveAmount", "taxAmount",
+			"alreadyClaimedTaxExclusiveAmount", "alreadyClaimedTaxAmount",
+		} {
+			names = append(names, s+f)
+		}
+	}
+
+	return append(names,
+		"monetarySummary.payableRoundingAmount",
+		"monetarySummary.paidDepositsAmount",
+		"paymentMeans",
+	)
+}
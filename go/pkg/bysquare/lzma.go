@@ -2,10 +2,13 @@ package bysquare
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 
-	"github.com/ulikunitz/xz/lzma"
+	xzlzma "github.com/ulikunitz/xz/lzma"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare/internal/lzma"
 )
 
 // CompressLZMA compresses data using LZMA1 with custom settings.
@@ -21,38 +24,60 @@ import (
 //
 // Properties byte: (pb * 5 + lp) * 9 + lc = (2 * 5 + 0) * 9 + 3 = 0x5D
 //
-// BySquare stores only the body (skips the 13-byte header)
+// BySquare stores only the body (skips the 13-byte header). The body comes
+// from a dedicated encoder that parses small payloads optimally, so it is
+// usually a few bytes shorter than the output of a general-purpose LZMA
+// writer.
 //
 // @see 3.11.
 func CompressLZMA(data []byte) ([]byte, error) {
-	var buf bytes.Buffer
-
-	// Create LZMA writer with custom properties
-	// Dictionary size: 2^17 = 131072
-	config := lzma.WriterConfig{
-		Properties: &lzma.Properties{
-			LC: 3, // Literal context bits
-			LP: 0, // Literal position bits
-			PB: 2, // Position bits
-		},
-		DictCap: 131_072, // 2^17
-		Size:    int64(len(data)),
-	}
+	return withLZMAHeader(data, lzma.Encode(data)), nil
+}
 
-	writer, err := config.NewWriter(&buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create LZMA writer: %w", err)
+// CompressLZMAReference compresses data like CompressLZMA, but the body is
+// the one the LZMA SDK encoder writes, byte for byte. The TypeScript
+// implementation compresses with that encoder, so its QR strings are
+// reproduced exactly. The body is a few bytes longer than that of
+// CompressLZMA and ends with an end marker, which decoders accept.
+//
+// Inputs longer than MaxCompressedSize are rejected with
+// ErrPayloadTooLarge.
+func CompressLZMAReference(data []byte) ([]byte, error) {
+	if len(data) > lzma.MaxReferenceInput {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrPayloadTooLarge, len(data), lzma.MaxReferenceInput)
 	}
+	return withLZMAHeader(data, lzma.EncodeReference(data)), nil
+}
 
-	if _, err := writer.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to write data: %w", err)
-	}
+// withLZMAHeader prepends the 13-byte LZMA header of the bysquare profile
+// to the body compressed from data.
+func withLZMAHeader(data, body []byte) []byte {
+	output := make([]byte, 13, 13+len(body))
+	output[0] = 0x5D
+	binary.LittleEndian.PutUint32(output[1:5], 131_072) // 2^17
+	binary.LittleEndian.PutUint64(output[5:13], uint64(len(data)))
 
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close writer: %w", err)
-	}
+	return append(output, body...)
+}
 
-	return buf.Bytes(), nil
+// Limits bounds the resources spent on decoding untrusted input. The zero
+// value applies the limits of the specification.
+type Limits struct {
+	// MaxInputLength caps the length of the QR string. Zero means no limit.
+	MaxInputLength int
+
+	// MaxPayloadSize caps the declared uncompressed size of the CRC32 and
+	// payload. Zero or values above MaxCompressedSize mean
+	// MaxCompressedSize.
+	MaxPayloadSize int
+}
+
+// maxPayloadSize returns the effective uncompressed size cap.
+func (l Limits) maxPayloadSize() int {
+	if l.MaxPayloadSize <= 0 || l.MaxPayloadSize > MaxCompressedSize {
+		return MaxCompressedSize
+	}
+	return l.MaxPayloadSize
 }
 
 // DecompressLZMA decompresses LZMA data.
@@ -69,8 +94,23 @@ func CompressLZMA(data []byte) ([]byte, error) {
 //
 // Properties byte: (pb * 5 + lp) * 9 + lc = (2 * 5 + 0) * 9 + 3 = 0x5D
 //
+// The declared uncompressed size is a hard cap: sizes above the limit are
+// rejected with ErrPayloadTooLarge before decompression starts, and no more
+// than the declared size is ever produced. When compressed bytes remain
+// after the declared size was decoded, the data is returned together with
+// ErrTrailingData.
+//
 // @see 3.11.
-func DecompressLZMA(compressed []byte, uncompressedSize int) ([]byte, error) {
+func DecompressLZMA(compressed []byte, uncompressedSize int, limits ...Limits) ([]byte, error) {
+	var limit Limits
+	if len(limits) > 0 {
+		limit = limits[0]
+	}
+
+	if uncompressedSize < 0 || uncompressedSize > limit.maxPayloadSize() {
+		return nil, fmt.Errorf("%w: declared size %d exceeds limit %d", ErrPayloadTooLarge, uncompressedSize, limit.maxPayloadSize())
+	}
+
 	header := make([]byte, 13)
 
 	// Properties: 0x5D (lc=3, lp=0, pb=2)
@@ -92,20 +132,35 @@ func DecompressLZMA(compressed []byte, uncompressedSize int) ([]byte, error) {
 	header[11] = 0x00
 	header[12] = 0x00
 
-	// Combine header with compressed data
-	fullData := append(header, compressed...)
+	// Combine header with compressed data. The bytes.Reader is read byte by
+	// byte, so its remaining length reveals trailing data afterwards.
+	input := bytes.NewReader(append(header, compressed...))
 
 	// Create LZMA reader
-	reader, err := lzma.NewReader(bytes.NewReader(fullData))
+	reader, err := xzlzma.NewReader(input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LZMA reader: %w", err)
 	}
 
-	// Read decompressed data
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, reader); err != nil {
+	// Read decompressed data, never more than the declared size
+	buf := bytes.NewBuffer(make([]byte, 0, uncompressedSize))
+	if _, err := io.Copy(buf, io.LimitReader(reader, int64(uncompressedSize))); err != nil {
 		return nil, fmt.Errorf("failed to decompress: %w", err)
 	}
 
+	if buf.Len() < uncompressedSize {
+		return nil, fmt.Errorf("%w: decompressed %d of %d bytes", ErrTruncatedPayload, buf.Len(), uncompressedSize)
+	}
+
+	// Nothing is read for an empty payload, so the range coder and end
+	// marker of a valid empty stream would count as trailing data.
+	if uncompressedSize == 0 {
+		return buf.Bytes(), nil
+	}
+
+	if input.Len() > 0 {
+		return buf.Bytes(), fmt.Errorf("%w: %d bytes after the LZMA stream", ErrTrailingData, input.Len())
+	}
+
 	return buf.Bytes(), nil
 }
@@ -0,0 +1,156 @@
+package bysquare
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	xzlzma "github.com/ulikunitz/xz/lzma"
+)
+
+func TestDecompressLZMALimits(t *testing.T) {
+	data := []byte("random-id\t1\t1\t100\tEUR")
+	compressed, err := CompressLZMA(data)
+	if err != nil {
+		t.Fatalf("CompressLZMA() error: %v", err)
+	}
+	body := compressed[13:]
+
+	decompressed, err := DecompressLZMA(body, len(data))
+	if err != nil {
+		t.Fatalf("DecompressLZMA() error: %v", err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Errorf("expected %q, got %q", data, decompressed)
+	}
+
+	testCases := []struct {
+		name     string
+		body     []byte
+		size     int
+		limits   Limits
+		expected error
+	}{
+		{"above spec limit", body, MaxCompressedSize + 1, Limits{}, ErrPayloadTooLarge},
+		{"above spec limit with larger custom limit", body, MaxCompressedSize + 1, Limits{MaxPayloadSize: 1 << 20}, ErrPayloadTooLarge},
+		{"above custom limit", body, len(data), Limits{MaxPayloadSize: 8}, ErrPayloadTooLarge},
+		{"negative size", body, -1, Limits{}, ErrPayloadTooLarge},
+		{"trailing garbage", append(append([]byte{}, body...), 0xDE, 0xAD), len(data), Limits{}, ErrTrailingData},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecompressLZMA(tc.body, tc.size, tc.limits)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestDecompressLZMAEmpty(t *testing.T) {
+	compressed, err := CompressLZMA(nil)
+	if err != nil {
+		t.Fatalf("CompressLZMA() error: %v", err)
+	}
+
+	decompressed, err := DecompressLZMA(compressed[13:], 0)
+	if err != nil {
+		t.Fatalf("DecompressLZMA() error: %v", err)
+	}
+	if len(decompressed) != 0 {
+		t.Errorf("expected empty result, got %q", decompressed)
+	}
+}
+
+func TestDecompressLZMANeverExceedsDeclaredSize(t *testing.T) {
+	// 64 KiB of zeros compresses to a few hundred bytes.
+	data := make([]byte, 1<<16-1)
+	compressed, err := CompressLZMA(data)
+	if err != nil {
+		t.Fatalf("CompressLZMA() error: %v", err)
+	}
+
+	decompressed, err := DecompressLZMA(compressed[13:], 100)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(decompressed) > 100 {
+		t.Errorf("expected at most 100 bytes, got %d", len(decompressed))
+	}
+}
+
+// compressXZ is the general-purpose writer CompressLZMA used before, kept
+// as the baseline for the benchmarks.
+func compressXZ(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	config := xzlzma.WriterConfig{
+		Properties: &xzlzma.Properties{LC: 3, LP: 0, PB: 2},
+		DictCap:    131_072,
+		Size:       int64(len(data)),
+	}
+	writer, err := config.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// benchmarkPayloads are checksummed payloads of typical sizes.
+var benchmarkPayloads = []struct {
+	name    string
+	payload []byte
+}{
+	{"pay", AddChecksum("random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t1\tSK9611000000002918599669\t\t0\t0\tJohn Doe\t\t")},
+	{"pay-note", AddChecksum("\t1\t1\t1250.5\tEUR\t20241231\t2024001\t0308\t\t\tFaktura 2024001 za sluzby poskytnute v decembri\t2\tSK9611000000002918599669\tTATRSKBX\tCZ6508000000192000145399\tGIBACZPX\t0\t0\tFirma s.r.o.\tHlavna 1\tBratislava")},
+	{"invoice", AddChecksum("INV-2024-001\t20240115\t20240115\tORD-123\tDN-456\tEUR\t\t\t\tSupplier s.r.o.\t2020123456\tSK2020123456\t12345678\tMain Street\t1\tBratislava\t81101\t\tSVK\tJohn Doe\t+421900123456\tjohn@example.com\tCustomer a.s.\t\t\t\t\t3\t\t\t\t\t\t\t\t\t1\t0.2\t1000\t200\t\t\t\t\t1")},
+	{"large", AddChecksum(strings.Repeat("Payment\t100\tEUR\tSK9611000000002918599669\t", 200))},
+}
+
+func TestCompressLZMASmallerThanXZ(t *testing.T) {
+	for _, tc := range benchmarkPayloads {
+		ours, err := CompressLZMA(tc.payload)
+		if err != nil {
+			t.Fatalf("%s: CompressLZMA() error: %v", tc.name, err)
+		}
+		xz, err := compressXZ(tc.payload)
+		if err != nil {
+			t.Fatalf("%s: compressXZ() error: %v", tc.name, err)
+		}
+		if len(ours) > len(xz) {
+			t.Errorf("%s: expected at most %d bytes, got %d", tc.name, len(xz), len(ours))
+		}
+	}
+}
+
+func BenchmarkCompressLZMA(b *testing.B) {
+	for _, tc := range benchmarkPayloads {
+		b.Run(tc.name, func(b *testing.B) {
+			b.Run("optimal", func(b *testing.B) {
+				benchmarkCompress(b, tc.payload, CompressLZMA)
+			})
+			b.Run("xz", func(b *testing.B) {
+				benchmarkCompress(b, tc.payload, compressXZ)
+			})
+		})
+	}
+}
+
+func benchmarkCompress(b *testing.B, data []byte, compress func([]byte) ([]byte, error)) {
+	var out []byte
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var err error
+		if out, err = compress(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(out)-13), "body-bytes")
+}
@@ -0,0 +1,31 @@
+package pay
+
+import (
+	"fmt"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+func init() {
+	bysquare.RegisterCodec(bysquare.Codec{
+		Name:         "pay",
+		BysquareType: 0x00,
+		DocumentType: 0x00,
+		New:          func() bysquare.Document { return &DataModel{} },
+		Encode: func(doc bysquare.Document) (string, error) {
+			model, ok := doc.(*DataModel)
+			if !ok {
+				return "", fmt.Errorf("%w: %T is not a *pay.DataModel", bysquare.ErrUnexpectedType, doc)
+			}
+			return Encode(*model)
+		},
+		Decode: func(qr string) (bysquare.Document, error) {
+			model, err := Decode(qr)
+			if err != nil {
+				return nil, err
+			}
+			return &model, nil
+		},
+		Schema: bysquare.JSONSchema(DataModel{}),
+	})
+}
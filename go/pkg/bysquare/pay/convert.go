@@ -0,0 +1,70 @@
+package pay
+
+import (
+	"fmt"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+// Convert returns a copy of model that fits the layout of version, together
+// with the values the target version cannot carry. Decoded models hold the
+// fields of every version, so a model decoded from one version converts to
+// any other; the caller's model is left untouched.
+//
+// Per specification revision:
+//
+//	+---------+-------------------------------------------+
+//	| Version | Beneficiary block                         |
+//	+---------+-------------------------------------------+
+//	| 1.0.0   | absent, name, street and city are dropped |
+//	| 1.1.0   | optional                                  |
+//	| 1.2.0   | name required                             |
+//	+---------+-------------------------------------------+
+//
+// Dropped values are reported as bysquare.Changes with an empty After.
+// Upgrading to 1.2.0 loses nothing but needs a beneficiary name on every
+// payment; a missing one is returned as a *ValidationError together with
+// the converted model, so callers can fill it in before encoding.
+func Convert(model DataModel, version bysquare.Version) (DataModel, bysquare.Changes, error) {
+	if version > bysquare.Version120 {
+		return DataModel{}, nil, fmt.Errorf("%w: %s", bysquare.ErrUnsupportedVersion, version)
+	}
+
+	detach(&model)
+
+	var lost bysquare.Changes
+	for i := range model.Payments {
+		payment := &model.Payments[i]
+		path := fmt.Sprintf("payments[%d].beneficiary", i)
+
+		switch {
+		case version < bysquare.Version110 && payment.Beneficiary != nil:
+			for _, f := range []struct{ name, value string }{
+				{"name", payment.Beneficiary.Name},
+				{"street", payment.Beneficiary.Street},
+				{"city", payment.Beneficiary.City},
+			} {
+				if f.value != "" {
+					lost = append(lost, bysquare.Change{Path: path + "." + f.name, Before: f.value})
+				}
+			}
+			payment.Beneficiary = nil
+
+		case version >= bysquare.Version110 && payment.Beneficiary == nil:
+			payment.Beneficiary = &Beneficiary{}
+		}
+	}
+
+	if version >= bysquare.Version120 {
+		for i, payment := range model.Payments {
+			if payment.Beneficiary.Name == "" {
+				return model, lost, &ValidationError{
+					Message: "beneficiary name is required",
+					Path:    fmt.Sprintf("payments[%d].beneficiary.name", i),
+				}
+			}
+		}
+	}
+
+	return model, lost, nil
+}
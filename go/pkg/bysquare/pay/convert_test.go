@@ -0,0 +1,68 @@
+package pay
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
+func TestConvert(t *testing.T) {
+	model := DataModel{
+		InvoiceID: "random-id",
+		Payments: []SimplePayment{
+			{
+				Type:         PaymentTypePaymentOrder,
+				Amount:       "100",
+				BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+				Beneficiary:  &Beneficiary{Name: "John Doe", Street: "Main 1"},
+			},
+			{
+				Type:         PaymentTypePaymentOrder,
+				Amount:       "50",
+				BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			},
+		},
+	}
+
+	down, lost, err := Convert(model, bysquare.Version100)
+	if err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+	expected := bysquare.Changes{
+		{Path: "payments[0].beneficiary.name", Before: "John Doe"},
+		{Path: "payments[0].beneficiary.street", Before: "Main 1"},
+	}
+	if !slices.Equal(lost, expected) {
+		t.Errorf("expected %+v, got %+v", expected, lost)
+	}
+	if down.Payments[0].Beneficiary != nil {
+		t.Errorf("expected beneficiary dropped, got %+v", down.Payments[0].Beneficiary)
+	}
+	if model.Payments[0].Beneficiary == nil {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	up, lost, err := Convert(down, bysquare.Version110)
+	if err != nil || len(lost) != 0 {
+		t.Fatalf("expected lossless upgrade, got %+v, %v", lost, err)
+	}
+	if up.Payments[1].Beneficiary == nil {
+		t.Error("expected an empty beneficiary on upgrade")
+	}
+
+	up, _, err = Convert(model, bysquare.Version120)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Path != "payments[1].beneficiary.name" {
+		t.Fatalf("expected missing beneficiary name, got %v", err)
+	}
+	up.Payments[1].Beneficiary.Name = "Jane Doe"
+	if _, err := Encode(up); err != nil {
+		t.Errorf("Encode() error: %v", err)
+	}
+
+	if _, _, err := Convert(model, bysquare.Version(3)); !errors.Is(err, bysquare.ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
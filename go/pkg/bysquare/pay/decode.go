@@ -1,8 +1,9 @@
 package pay
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
@@ -11,6 +12,34 @@ import (
 // ErrMissingBankAccount indicates no bank accounts provided.
 var ErrMissingBankAccount = fmt.Errorf("at least one bank account required")
 
+// DecodeOptions configures the decoding process.
+type DecodeOptions struct {
+	// Lenient recovers as much data as possible from damaged or
+	// non-conforming codes. Checksum mismatches, unsupported versions,
+	// missing fields and malformed counts are collected as
+	// bysquare.Warnings, which Decode returns as the error together with
+	// the best-effort model.
+	Lenient bool
+
+	// Strict rejects anything a conforming encoder would not produce:
+	// a non-zero reserved nibble, malformed numbers, extension flags other
+	// than "0" and "1", extensions on payments of another type, and
+	// missing or trailing fields. Every violation is reported as a
+	// bysquare.FieldError matching bysquare.ErrNonCanonical, joined into
+	// one error. Strict cannot be combined with Lenient.
+	Strict bool
+
+	// Limits bounds the resources spent on untrusted input, such as codes
+	// from a public scan endpoint. The zero value applies the limits of
+	// the specification.
+	Limits bysquare.Limits
+}
+
+// DefaultDecodeOptions returns default decoding options.
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{}
+}
+
 // Decode parses a BySquare QR string back to DataModel.
 //
 // Input binary structure (after base32hex decoding):
@@ -32,132 +61,247 @@ var ErrMissingBankAccount = fmt.Errorf("at least one bank account required")
 //	+------------------+---------------------------+
 //
 // @see 3.16.
-func Decode(qr string) (DataModel, error) {
-	bytes, err := bysquare.DecodeBase32Hex(qr, true)
-	if err != nil {
-		return DataModel{}, fmt.Errorf("base32hex decode failed: %w", err)
+func Decode(qr string, opts ...DecodeOptions) (DataModel, error) {
+	options := DefaultDecodeOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.Lenient && options.Strict {
+		return DataModel{}, bysquare.ErrLenientStrict
 	}
 
-	if len(bytes) < 4 {
-		return DataModel{}, fmt.Errorf("invalid data: too short")
+	header, payload, err := bysquare.Open(qr, 0x00, bysquare.OpenOptions{Lenient: options.Lenient, Limits: options.Limits})
+	var warnings bysquare.Warnings
+	if err != nil && !errors.As(err, &warnings) {
+		return DataModel{}, err
 	}
 
-	headerBytes := bytes[0:2]
-	header := bysquare.ParseBysquareHeader(headerBytes)
+	d := &decoder{
+		parts:    strings.Split(payload, "\t"),
+		version:  bysquare.Version(header.Version),
+		options:  options,
+		warnings: warnings,
+	}
+	if options.Strict && header.Reserved != 0 {
+		d.violations = append(d.violations, fmt.Errorf("%w: reserved nibble is %d", bysquare.ErrNonCanonical, header.Reserved))
+	}
 
-	if header.Version > uint8(bysquare.Version120) {
-		return DataModel{}, fmt.Errorf("unsupported version: %d", header.Version)
+	model, err := d.deserialize()
+	if err != nil {
+		err = fmt.Errorf("deserialization failed: %w", err)
+	}
+	if len(d.violations) > 0 {
+		return DataModel{}, errors.Join(append(d.violations, err)...)
+	}
+	if err != nil {
+		return DataModel{}, err
 	}
 
-	payloadLengthBytes := bytes[2:4]
-	payloadLength := binary.LittleEndian.Uint16(payloadLengthBytes)
+	return model, d.warnings.Err()
+}
+
+// decoder holds the state of one deserialization.
+type decoder struct {
+	parts    []string
+	idx      int
+	version  bysquare.Version
+	options  DecodeOptions
+	warnings bysquare.Warnings
 
-	compressed := bytes[4:]
+	// truncated is set once a lenient decode has reported missing fields;
+	// reads past the end then return empty fields.
+	truncated bool
 
-	decompressed, err := bysquare.DecompressLZMA(compressed, int(payloadLength))
-	if err != nil {
-		return DataModel{}, fmt.Errorf("LZMA decompression failed: %w", err)
+	// violations collects non-canonical fields in strict mode.
+	violations []error
+}
+
+// warn records a recoverable problem in lenient mode and returns nil, or
+// returns the problem unchanged otherwise.
+func (d *decoder) warn(err error) error {
+	if !d.options.Lenient {
+		return err
 	}
+	d.warnings = append(d.warnings, err)
+	return nil
+}
 
-	if len(decompressed) < 4 {
-		return DataModel{}, fmt.Errorf("decompressed data too short")
+// violate records a non-canonical field in strict mode.
+func (d *decoder) violate(index int, name string, err error) {
+	if !d.options.Strict {
+		return
 	}
+	d.violations = append(d.violations, &bysquare.FieldError{
+		Index: index,
+		Name:  name,
+		Err:   fmt.Errorf("%w: %w", bysquare.ErrNonCanonical, err),
+	})
+}
 
-	checksumBytes := decompressed[0:4]
-	expectedChecksum := binary.LittleEndian.Uint32(checksumBytes)
+// need ensures that n more fields follow the current position. In lenient
+// mode missing fields read as empty values and only the first truncation
+// is recorded.
+func (d *decoder) need(n int, name string) error {
+	if n <= len(d.parts)-d.idx {
+		return nil
+	}
+	return d.truncate(name)
+}
 
-	payload := decompressed[4:]
-	payloadStr := string(payload)
+// truncate reports that the payload ends inside name.
+func (d *decoder) truncate(name string) error {
+	if d.truncated {
+		return nil
+	}
+	err := d.warn(&bysquare.FieldError{Index: len(d.parts), Name: name, Err: bysquare.ErrTruncatedPayload})
+	if err != nil {
+		return err
+	}
+	d.truncated = true
+	return nil
+}
+
+// peek returns the field at the current position, or "" past the end.
+func (d *decoder) peek() string {
+	if d.idx >= len(d.parts) {
+		return ""
+	}
+	return d.parts[d.idx]
+}
+
+// next returns the field at the current position and advances. Past the
+// end of a truncated payload it returns "" and stays put.
+func (d *decoder) next() string {
+	if d.idx >= len(d.parts) {
+		return ""
+	}
+	v := d.parts[d.idx]
+	d.idx++
+	return v
+}
 
-	actualChecksum := bysquare.Crc32Checksum(payloadStr)
-	if actualChecksum != expectedChecksum {
-		return DataModel{}, fmt.Errorf("CRC32 checksum mismatch")
+// number parses the integer field at the current position and advances.
+// Malformed values read as zero and are a violation in strict mode.
+func (d *decoder) number(name string) int {
+	index := d.idx
+	n, err := bysquare.ParseNumber(d.next())
+	if err != nil {
+		d.violate(index, name, err)
 	}
+	return n
+}
 
-	model, err := deserialize(payloadStr)
+// decimal parses the decimal field at the current position and advances.
+// Malformed values read as zero and are a violation in strict mode.
+func (d *decoder) decimal(name string) bysquare.Decimal {
+	index := d.idx
+	v, err := bysquare.ParseDecimal(d.next())
 	if err != nil {
-		return DataModel{}, fmt.Errorf("deserialization failed: %w", err)
+		d.violate(index, name, err)
 	}
+	return v
+}
 
-	return model, nil
+// flag reads the extension flag at the current position and advances.
+// Only "1" enables the extension; values other than "0" and "1" are a
+// violation in strict mode.
+func (d *decoder) flag(name string) bool {
+	v := d.next()
+	if v != "0" && v != "1" {
+		d.violate(d.idx-1, name, fmt.Errorf("extension flag %q", v))
+	}
+	return v == "1"
 }
 
-// deserialize parses tab-separated format to DataModel.
-func deserialize(data string) (DataModel, error) {
-	parts := strings.Split(data, "\t")
-	idx := 0
+// count parses the count field at the current position and advances. In
+// lenient mode malformed counts are recorded and read as zero.
+func (d *decoder) count(name string) (int, error) {
+	index := d.idx
+	n, err := parseCount(d.next())
+	if err != nil {
+		return 0, d.warn(&bysquare.FieldError{Index: index, Name: name, Err: err})
+	}
+	return n, nil
+}
 
-	if len(parts) < 2 {
-		return DataModel{}, fmt.Errorf("insufficient data fields")
+// deserialize parses tab-separated format to DataModel.
+func (d *decoder) deserialize() (DataModel, error) {
+	if err := d.need(2, "payments"); err != nil {
+		return DataModel{}, err
 	}
 
-	invoiceID := parts[idx]
-	idx++
+	invoiceID := d.next()
 
-	paymentsCount, err := bysquare.ParseNumber(parts[idx])
+	paymentsCount, err := d.count("payments")
 	if err != nil {
-		return DataModel{}, fmt.Errorf("invalid payments count: %w", err)
+		return DataModel{}, err
 	}
-	idx++
 
 	model := DataModel{
 		InvoiceID: invoiceID,
-		Payments:  make([]SimplePayment, 0, paymentsCount),
+		Payments:  make([]SimplePayment, 0, min(paymentsCount, len(d.parts))),
 	}
 
 	for i := 0; i < paymentsCount; i++ {
-		if idx+9 > len(parts) {
-			return DataModel{}, fmt.Errorf("insufficient payment fields")
+		if d.options.Lenient && d.idx >= len(d.parts) {
+			// Do not invent payments that are missing entirely.
+			d.warn(&bysquare.FieldError{
+				Index: d.idx,
+				Name:  fmt.Sprintf("payments[%d]", i),
+				Err:   bysquare.ErrTruncatedPayload,
+			})
+			break
 		}
 
-		paymentType, _ := bysquare.ParseNumber(parts[idx])
-		idx++
-		amount, _ := bysquare.ParseFloat(parts[idx])
-		idx++
-		currencyCode := parts[idx]
-		idx++
-		paymentDueDate := parts[idx]
-		idx++
-		variableSymbol := parts[idx]
-		idx++
-		constantSymbol := parts[idx]
-		idx++
-		specificSymbol := parts[idx]
-		idx++
-		originatorsRefInfo := parts[idx]
-		idx++
-		paymentNote := parts[idx]
-		idx++
+		if err := d.need(10, fmt.Sprintf("payments[%d]", i)); err != nil {
+			return DataModel{}, err
+		}
+
+		p := fmt.Sprintf("payments[%d]", i)
+		paymentType := d.number(p + ".type")
+		amount := d.decimal(p + ".amount")
 
 		payment := SimplePayment{
 			Type:                            PaymentType(paymentType),
 			Amount:                          amount,
-			CurrencyCode:                    CurrencyCode(currencyCode),
-			PaymentDueDate:                  paymentDueDate,
-			VariableSymbol:                  variableSymbol,
-			ConstantSymbol:                  constantSymbol,
-			SpecificSymbol:                  specificSymbol,
-			OriginatorsReferenceInformation: originatorsRefInfo,
-			PaymentNote:                     paymentNote,
+			CurrencyCode:                    CurrencyCode(d.next()),
+			PaymentDueDate:                  d.next(),
+			VariableSymbol:                  d.next(),
+			ConstantSymbol:                  d.next(),
+			SpecificSymbol:                  d.next(),
+			OriginatorsReferenceInformation: d.next(),
+			PaymentNote:                     d.next(),
 			BankAccounts:                    []BankAccount{},
 		}
 
-		accountsCount, _ := bysquare.ParseNumber(parts[idx])
-		idx++
+		accountsIndex := d.idx
+		accountsCount, err := parseCount(d.next())
+		if err != nil {
+			d.violate(accountsIndex, p+".bankAccounts", err)
+			accountsCount = 0
+		}
+		if remaining := (len(d.parts) - d.idx + 1) / 2; d.options.Lenient && accountsCount > remaining {
+			// Do not invent accounts that are missing entirely.
+			d.truncate(p + ".bankAccounts")
+			accountsCount = remaining
+		}
 
 		for j := 0; j < accountsCount; j++ {
-			if idx+2 > len(parts) {
-				return DataModel{}, fmt.Errorf("insufficient bank account fields")
+			name := fmt.Sprintf("%s.bankAccounts[%d]", p, j)
+			if err := d.need(2, name); err != nil {
+				return DataModel{}, err
 			}
 
-			iban := parts[idx]
-			idx++
-			if iban == "" {
-				return DataModel{}, ErrMissingBankAccount
+			if d.peek() == "" {
+				err := d.warn(&bysquare.FieldError{Index: d.idx, Name: name + ".iban", Err: ErrMissingBankAccount})
+				if err != nil {
+					return DataModel{}, err
+				}
 			}
 
-			bic := parts[idx]
-			idx++
+			iban := d.next()
+			bic := d.next()
 
 			payment.BankAccounts = append(payment.BankAccounts, BankAccount{
 				IBAN: iban,
@@ -166,27 +310,24 @@ func deserialize(data string) (DataModel, error) {
 		}
 
 		// Standing order extension
-		if idx >= len(parts) {
-			return DataModel{}, fmt.Errorf("missing standing order extension field")
+		s := p + ".standingOrderExt"
+		if err := d.need(1, s); err != nil {
+			return DataModel{}, err
 		}
-		standingOrderExt := parts[idx]
-		idx++
 
-		if standingOrderExt == "1" {
-			if idx+4 > len(parts) {
-				return DataModel{}, fmt.Errorf("insufficient standing order fields")
+		if flagIndex := d.idx; d.flag(s) {
+			if err := d.need(4, s); err != nil {
+				return DataModel{}, err
 			}
 
-			day, _ := bysquare.ParseNumber(parts[idx])
-			idx++
-			month, _ := bysquare.ParseNumber(parts[idx])
-			idx++
-			periodicity := parts[idx]
-			idx++
-			lastDate := parts[idx]
-			idx++
+			day := d.number(s + ".day")
+			month := d.number(s + ".month")
+			periodicity := d.next()
+			lastDate := d.next()
 
-			if payment.Type == PaymentTypeStandingOrder {
+			if payment.Type != PaymentTypeStandingOrder {
+				d.violate(flagIndex, s, fmt.Errorf("extension on payment type %d", payment.Type))
+			} else {
 				payment.StandingOrderExt = &StandingOrder{
 					Day:         uint8(day),
 					Month:       uint16(month),
@@ -197,39 +338,30 @@ func deserialize(data string) (DataModel, error) {
 		}
 
 		// Direct debit extension
-		if idx >= len(parts) {
-			return DataModel{}, fmt.Errorf("missing direct debit extension field")
+		dd := p + ".directDebitExt"
+		if err := d.need(1, dd); err != nil {
+			return DataModel{}, err
 		}
-		directDebitExt := parts[idx]
-		idx++
 
-		if directDebitExt == "1" {
-			if idx+10 > len(parts) {
-				return DataModel{}, fmt.Errorf("insufficient direct debit fields")
+		if flagIndex := d.idx; d.flag(dd) {
+			if err := d.need(10, dd); err != nil {
+				return DataModel{}, err
 			}
 
-			scheme, _ := bysquare.ParseNumber(parts[idx])
-			idx++
-			ddType, _ := bysquare.ParseNumber(parts[idx])
-			idx++
-			varSymbol := parts[idx]
-			idx++
-			specSymbol := parts[idx]
-			idx++
-			origRefInfo := parts[idx]
-			idx++
-			mandateID := parts[idx]
-			idx++
-			creditorID := parts[idx]
-			idx++
-			contractID := parts[idx]
-			idx++
-			maxAmount, _ := bysquare.ParseFloat(parts[idx])
-			idx++
-			validTillDate := parts[idx]
-			idx++
-
-			if payment.Type == PaymentTypeDirectDebit {
+			scheme := d.number(dd + ".directDebitScheme")
+			ddType := d.number(dd + ".directDebitType")
+			varSymbol := d.next()
+			specSymbol := d.next()
+			origRefInfo := d.next()
+			mandateID := d.next()
+			creditorID := d.next()
+			contractID := d.next()
+			maxAmount := d.decimal(dd + ".maxAmount")
+			validTillDate := d.next()
+
+			if payment.Type != PaymentTypeDirectDebit {
+				d.violate(flagIndex, dd, fmt.Errorf("extension on payment type %d", payment.Type))
+			} else {
 				payment.DirectDebitExt = &DirectDebit{
 					DirectDebitScheme:        uint8(scheme),
 					DirectDebitType:          uint8(ddType),
@@ -248,30 +380,56 @@ func deserialize(data string) (DataModel, error) {
 		model.Payments = append(model.Payments, payment)
 	}
 
+	// Beneficiary blocks were added in 1.1.0. Encoders that ignore the
+	// header version append them to 1.0.0 payloads as well; outside strict
+	// mode a 1.0.0 payload ending in exactly one block per payment is read
+	// as such, strict mode rejects it.
+	beneficiaries := d.version >= bysquare.Version110
+	if !beneficiaries && len(model.Payments) > 0 && len(d.parts)-d.idx == 3*len(model.Payments) {
+		if d.options.Strict {
+			d.violate(d.idx, "payments[0].beneficiary", fmt.Errorf("beneficiary block in version %s", d.version))
+			return model, nil
+		}
+		beneficiaries = true
+	}
+
 	// Parse beneficiary blocks (one per payment)
-	for i := 0; i < paymentsCount; i++ {
-		if idx+3 > len(parts) {
+	if beneficiaries {
+		for i := range model.Payments {
+			if d.idx+3 > len(d.parts) {
+				d.violate(len(d.parts), fmt.Sprintf("payments[%d].beneficiary", i), bysquare.ErrTruncatedPayload)
+				model.Payments[i].Beneficiary = &Beneficiary{
+					Name:   "",
+					Street: "",
+					City:   "",
+				}
+				continue
+			}
+
 			model.Payments[i].Beneficiary = &Beneficiary{
-				Name:   "",
-				Street: "",
-				City:   "",
+				Name:   d.next(),
+				Street: d.next(),
+				City:   d.next(),
 			}
-			continue
 		}
+	}
 
-		name := parts[idx]
-		idx++
-		street := parts[idx]
-		idx++
-		city := parts[idx]
-		idx++
-
-		model.Payments[i].Beneficiary = &Beneficiary{
-			Name:   name,
-			Street: street,
-			City:   city,
-		}
+	if d.idx < len(d.parts) {
+		d.violate(d.idx, "unknown", fmt.Errorf("%d trailing fields", len(d.parts)-d.idx))
+		model.Extra = slices.Clone(d.parts[d.idx:])
 	}
 
 	return model, nil
 }
+
+// parseCount parses a block count field. An empty field counts as zero.
+func parseCount(s string) (int, error) {
+	n, err := bysquare.ParseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative count %d", n)
+	}
+	return n, nil
+}
@@ -1,7 +1,13 @@
 package pay
 
 import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/xseman/bysquare/go/pkg/bysquare"
 )
 
 func TestDecodeSimplePayment(t *testing.T) {
@@ -21,7 +27,7 @@ func TestDecodeSimplePayment(t *testing.T) {
 	}
 
 	payment := model.Payments[0]
-	if payment.Amount != 100 {
+	if payment.Amount != "100" {
 		t.Errorf("expected Amount 100, got %v", payment.Amount)
 	}
 
@@ -39,6 +45,11 @@ func TestDecodeSimplePayment(t *testing.T) {
 }
 
 func TestDecodeInvalidInput(t *testing.T) {
+	invoiceFrame, err := bysquare.Seal(bysquare.BysquareHeader{BySquareType: 0x01}, "random-id\t0")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
 	tests := []struct {
 		name  string
 		input string
@@ -47,6 +58,7 @@ func TestDecodeInvalidInput(t *testing.T) {
 		{"too short", "00"},
 		{"invalid base32hex", "!!!INVALID!!!"},
 		{"garbage data", "ZZZZZZZZZZZZZZZZZZZZ"},
+		{"invoice bysquareType", invoiceFrame},
 	}
 
 	for _, tt := range tests {
@@ -70,7 +82,7 @@ func TestRoundTrip(t *testing.T) {
 				InvoiceID: "test-roundtrip",
 				Payments: []SimplePayment{{
 					Type:           PaymentTypePaymentOrder,
-					Amount:         123.45,
+					Amount:         "123.45",
 					CurrencyCode:   CurrencyEUR,
 					VariableSymbol: "456789",
 					PaymentNote:    "Test payment",
@@ -90,7 +102,7 @@ func TestRoundTrip(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypeStandingOrder,
-					Amount:       50,
+					Amount:       "50",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -110,7 +122,7 @@ func TestRoundTrip(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypeDirectDebit,
-					Amount:       200,
+					Amount:       "200",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -120,7 +132,7 @@ func TestRoundTrip(t *testing.T) {
 						DirectDebitType:   0,
 						MandateID:         "MANDATE-1",
 						CreditorID:        "CRED-1",
-						MaxAmount:         500,
+						MaxAmount:         "500",
 					},
 					Beneficiary: &Beneficiary{Name: "Test"},
 				}},
@@ -131,7 +143,7 @@ func TestRoundTrip(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       10,
+					Amount:       "10",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -183,3 +195,415 @@ func TestRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeTypedErrors(t *testing.T) {
+	seal := func(version uint8, payload string) string {
+		qr, err := bysquare.Seal(bysquare.BysquareHeader{Version: version}, payload)
+		if err != nil {
+			t.Fatalf("Seal() error: %v", err)
+		}
+		return qr
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		expected  error
+		wantIndex int
+		wantName  string
+	}{
+		{
+			name:     "unsupported version",
+			input:    seal(0x0F, "random-id\t0"),
+			expected: bysquare.ErrUnsupportedVersion,
+		},
+		{
+			name:      "truncated payment",
+			input:     seal(0x02, "random-id\t1\t1\t100"),
+			expected:  bysquare.ErrTruncatedPayload,
+			wantIndex: 4,
+			wantName:  "payments[0]",
+		},
+		{
+			name:      "truncated bank account",
+			input:     seal(0x02, "random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t2\tSK9611000000002918599669\t"),
+			expected:  bysquare.ErrTruncatedPayload,
+			wantIndex: 14,
+			wantName:  "payments[0].bankAccounts[1]",
+		},
+		{
+			name:      "missing IBAN",
+			input:     seal(0x02, "random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t1\t\t\t0\t0"),
+			expected:  ErrMissingBankAccount,
+			wantIndex: 12,
+			wantName:  "payments[0].bankAccounts[0].iban",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.input)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			if tt.wantName == "" {
+				return
+			}
+
+			var fieldErr *bysquare.FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected *bysquare.FieldError, got %T", err)
+			}
+			if fieldErr.Index != tt.wantIndex {
+				t.Errorf("expected index %d, got %d", tt.wantIndex, fieldErr.Index)
+			}
+			if fieldErr.Name != tt.wantName {
+				t.Errorf("expected name %q, got %q", tt.wantName, fieldErr.Name)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidPaymentsCount(t *testing.T) {
+	for _, count := range []string{"x", "-1"} {
+		qr, err := bysquare.Seal(bysquare.BysquareHeader{}, "random-id\t"+count)
+		if err != nil {
+			t.Fatalf("Seal() error: %v", err)
+		}
+
+		_, err = Decode(qr)
+		var fieldErr *bysquare.FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("count %q: expected *bysquare.FieldError, got %v", count, err)
+		}
+		if fieldErr.Index != 1 || fieldErr.Name != "payments" {
+			t.Errorf("count %q: expected field 1 (payments), got %d (%s)", count, fieldErr.Index, fieldErr.Name)
+		}
+	}
+}
+
+func TestDecodeLenient(t *testing.T) {
+	payload := "random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t1\tSK9611000000002918599669\t\t0"
+
+	// Same payload with a stored checksum that does not match.
+	checked := bysquare.AddChecksum(payload)
+	binary.LittleEndian.PutUint32(checked[:4], 0xFFFFFFFF)
+	compressed, err := bysquare.CompressLZMA(checked)
+	if err != nil {
+		t.Fatalf("CompressLZMA() error: %v", err)
+	}
+	corrupted, err := bysquare.Frame{Length: len(checked), Body: compressed[13:]}.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() error: %v", err)
+	}
+
+	truncated, err := bysquare.Seal(bysquare.BysquareHeader{}, payload)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{"checksum mismatch", string(corrupted), bysquare.ErrChecksumMismatch},
+		{"missing direct debit flag", truncated, bysquare.ErrTruncatedPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.input); !errors.Is(err, tt.expected) {
+				t.Fatalf("strict: expected %v, got %v", tt.expected, err)
+			}
+
+			model, err := Decode(tt.input, DecodeOptions{Lenient: true})
+			var warnings bysquare.Warnings
+			if !errors.As(err, &warnings) {
+				t.Fatalf("expected bysquare.Warnings, got %v", err)
+			}
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected warning %v, got %v", tt.expected, err)
+			}
+
+			if len(model.Payments) != 1 {
+				t.Fatalf("expected 1 payment, got %d", len(model.Payments))
+			}
+			if model.Payments[0].Amount != "100" {
+				t.Errorf("expected amount 100, got %v", model.Payments[0].Amount)
+			}
+			if model.Payments[0].BankAccounts[0].IBAN != "SK9611000000002918599669" {
+				t.Errorf("expected IBAN SK9611000000002918599669, got %q", model.Payments[0].BankAccounts[0].IBAN)
+			}
+		})
+	}
+}
+
+func TestDecodeLenientMissingPayments(t *testing.T) {
+	qr, err := bysquare.Seal(bysquare.BysquareHeader{}, "random-id\t2\t1\t100\tEUR\t\t\t\t\t\t\t1\tSK9611000000002918599669\t\t0\t0")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	model, err := Decode(qr, DecodeOptions{Lenient: true})
+	var fieldErr *bysquare.FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *bysquare.FieldError warning, got %v", err)
+	}
+	if fieldErr.Name != "payments[1]" {
+		t.Errorf("expected name payments[1], got %q", fieldErr.Name)
+	}
+	if len(model.Payments) != 1 {
+		t.Errorf("expected 1 recovered payment, got %d", len(model.Payments))
+	}
+}
+
+func TestDecodeLenientAccountsCount(t *testing.T) {
+	qr, err := bysquare.Seal(bysquare.BysquareHeader{}, "id\t1\t1\t100\tEUR\t\t\t\t\t\t\t20000000\t")
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	model, err := Decode(qr, DecodeOptions{Lenient: true})
+	var warnings bysquare.Warnings
+	if !errors.As(err, &warnings) {
+		t.Fatalf("expected bysquare.Warnings, got %v", err)
+	}
+	truncations := 0
+	for _, w := range warnings {
+		if errors.Is(w, bysquare.ErrTruncatedPayload) {
+			truncations++
+		}
+	}
+	if truncations != 1 {
+		t.Errorf("expected a single truncation warning, got %d", truncations)
+	}
+	if n := len(model.Payments[0].BankAccounts); n != 1 {
+		t.Errorf("expected the accounts count capped to 1, got %d", n)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	canonical, err := Encode(DataModel{
+		InvoiceID: "random-id",
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "100",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			Beneficiary:  &Beneficiary{Name: "John Doe"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if _, err := Decode(canonical, DecodeOptions{Strict: true}); err != nil {
+		t.Fatalf("expected canonical code to pass, got %v", err)
+	}
+
+	seal := func(header bysquare.BysquareHeader, payload string) string {
+		qr, err := bysquare.Seal(header, payload)
+		if err != nil {
+			t.Fatalf("Seal() error: %v", err)
+		}
+		return qr
+	}
+
+	// Payment order with a standing order extension, a direct debit flag
+	// of "2", a malformed bank accounts count and a trailing field.
+	qr := seal(
+		bysquare.BysquareHeader{Version: uint8(bysquare.Version120), Reserved: 0x01},
+		"random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\tx\t1\t1\t1\tm\t\t2\t\t\t\textra",
+	)
+
+	// Default decoding tolerates all of it.
+	if _, err := Decode(qr); err != nil {
+		t.Fatalf("expected default decode to succeed, got %v", err)
+	}
+
+	_, err = Decode(qr, DecodeOptions{Strict: true})
+	if !errors.Is(err, bysquare.ErrNonCanonical) {
+		t.Fatalf("expected ErrNonCanonical, got %v", err)
+	}
+
+	var violations interface{ Unwrap() []error }
+	if !errors.As(err, &violations) {
+		t.Fatalf("expected joined violations, got %T", err)
+	}
+
+	expected := []string{
+		"",
+		"payments[0].bankAccounts",
+		"payments[0].standingOrderExt",
+		"payments[0].directDebitExt",
+		"unknown",
+	}
+	got := violations.Unwrap()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %v", len(expected), len(got), err)
+	}
+	for i, name := range expected {
+		if !errors.Is(got[i], bysquare.ErrNonCanonical) {
+			t.Errorf("violation %d: expected ErrNonCanonical, got %v", i, got[i])
+		}
+		var fieldErr *bysquare.FieldError
+		if !errors.As(got[i], &fieldErr) {
+			if name != "" {
+				t.Errorf("violation %d: expected *bysquare.FieldError, got %v", i, got[i])
+			}
+			continue
+		}
+		if fieldErr.Name != name {
+			t.Errorf("violation %d: expected name %q, got %q", i, name, fieldErr.Name)
+		}
+	}
+
+	if _, err := Decode(seal(bysquare.BysquareHeader{BySquareType: 0x01}, "random-id\t0"), DecodeOptions{Strict: true}); !errors.Is(err, bysquare.ErrUnexpectedType) {
+		t.Errorf("expected ErrUnexpectedType, got %v", err)
+	}
+	if _, err := Decode(canonical, DecodeOptions{Strict: true, Lenient: true}); !errors.Is(err, bysquare.ErrLenientStrict) {
+		t.Errorf("expected ErrLenientStrict, got %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add("0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00")
+	f.Add("")
+	f.Add("0000")
+	f.Add("!!!")
+
+	f.Fuzz(func(t *testing.T, qr string) {
+		for _, opts := range []DecodeOptions{{}, {Lenient: true}, {Strict: true}} {
+			Decode(qr, opts)
+		}
+	})
+}
+
+func TestDecodeExactDecimal(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "1234567890123.123456789",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			Beneficiary:  &Beneficiary{Name: "John Doe"},
+		}},
+	}
+
+	encoded, err := Encode(model)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got := decoded.Payments[0].Amount; got != model.Payments[0].Amount {
+		t.Errorf("expected amount %s, got %s", model.Payments[0].Amount, got)
+	}
+}
+
+func TestDecodeVersions(t *testing.T) {
+	model := DataModel{
+		InvoiceID: "random-id",
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "100",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			Beneficiary:  &Beneficiary{Name: "John Doe", City: "Bratislava"},
+		}},
+	}
+
+	tests := []struct {
+		version     bysquare.Version
+		fields      int
+		beneficiary *Beneficiary
+	}{
+		{bysquare.Version100, 16, nil},
+		{bysquare.Version110, 19, &Beneficiary{Name: "John Doe", City: "Bratislava"}},
+		{bysquare.Version120, 19, &Beneficiary{Name: "John Doe", City: "Bratislava"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version.String(), func(t *testing.T) {
+			opts := DefaultEncodeOptions()
+			opts.Version = tt.version
+
+			qr, err := Encode(model, opts)
+			if err != nil {
+				t.Fatalf("Encode() error: %v", err)
+			}
+
+			header, payload, err := bysquare.Open(qr, 0x00)
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+			if header.Version != uint8(tt.version) {
+				t.Errorf("expected header version %d, got %d", tt.version, header.Version)
+			}
+			if got := len(strings.Split(payload, "\t")); got != tt.fields {
+				t.Errorf("expected %d fields, got %d", tt.fields, got)
+			}
+
+			decoded, err := Decode(qr, DecodeOptions{Strict: true})
+			if err != nil {
+				t.Fatalf("Decode() error: %v", err)
+			}
+			got := decoded.Payments[0].Beneficiary
+			if (got == nil) != (tt.beneficiary == nil) || got != nil && *got != *tt.beneficiary {
+				t.Errorf("expected beneficiary %+v, got %+v", tt.beneficiary, got)
+			}
+		})
+	}
+}
+
+func TestDecodeLegacyBeneficiary(t *testing.T) {
+	// Version 1.0.0 header with a beneficiary block, as written by the
+	// TypeScript library.
+	qr := "0004I0006UC5LT8E21H3IC1K9R40P82GJL22NTU0586BBEOEKDMQSVUUBAOP1C0FFE14UJA1F1LJMV0FONE35J05TRC77FTIMV87NKNANNOFJB684000"
+
+	model, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if b := model.Payments[0].Beneficiary; b == nil || b.Name != "John Doe" {
+		t.Errorf("expected beneficiary John Doe, got %+v", b)
+	}
+
+	_, err = Decode(qr, DecodeOptions{Strict: true})
+	var fieldErr *bysquare.FieldError
+	if !errors.Is(err, bysquare.ErrNonCanonical) || !errors.As(err, &fieldErr) || fieldErr.Name != "payments[0].beneficiary" {
+		t.Errorf("expected beneficiary violation, got %v", err)
+	}
+}
+
+func TestDecodeExtra(t *testing.T) {
+	payload := "random-id\t1\t1\t100\tEUR\t\t\t\t\t\t\t1\tSK9611000000002918599669\t\t0\t0\tJohn Doe\t\t\tvendor\t\t42"
+	qr, err := bysquare.Seal(bysquare.BysquareHeader{Version: uint8(bysquare.Version120)}, payload)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	model, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	expected := []string{"vendor", "", "42"}
+	if !slices.Equal(model.Extra, expected) {
+		t.Errorf("expected extra %q, got %q", expected, model.Extra)
+	}
+
+	reencoded, err := Encode(model, EncodeOptions{Version: bysquare.Version120})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if _, got, _ := bysquare.Open(reencoded, 0x00); got != payload {
+		t.Errorf("expected payload %q, got %q", payload, got)
+	}
+
+	if _, err := Decode(qr, DecodeOptions{Strict: true}); !errors.Is(err, bysquare.ErrNonCanonical) {
+		t.Errorf("expected ErrNonCanonical for trailing fields, got %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package pay
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
@@ -11,12 +13,73 @@ import (
 type EncodeOptions struct {
 	// Deburr removes diacritics from text fields.
 	Deburr bool
+	// DeburrFields selects the fields Deburr rewrites. Zero selects
+	// DefaultDeburrFields.
+	DeburrFields Field
+	// Transliterator rewrites text fields when Deburr is set. Nil selects
+	// bysquare.DefaultTransliterator; bysquare.ExtendedTransliterator also
+	// covers Cyrillic, Greek and the remaining Latin blocks.
+	Transliterator bysquare.Transliterator
+	// DomesticCountry, "SK" or "CZ", lets BankAccount.IBAN carry a
+	// domestic account number, "prefix-number/bank code". Such numbers
+	// are converted to IBANs before validation, see
+	// bysquare.ParseDomesticAccount. Empty requires IBANs.
+	DomesticCountry string
+	// FillBIC derives missing BICs from the bank code of the IBAN using
+	// BICTable. A supplied BIC of another bank is kept and reported as a
+	// *ValidationError matching bysquare.ErrBICMismatch in
+	// EncodeResult.Warnings. Encode drops these warnings.
+	FillBIC bool
+	// BICTable maps bank codes to BICs for FillBIC. Nil selects
+	// bysquare.DefaultBICTable.
+	BICTable *bysquare.BICTable
 	// Validate performs validation before encoding.
 	Validate bool
-	// Version specifies the BySquare format version.
+	// Version specifies the BySquare format version. The payload is laid
+	// out for that version: fields it does not define, such as the
+	// beneficiary before 1.1.0, are left out. Convert reports them.
 	Version bysquare.Version
+	// ListedConstantSymbols makes Validate require constant symbols to be
+	// on the list embedded in bysquare, see ValidateOptions.
+	ListedConstantSymbols bool
+	// Repertoire restricts the characters of every text field. Zero
+	// allows any character.
+	Repertoire bysquare.Repertoire
+	// RepertoirePolicy selects what happens to characters outside
+	// Repertoire: the zero value rejects them during validation, the
+	// others rewrite them using Transliterator, or
+	// bysquare.ExtendedTransliterator when it is nil.
+	RepertoirePolicy bysquare.RepertoirePolicy
+	// Compression selects the LZMA encoder. The zero value gives the
+	// shortest QR strings; bysquare.CompressionReference reproduces those
+	// of the TypeScript implementation.
+	Compression bysquare.Compression
+	// Fit bounds the QR version of the output. The zero value does not
+	// limit it.
+	Fit bysquare.FitOptions
 }
 
+// Field selects text fields for diacritics removal. Values can be combined
+// with bitwise OR.
+type Field uint16
+
+const (
+	FieldPaymentNote Field = 1 << iota
+	FieldBeneficiaryName
+	FieldBeneficiaryStreet
+	FieldBeneficiaryCity
+	FieldOriginatorsReferenceInformation
+	// FieldDirectDebit selects the reference, mandate, creditor and
+	// contract identifiers of DirectDebitExt.
+	FieldDirectDebit
+	FieldInvoiceID
+
+	// DefaultDeburrFields are the fields the TypeScript library deburrs.
+	DefaultDeburrFields = FieldPaymentNote | FieldBeneficiaryName | FieldBeneficiaryStreet | FieldBeneficiaryCity
+	// AllFields selects every free-text field.
+	AllFields = FieldInvoiceID<<1 - 1
+)
+
 // DefaultEncodeOptions returns default encoding options.
 func DefaultEncodeOptions() EncodeOptions {
 	return EncodeOptions{
@@ -29,13 +92,20 @@ func DefaultEncodeOptions() EncodeOptions {
 // Encode generates a BySquare QR string from the data model.
 //
 // The encoding process:
-// 1. Optional diacritics removal (deburr)
-// 2. Optional validation
-// 3. Serialization to tab-separated format
-// 4. CRC32 checksum addition
-// 5. LZMA compression
-// 6. Binary header construction
-// 7. Base32Hex encoding
+// 1. Optional diacritics removal (deburr), see EncodeOptions.Transliterator
+// 2. Optional domestic account conversion, see EncodeOptions.DomesticCountry
+// 3. Optional BIC derivation, see EncodeOptions.FillBIC
+// 4. Symbol normalization, see NormalizeSymbols
+// 5. Optional validation
+// 6. Serialization to tab-separated format, laid out for EncodeOptions.Version
+// 7. CRC32 checksum addition
+// 8. LZMA compression
+// 9. Binary header construction
+// 10. Base32Hex encoding
+// 11. Optional QR capacity check, see EncodeOptions.Fit
+//
+// EncodeDetailed runs the same steps and also returns what did not stop
+// encoding.
 //
 // Complete BySquare QR binary structure:
 //
@@ -46,47 +116,167 @@ func DefaultEncodeOptions() EncodeOptions {
 //	| (4 nibbles)      | (little-endian)  |  (compressed CRC+payload)   |
 //	+------------------+------------------+-----------------------------+
 func Encode(model DataModel, opts ...EncodeOptions) (string, error) {
+	result, err := EncodeDetailed(model, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.QR, nil
+}
+
+// EncodeResult is the output of EncodeDetailed.
+type EncodeResult struct {
+	// QR is the encoded string.
+	QR string
+	// Changes lists the text fields rewritten by EncodeOptions.Deburr and
+	// RepertoirePolicy, followed by the symbols written in canonical form,
+	// see NormalizeSymbols.
+	Changes bysquare.Changes
+	// Warnings lists the problems that did not stop encoding, such as
+	// the BIC mismatches found by EncodeOptions.FillBIC.
+	Warnings bysquare.Warnings
+}
+
+// EncodeDetailed encodes the data model like Encode and returns the QR
+// string together with the rewritten fields and the warnings. The error
+// is reserved for failures, in which case no QR string is produced.
+func EncodeDetailed(model DataModel, opts ...EncodeOptions) (*EncodeResult, error) {
 	options := DefaultEncodeOptions()
 	if len(opts) > 0 {
 		options = opts[0]
 	}
 
-	if options.Deburr {
-		removeDiacritics(&model)
+	header, result, err := prepare(&model, options)
+	if err != nil {
+		return nil, err
 	}
 
-	if options.Validate {
-		if err := ValidateDataModel(&model, options.Version); err != nil {
-			return "", err
+	result.QR, err = seal(model, header, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// seal encodes the prepared model and applies options.Fit.
+func seal(model DataModel, header bysquare.BysquareHeader, options EncodeOptions) (string, error) {
+	qr, err := bysquare.Seal(header, serialize(model, options.Version), bysquare.SealOptions{Compression: options.Compression})
+	if err != nil {
+		return "", err
+	}
+
+	err = options.Fit.Check(qr)
+	if err == nil {
+		return qr, nil
+	}
+	if options.Fit.Policy != bysquare.FitTruncate || !errors.Is(err, bysquare.ErrCapacityExceeded) {
+		return "", err
+	}
+
+	// Truncate notes from the last payment backwards, on a copy so the
+	// caller's payments stay untouched.
+	model.Payments = slices.Clone(model.Payments)
+	for i := len(model.Payments) - 1; i >= 0; i-- {
+		payment := &model.Payments[i]
+		if payment.PaymentNote == "" {
+			continue
+		}
+
+		qr, err = options.Fit.Truncate(payment.PaymentNote, func(note string) (string, error) {
+			payment.PaymentNote = note
+			return bysquare.Seal(header, serialize(model, options.Version), bysquare.SealOptions{Compression: options.Compression})
+		})
+		if !errors.Is(err, bysquare.ErrCapacityExceeded) {
+			return qr, err
 		}
+		payment.PaymentNote = ""
 	}
 
-	payloadTabbed := serialize(model)
-	payloadChecked := bysquare.AddChecksum(payloadTabbed)
+	return "", err
+}
 
-	payloadCompressed, err := bysquare.CompressLZMA(payloadChecked)
+// Plan reports the QR length of the encoded model, the minimal QR version
+// for every error correction level and the fields that cost the most
+// characters. Deburr, Validate, Version and Compression apply as in Encode;
+// Fit is ignored, so the plan describes the untruncated output.
+func Plan(model DataModel, opts ...EncodeOptions) (*bysquare.Plan, error) {
+	options := DefaultEncodeOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	header, _, err := prepare(&model, options)
 	if err != nil {
-		return "", fmt.Errorf("LZMA compression failed: %w", err)
+		return nil, err
 	}
 
-	if len(payloadCompressed) < 13 {
-		return "", fmt.Errorf("compressed payload too short")
+	return bysquare.PlanPayload(header, serialize(model, options.Version), bysquare.SealOptions{Compression: options.Compression})
+}
+
+// prepare applies deburring, the repertoire policy, domestic account
+// conversion, BIC derivation, symbol normalization and validation to model
+// and returns the header for its encoding with the rewritten fields and the
+// BIC warnings.
+func prepare(model *DataModel, options EncodeOptions) (bysquare.BysquareHeader, *EncodeResult, error) {
+	result := &EncodeResult{}
+	coerce := options.Repertoire != bysquare.RepertoireAny && options.RepertoirePolicy != bysquare.RepertoireReject
+	detach(model)
+
+	if options.Deburr {
+		result.Changes = Deburr(model, options)
 	}
-	lzmaBody := payloadCompressed[13:]
 
-	header := bysquare.BuildBysquareHeader(0x00, uint8(options.Version), 0x00, 0x00)
-	payloadLength := bysquare.BuildPayloadLength(len(payloadChecked))
+	if coerce {
+		var t bysquare.Transliterator
+		if options.RepertoirePolicy == bysquare.RepertoireTransliterate {
+			t = options.Transliterator
+			if t == nil {
+				t = bysquare.ExtendedTransliterator
+			}
+		}
+		walkText(model, func(_ Field, path string, s *string) {
+			if coerced := options.Repertoire.Coerce(*s, t); coerced != *s {
+				result.Changes = append(result.Changes, bysquare.Change{Path: path, Before: *s, After: coerced})
+				*s = coerced
+			}
+		})
+	}
+
+	if options.DomesticCountry != "" {
+		if err := convertDomestic(model, options.DomesticCountry); err != nil {
+			return bysquare.BysquareHeader{}, nil, err
+		}
+	}
+
+	if options.FillBIC {
+		table := options.BICTable
+		if table == nil {
+			table = bysquare.DefaultBICTable()
+		}
+		result.Warnings = fillBIC(model, table)
+	}
+
+	result.Changes = append(result.Changes, normalizeSymbols(model)...)
 
-	output := make([]byte, 0, len(header)+len(payloadLength)+len(lzmaBody))
-	output = append(output, header...)
-	output = append(output, payloadLength...)
-	output = append(output, lzmaBody...)
+	if options.Validate {
+		if err := validateDataModel(model, options.Version, ValidateOptions{ListedConstantSymbols: options.ListedConstantSymbols}); err != nil {
+			return bysquare.BysquareHeader{}, nil, err
+		}
+		if err := ValidateRepertoire(model, options.Repertoire); err != nil {
+			return bysquare.BysquareHeader{}, nil, err
+		}
+	}
 
-	return bysquare.EncodeBase32Hex(output, false), nil
+	return bysquare.BysquareHeader{
+		BySquareType: 0x00,
+		Version:      uint8(options.Version),
+	}, result, nil
 }
 
-// serialize converts DataModel to tab-separated format.
-func serialize(model DataModel) string {
+// serialize converts DataModel to tab-separated format in the layout of
+// version, followed by model.Extra. The beneficiary block was added in
+// 1.1.0.
+func serialize(model DataModel, version bysquare.Version) string {
 	parts := make([]string, 0, 100)
 
 	parts = append(parts, bysquare.Sanitize(model.InvoiceID))
@@ -94,7 +284,7 @@ func serialize(model DataModel) string {
 
 	for _, payment := range model.Payments {
 		parts = append(parts, fmt.Sprintf("%d", payment.Type))
-		parts = append(parts, bysquare.FormatFloat(payment.Amount))
+		parts = append(parts, bysquare.FormatDecimal(payment.Amount))
 		parts = append(parts, bysquare.Sanitize(string(payment.CurrencyCode)))
 		parts = append(parts, bysquare.Sanitize(payment.PaymentDueDate))
 		parts = append(parts, bysquare.Sanitize(payment.VariableSymbol))
@@ -129,43 +319,166 @@ func serialize(model DataModel) string {
 			parts = append(parts, bysquare.Sanitize(payment.DirectDebitExt.MandateID))
 			parts = append(parts, bysquare.Sanitize(payment.DirectDebitExt.CreditorID))
 			parts = append(parts, bysquare.Sanitize(payment.DirectDebitExt.ContractID))
-			parts = append(parts, bysquare.FormatFloat(payment.DirectDebitExt.MaxAmount))
+			parts = append(parts, bysquare.FormatDecimal(payment.DirectDebitExt.MaxAmount))
 			parts = append(parts, bysquare.Sanitize(payment.DirectDebitExt.ValidTillDate))
 		} else {
 			parts = append(parts, "0")
 		}
 	}
 
-	for _, payment := range model.Payments {
-		if payment.Beneficiary != nil {
-			parts = append(parts, bysquare.Sanitize(payment.Beneficiary.Name))
-			parts = append(parts, bysquare.Sanitize(payment.Beneficiary.Street))
-			parts = append(parts, bysquare.Sanitize(payment.Beneficiary.City))
-		} else {
-			parts = append(parts, "", "", "")
+	if version >= bysquare.Version110 {
+		for _, payment := range model.Payments {
+			if payment.Beneficiary != nil {
+				parts = append(parts, bysquare.Sanitize(payment.Beneficiary.Name))
+				parts = append(parts, bysquare.Sanitize(payment.Beneficiary.Street))
+				parts = append(parts, bysquare.Sanitize(payment.Beneficiary.City))
+			} else {
+				parts = append(parts, "", "", "")
+			}
 		}
 	}
 
+	for _, v := range model.Extra {
+		parts = append(parts, bysquare.Sanitize(v))
+	}
+
 	return strings.Join(parts, "\t")
 }
 
-// removeDiacritics replaces diacritical marks in user-facing text fields.
-func removeDiacritics(model *DataModel) {
+// Deburr rewrites the fields selected by options.DeburrFields with
+// options.Transliterator in place and returns the fields it changed, so
+// applications can show users what was altered. options.Deburr is ignored.
+// Encode applies the same rewrite to a copy of its model; EncodeDetailed
+// reports the changes in EncodeResult.Changes.
+func Deburr(model *DataModel, opts ...EncodeOptions) bysquare.Changes {
+	options := DefaultEncodeOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	fields := options.DeburrFields
+	if fields == 0 {
+		fields = DefaultDeburrFields
+	}
+	t := options.Transliterator
+	if t == nil {
+		t = bysquare.DefaultTransliterator
+	}
+
+	var changes bysquare.Changes
+	walkText(model, func(field Field, path string, s *string) {
+		if fields&field != 0 {
+			changes.Apply(t, path, s)
+		}
+	})
+
+	return changes
+}
+
+// NormalizeSymbols rewrites the payment symbols of model in place to the
+// canonical form of bysquare.NormalizeSymbol and returns the symbols it
+// changed. It stops at the first malformed symbol and returns it as a
+// *ValidationError. Constant symbols are not looked up.
+func NormalizeSymbols(model *DataModel) (bysquare.Changes, error) {
+	var changes bysquare.Changes
 	for i := range model.Payments {
-		payment := &model.Payments[i]
-		if payment.PaymentNote != "" {
-			payment.PaymentNote = bysquare.Deburr(payment.PaymentNote)
+		for _, f := range symbolFields(&model.Payments[i], fmt.Sprintf("payments[%d]", i)) {
+			normalized, err := bysquare.NormalizeSymbol(f.kind, *f.value)
+			if err != nil {
+				return changes, &ValidationError{Message: err.Error(), Path: f.path, Err: err}
+			}
+			if normalized != *f.value {
+				changes = append(changes, bysquare.Change{Path: f.path, Before: *f.value, After: normalized})
+				*f.value = normalized
+			}
 		}
-		if payment.Beneficiary != nil {
-			if payment.Beneficiary.Name != "" {
-				payment.Beneficiary.Name = bysquare.Deburr(payment.Beneficiary.Name)
+	}
+	return changes, nil
+}
+
+// normalizeSymbols rewrites the symbols of model that NormalizeSymbols
+// accepts and returns those it changed. Malformed symbols are left for
+// validation to report.
+func normalizeSymbols(model *DataModel) bysquare.Changes {
+	var changes bysquare.Changes
+	for i := range model.Payments {
+		for _, f := range symbolFields(&model.Payments[i], fmt.Sprintf("payments[%d]", i)) {
+			normalized, err := bysquare.NormalizeSymbol(f.kind, *f.value)
+			if err == nil && normalized != *f.value {
+				changes = append(changes, bysquare.Change{Path: f.path, Before: *f.value, After: normalized})
+				*f.value = normalized
 			}
-			if payment.Beneficiary.Street != "" {
-				payment.Beneficiary.Street = bysquare.Deburr(payment.Beneficiary.Street)
+		}
+	}
+	return changes
+}
+
+// convertDomestic replaces the domestic account numbers of model, those
+// containing "/", with IBANs.
+func convertDomestic(model *DataModel, country string) error {
+	for i := range model.Payments {
+		for j := range model.Payments[i].BankAccounts {
+			account := &model.Payments[i].BankAccounts[j]
+			if !strings.Contains(account.IBAN, "/") {
+				continue
 			}
-			if payment.Beneficiary.City != "" {
-				payment.Beneficiary.City = bysquare.Deburr(payment.Beneficiary.City)
+
+			domestic, err := bysquare.ParseDomesticAccount(country, account.IBAN)
+			if err != nil {
+				return &ValidationError{
+					Message: err.Error(),
+					Path:    fmt.Sprintf("payments[%d].bankAccounts[%d].iban", i, j),
+					Err:     err,
+				}
 			}
+			account.IBAN = domestic.IBAN()
+		}
+	}
+	return nil
+}
+
+// fillBIC sets missing BICs of model from the bank code of their IBAN and
+// returns a warning for every BIC of another bank.
+func fillBIC(model *DataModel, table *bysquare.BICTable) bysquare.Warnings {
+	var warnings bysquare.Warnings
+	for i := range model.Payments {
+		for j := range model.Payments[i].BankAccounts {
+			account := &model.Payments[i].BankAccounts[j]
+			bank, ok := table.LookupIBAN(account.IBAN)
+			switch {
+			case !ok:
+			case account.BIC == "":
+				account.BIC = bank.BIC
+			case !bank.MatchesBIC(account.BIC):
+				warnings = append(warnings, &ValidationError{
+					Message: fmt.Sprintf("BIC %s does not match bank %s (%s) of the IBAN", account.BIC, bank.Code, bank.BIC),
+					Path:    fmt.Sprintf("payments[%d].bankAccounts[%d].bic", i, j),
+					Err:     bysquare.ErrBICMismatch,
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// detach copies the payments of model and the structs they point to, so
+// rewriting text leaves the caller's data untouched.
+func detach(model *DataModel) {
+	model.Payments = slices.Clone(model.Payments)
+	for i := range model.Payments {
+		payment := &model.Payments[i]
+		payment.BankAccounts = slices.Clone(payment.BankAccounts)
+		if payment.StandingOrderExt != nil {
+			so := *payment.StandingOrderExt
+			payment.StandingOrderExt = &so
+		}
+		if payment.Beneficiary != nil {
+			beneficiary := *payment.Beneficiary
+			payment.Beneficiary = &beneficiary
+		}
+		if payment.DirectDebitExt != nil {
+			dd := *payment.DirectDebitExt
+			payment.DirectDebitExt = &dd
 		}
 	}
 }
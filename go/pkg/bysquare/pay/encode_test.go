@@ -1,10 +1,14 @@
 package pay
 
 import (
+	"errors"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
+	"github.com/xseman/bysquare/go/pkg/bysquare/qr"
 )
 
 func TestSerialize(t *testing.T) {
@@ -19,7 +23,7 @@ func TestSerialize(t *testing.T) {
 				InvoiceID: "inv-1",
 				Payments: []SimplePayment{{
 					Type:           PaymentTypePaymentOrder,
-					Amount:         100.50,
+					Amount:         "100.50",
 					CurrencyCode:   CurrencyEUR,
 					VariableSymbol: "123",
 					BankAccounts: []BankAccount{
@@ -35,7 +39,7 @@ func TestSerialize(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypeStandingOrder,
-					Amount:       50,
+					Amount:       "50",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -56,7 +60,7 @@ func TestSerialize(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypeDirectDebit,
-					Amount:       200,
+					Amount:       "200",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -65,7 +69,7 @@ func TestSerialize(t *testing.T) {
 						DirectDebitScheme: 1,
 						DirectDebitType:   0,
 						MandateID:         "MANDATE-1",
-						MaxAmount:         500,
+						MaxAmount:         "500",
 					},
 					Beneficiary: &Beneficiary{Name: "Test"},
 				}},
@@ -77,7 +81,7 @@ func TestSerialize(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       10,
+					Amount:       "10",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -92,7 +96,7 @@ func TestSerialize(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := serialize(tt.model)
+			result := serialize(tt.model, bysquare.Version120)
 
 			for _, s := range tt.contains {
 				if !strings.Contains(result, s) {
@@ -115,7 +119,7 @@ func TestEncode(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -130,7 +134,7 @@ func TestEncode(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -146,7 +150,7 @@ func TestEncode(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -182,7 +186,7 @@ func TestEncodeDeburr(t *testing.T) {
 		return DataModel{
 			Payments: []SimplePayment{{
 				Type:         PaymentTypePaymentOrder,
-				Amount:       100,
+				Amount:       "100",
 				CurrencyCode: CurrencyEUR,
 				PaymentNote:  "Platba za služby",
 				BankAccounts: []BankAccount{
@@ -208,11 +212,440 @@ func TestEncodeDeburr(t *testing.T) {
 	}
 }
 
+func TestEncodeTransliterator(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "100",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  "Оплата за послуги",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "Юлія Шевченко", City: "Київ"},
+		}},
+	}
+
+	options := DefaultEncodeOptions()
+	options.Transliterator = bysquare.ExtendedTransliterator
+
+	qr, err := Encode(model, options)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	payment := decoded.Payments[0]
+	if expected := "Oplata za poslugi"; payment.PaymentNote != expected {
+		t.Errorf("expected note %q, got %q", expected, payment.PaymentNote)
+	}
+	if expected := "Yuliya Shevchenko"; payment.Beneficiary.Name != expected {
+		t.Errorf("expected name %q, got %q", expected, payment.Beneficiary.Name)
+	}
+	if expected := "Kiyiv"; payment.Beneficiary.City != expected {
+		t.Errorf("expected city %q, got %q", expected, payment.Beneficiary.City)
+	}
+}
+
+func TestDeburrFields(t *testing.T) {
+	makeModel := func() DataModel {
+		return DataModel{
+			InvoiceID: "FA-č1",
+			Payments: []SimplePayment{{
+				Type:         PaymentTypeDirectDebit,
+				Amount:       "10",
+				CurrencyCode: CurrencyEUR,
+				PaymentNote:  "Služby",
+				BankAccounts: []BankAccount{
+					{IBAN: "SK9611000000002918599669"},
+				},
+				Beneficiary:    &Beneficiary{Name: "Ján", City: "Bratislava"},
+				DirectDebitExt: &DirectDebit{MandateID: "MANDÁT-1"},
+			}},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		fields   Field
+		expected []string
+	}{
+		{
+			name:     "default fields",
+			fields:   0,
+			expected: []string{"payments[0].paymentNote", "payments[0].beneficiary.name"},
+		},
+		{
+			name:     "note only",
+			fields:   FieldPaymentNote,
+			expected: []string{"payments[0].paymentNote"},
+		},
+		{
+			name:   "all fields",
+			fields: AllFields,
+			expected: []string{
+				"invoiceId",
+				"payments[0].paymentNote",
+				"payments[0].directDebitExt.mandateId",
+				"payments[0].beneficiary.name",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := makeModel()
+			changes := Deburr(&model, EncodeOptions{DeburrFields: tc.fields})
+
+			var paths []string
+			for _, c := range changes {
+				paths = append(paths, c.Path)
+			}
+			if !slices.Equal(paths, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+
+	model := makeModel()
+	changes := Deburr(&model, EncodeOptions{DeburrFields: FieldBeneficiaryName})
+	if expected := (bysquare.Change{Path: "payments[0].beneficiary.name", Before: "Ján", After: "Jan"}); len(changes) != 1 || changes[0] != expected {
+		t.Errorf("expected [%+v], got %+v", expected, changes)
+	}
+	if model.Payments[0].Beneficiary.Name != "Jan" {
+		t.Errorf("expected name rewritten in place, got %q", model.Payments[0].Beneficiary.Name)
+	}
+}
+
+func TestNormalizeSymbols(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:           PaymentTypeDirectDebit,
+			VariableSymbol: "0000123",
+			ConstantSymbol: "308",
+			SpecificSymbol: "42",
+			DirectDebitExt: &DirectDebit{SpecificSymbol: "0042"},
+		}},
+	}
+
+	changes, err := NormalizeSymbols(&model)
+	if err != nil {
+		t.Fatalf("NormalizeSymbols failed: %v", err)
+	}
+
+	expected := bysquare.Changes{
+		{Path: "payments[0].variableSymbol", Before: "0000123", After: "123"},
+		{Path: "payments[0].constantSymbol", Before: "308", After: "0308"},
+		{Path: "payments[0].directDebitExt.specificSymbol", Before: "0042", After: "42"},
+	}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+	if model.Payments[0].ConstantSymbol != "0308" {
+		t.Errorf("expected the model to be rewritten, got %q", model.Payments[0].ConstantSymbol)
+	}
+
+	model.Payments[0].ConstantSymbol = "12345"
+	var validationErr *ValidationError
+	if _, err := NormalizeSymbols(&model); !errors.As(err, &validationErr) || validationErr.Path != "payments[0].constantSymbol" {
+		t.Errorf("expected ValidationError for the constant symbol, got %v", err)
+	}
+}
+
+func TestEncodeNormalizesSymbols(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:           PaymentTypePaymentOrder,
+			Amount:         "10",
+			CurrencyCode:   CurrencyEUR,
+			VariableSymbol: " 000000000000123 ",
+			ConstantSymbol: "8",
+			SpecificSymbol: "00000000001234567890",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "Test"},
+		}},
+	}
+
+	result, err := EncodeDetailed(model)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	expected := bysquare.Changes{
+		{Path: "payments[0].variableSymbol", Before: " 000000000000123 ", After: "123"},
+		{Path: "payments[0].constantSymbol", Before: "8", After: "0008"},
+		{Path: "payments[0].specificSymbol", Before: "00000000001234567890", After: "1234567890"},
+	}
+	if !slices.Equal(result.Changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result.Changes)
+	}
+
+	_, payload, err := bysquare.Open(result.QR, 0x00)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if !strings.Contains(payload, "\t123\t0008\t1234567890\t") {
+		t.Errorf("expected canonical symbols in the payload, got %q", payload)
+	}
+	if model.Payments[0].VariableSymbol != " 000000000000123 " {
+		t.Errorf("expected caller's model untouched, got %q", model.Payments[0].VariableSymbol)
+	}
+}
+
+func TestEncodeDeburrKeepsModel(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  "Služby",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "Ján"},
+		}},
+	}
+
+	if _, err := Encode(model); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if model.Payments[0].PaymentNote != "Služby" || model.Payments[0].Beneficiary.Name != "Ján" {
+		t.Errorf("expected caller's model untouched, got note %q and name %q",
+			model.Payments[0].PaymentNote, model.Payments[0].Beneficiary.Name)
+	}
+}
+
+func TestEncodeRepertoire(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  "Ďakujem 😀",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "ЖУК"},
+		}},
+	}
+
+	testCases := []struct {
+		name        string
+		policy      bysquare.RepertoirePolicy
+		note        string
+		beneficiary string
+	}{
+		{name: "replace", policy: bysquare.RepertoireReplace, note: "Ďakujem ?", beneficiary: "???"},
+		{name: "transliterate", policy: bysquare.RepertoireTransliterate, note: "Ďakujem ?", beneficiary: "ZHUK"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := DefaultEncodeOptions()
+			options.Deburr = false
+			options.Repertoire = bysquare.RepertoireLatin2
+			options.RepertoirePolicy = tc.policy
+
+			qr, err := Encode(model, options)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			decoded, err := Decode(qr)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			payment := decoded.Payments[0]
+			if payment.PaymentNote != tc.note {
+				t.Errorf("expected note %q, got %q", tc.note, payment.PaymentNote)
+			}
+			if payment.Beneficiary.Name != tc.beneficiary {
+				t.Errorf("expected name %q, got %q", tc.beneficiary, payment.Beneficiary.Name)
+			}
+		})
+	}
+
+	options := DefaultEncodeOptions()
+	options.Repertoire = bysquare.RepertoireASCII
+	var repErr *bysquare.RepertoireError
+	if _, err := Encode(model, options); !errors.As(err, &repErr) {
+		t.Errorf("expected RepertoireError, got %v", err)
+	}
+
+	if model.Payments[0].Beneficiary.Name != "ЖУК" {
+		t.Error("expected the caller's model to stay untouched")
+	}
+}
+
+func TestEncodeDomesticAccount(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{
+				{IBAN: "19-8742637541/1200"},
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "John Doe"},
+		}},
+	}
+
+	if _, err := Encode(model); err == nil {
+		t.Fatal("expected a domestic number to fail without DomesticCountry")
+	}
+
+	options := DefaultEncodeOptions()
+	options.DomesticCountry = "SK"
+	qr, err := Encode(model, options)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if model.Payments[0].BankAccounts[0].IBAN != "19-8742637541/1200" {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	decoded, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got := decoded.Payments[0].BankAccounts[0].IBAN; got != "SK3112000000198742637541" {
+		t.Errorf("expected converted IBAN, got %s", got)
+	}
+
+	model.Payments[0].BankAccounts[0].IBAN = "19-8742637542/1200"
+	var validationErr *ValidationError
+	_, err = Encode(model, options)
+	if !errors.As(err, &validationErr) || validationErr.Path != "payments[0].bankAccounts[0].iban" {
+		t.Fatalf("expected ValidationError for the account, got %v", err)
+	}
+	if !errors.Is(err, bysquare.ErrDomesticAccount) {
+		t.Errorf("expected ErrDomesticAccount, got %v", err)
+	}
+}
+
+func TestEncodeDetailedChanges(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  "Ďakujem 😀",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "ЖУК"},
+		}},
+	}
+
+	options := DefaultEncodeOptions()
+	options.Repertoire = bysquare.RepertoireASCII
+	options.RepertoirePolicy = bysquare.RepertoireReplace
+
+	result, err := EncodeDetailed(model, options)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	expected := bysquare.Changes{
+		{Path: "payments[0].paymentNote", Before: "Ďakujem 😀", After: "Dakujem 😀"},
+		{Path: "payments[0].paymentNote", Before: "Dakujem 😀", After: "Dakujem ?"},
+		{Path: "payments[0].beneficiary.name", Before: "ЖУК", After: "???"},
+	}
+	if !slices.Equal(result.Changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result.Changes)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", result.Warnings)
+	}
+
+	qr, err := Encode(model, options)
+	if err != nil || qr != result.QR {
+		t.Errorf("expected Encode to return %q, got %q, %v", result.QR, qr, err)
+	}
+}
+
+func TestEncodeFillBIC(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+				{IBAN: "CZ6508000000192000145399", BIC: "GIBACZPXXXX"},
+				{IBAN: "CZ6508000000192000145399", BIC: "KOMBCZPP"},
+				{IBAN: "DE89370400440532013000"},
+			},
+			Beneficiary: &Beneficiary{Name: "John Doe"},
+		}},
+	}
+
+	options := DefaultEncodeOptions()
+	options.FillBIC = true
+	result, err := EncodeDetailed(model, options)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	warnings := result.Warnings
+	if len(warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", warnings)
+	}
+	var validationErr *ValidationError
+	if !errors.As(warnings[0], &validationErr) || validationErr.Path != "payments[0].bankAccounts[2].bic" {
+		t.Errorf("expected warning for the third account, got %v", warnings[0])
+	}
+	if !errors.Is(warnings[0], bysquare.ErrBICMismatch) {
+		t.Errorf("expected ErrBICMismatch, got %v", warnings[0])
+	}
+	if model.Payments[0].BankAccounts[0].BIC != "" {
+		t.Error("expected the caller's model to stay untouched")
+	}
+
+	if qr, err := Encode(model, options); err != nil || qr != result.QR {
+		t.Errorf("expected Encode to return %q without error, got %q, %v", result.QR, qr, err)
+	}
+
+	decoded, err := Decode(result.QR)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	var bics []string
+	for _, account := range decoded.Payments[0].BankAccounts {
+		bics = append(bics, account.BIC)
+	}
+	if expected := []string{"TATRSKBX", "GIBACZPXXXX", "KOMBCZPP", ""}; !slices.Equal(bics, expected) {
+		t.Errorf("expected BICs %q, got %q", expected, bics)
+	}
+
+	table, err := bysquare.ParseBICTable(strings.NewReader("DE\t37040044\tCOBADEFFXXX\n"))
+	if err != nil {
+		t.Fatalf("ParseBICTable failed: %v", err)
+	}
+	model.Payments[0].BankAccounts = model.Payments[0].BankAccounts[3:]
+	options.BICTable = table
+	qr, err := Encode(model, options)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if decoded, _ := Decode(qr); decoded.Payments[0].BankAccounts[0].BIC != "COBADEFFXXX" {
+		t.Errorf("expected BIC from the custom table, got %+v", decoded.Payments[0].BankAccounts)
+	}
+}
+
 func TestEncodeValidationError(t *testing.T) {
 	model := DataModel{
 		Payments: []SimplePayment{{
 			Type:         PaymentTypePaymentOrder,
-			Amount:       100,
+			Amount:       "100",
 			CurrencyCode: CurrencyEUR,
 			BankAccounts: []BankAccount{
 				{IBAN: "INVALID"},
@@ -227,6 +660,39 @@ func TestEncodeValidationError(t *testing.T) {
 	}
 }
 
+func TestEncodeInvalidAmountKeepsLayout(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypeDirectDebit,
+			Amount:       "1\t2",
+			CurrencyCode: CurrencyEUR,
+			BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			DirectDebitExt: &DirectDebit{
+				MaxAmount: "3\t4",
+				MandateID: "M-1",
+			},
+			Beneficiary: &Beneficiary{Name: "John Doe"},
+		}},
+	}
+
+	qr, err := Encode(model, EncodeOptions{Validate: false, Version: bysquare.Version120})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decoded, err := Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	payment := decoded.Payments[0]
+	if payment.CurrencyCode != CurrencyEUR {
+		t.Errorf("expected currency EUR, got %q", payment.CurrencyCode)
+	}
+	if payment.DirectDebitExt == nil || payment.DirectDebitExt.MandateID != "M-1" {
+		t.Errorf("expected mandate M-1, got %+v", payment.DirectDebitExt)
+	}
+}
+
 func TestEncodeNoPayments(t *testing.T) {
 	model := DataModel{
 		Payments: []SimplePayment{},
@@ -237,3 +703,152 @@ func TestEncodeNoPayments(t *testing.T) {
 		t.Error("expected error for empty payments")
 	}
 }
+
+func TestEncodePayloadTooLarge(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "100",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  strings.Repeat("a", 70_000),
+			BankAccounts: []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			Beneficiary:  &Beneficiary{Name: "John Doe"},
+		}},
+	}
+
+	_, err := Encode(model, EncodeOptions{Version: bysquare.Version120})
+	if !errors.Is(err, bysquare.ErrPayloadTooLarge) {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
+
+// planNote returns a note of n characters that compresses poorly.
+func planNote(n int) string {
+	var sb strings.Builder
+	for i := 1; sb.Len() < n; i++ {
+		sb.WriteString(strconv.Itoa(i * 7919 % 10007))
+		sb.WriteByte(' ')
+	}
+	return sb.String()[:n]
+}
+
+func planModel(note string) DataModel {
+	return DataModel{
+		Payments: []SimplePayment{{
+			Type:           PaymentTypePaymentOrder,
+			Amount:         "100",
+			CurrencyCode:   CurrencyEUR,
+			VariableSymbol: "123",
+			PaymentNote:    note,
+			BankAccounts:   []BankAccount{{IBAN: "SK9611000000002918599669"}},
+			Beneficiary:    &Beneficiary{Name: "John Doe"},
+		}},
+	}
+}
+
+func TestPlan(t *testing.T) {
+	model := planModel(planNote(300))
+
+	plan, err := Plan(model)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	encoded, err := Encode(model)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if plan.Length != len(encoded) {
+		t.Errorf("expected length %d, got %d", len(encoded), plan.Length)
+	}
+
+	for level := qr.LevelL; level <= qr.LevelH; level++ {
+		if expected := qr.MinVersionFor(len(encoded), level); plan.Version(level) != expected {
+			t.Errorf("level %s: expected version %d, got %d", level, expected, plan.Version(level))
+		}
+	}
+	if plan.Version(qr.LevelL) >= plan.Version(qr.LevelH) {
+		t.Errorf("expected level H to need a larger version than L, got %v", plan.Versions)
+	}
+
+	if len(plan.Fields) == 0 || plan.Fields[0].Name != "payments[0].paymentNote" {
+		t.Fatalf("expected payment note to cost the most, got %+v", plan.Fields)
+	}
+	if plan.Fields[0].Length != 300 {
+		t.Errorf("expected note length 300, got %d", plan.Fields[0].Length)
+	}
+	for _, f := range plan.Fields {
+		if f.Cost > plan.Fields[0].Cost {
+			t.Errorf("fields not sorted by cost: %+v", plan.Fields)
+		}
+	}
+}
+
+func TestEncodeFit(t *testing.T) {
+	note := planNote(300)
+	model := planModel(note)
+
+	plan, err := Plan(model)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	version := plan.Version(qr.LevelM)
+
+	t.Run("fits", func(t *testing.T) {
+		options := DefaultEncodeOptions()
+		options.Fit = bysquare.FitOptions{MaxVersion: version, Level: qr.LevelM}
+		if _, err := Encode(model, options); err != nil {
+			t.Errorf("expected fit at version %d, got %v", version, err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		options := DefaultEncodeOptions()
+		options.Fit = bysquare.FitOptions{MaxVersion: version - 1, Level: qr.LevelM}
+		_, err := Encode(model, options)
+		if !errors.Is(err, bysquare.ErrCapacityExceeded) {
+			t.Errorf("expected ErrCapacityExceeded, got %v", err)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		options := DefaultEncodeOptions()
+		options.Fit = bysquare.FitOptions{
+			MaxVersion: version - 1,
+			Level:      qr.LevelM,
+			Policy:     bysquare.FitTruncate,
+		}
+		encoded, err := Encode(model, options)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		if len(encoded) > qr.Capacity(version-1, qr.LevelM) {
+			t.Errorf("expected at most %d characters, got %d", qr.Capacity(version-1, qr.LevelM), len(encoded))
+		}
+
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		got := decoded.Payments[0].PaymentNote
+		if got == "" || len(got) >= len(note) || !strings.HasPrefix(note, got) {
+			t.Errorf("expected a shorter prefix of the note, got %q", got)
+		}
+		if model.Payments[0].PaymentNote != note {
+			t.Error("expected the caller's model to stay untouched")
+		}
+	})
+
+	t.Run("truncate too small", func(t *testing.T) {
+		options := DefaultEncodeOptions()
+		options.Fit = bysquare.FitOptions{
+			MaxVersion: 1,
+			Level:      qr.LevelH,
+			Policy:     bysquare.FitTruncate,
+		}
+		_, err := Encode(model, options)
+		if !errors.Is(err, bysquare.ErrCapacityExceeded) {
+			t.Errorf("expected ErrCapacityExceeded, got %v", err)
+		}
+	})
+}
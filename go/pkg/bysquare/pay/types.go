@@ -4,6 +4,10 @@
 // to the Slovak Banking Association specification.
 package pay
 
+import (
+	"github.com/xseman/bysquare/go/pkg/bysquare"
+)
+
 // PaymentType represents the type of payment.
 type PaymentType uint8
 
@@ -95,19 +99,19 @@ type Beneficiary struct {
 
 // SimplePayment represents base payment fields.
 type SimplePayment struct {
-	Type                            PaymentType    `json:"type" validate:"required,min=1,max=4"`
-	Amount                          float64        `json:"amount,omitempty"`
-	CurrencyCode                    CurrencyCode   `json:"currencyCode,omitempty" validate:"omitempty,iso4217"`
-	PaymentDueDate                  string         `json:"paymentDueDate,omitempty" validate:"omitempty,len=8,numeric"`
-	VariableSymbol                  string         `json:"variableSymbol,omitempty"`
-	ConstantSymbol                  string         `json:"constantSymbol,omitempty"`
-	SpecificSymbol                  string         `json:"specificSymbol,omitempty"`
-	OriginatorsReferenceInformation string         `json:"originatorsReferenceInformation,omitempty"`
-	PaymentNote                     string         `json:"paymentNote,omitempty"`
-	BankAccounts                    []BankAccount  `json:"bankAccounts" validate:"required,min=1,dive"`
-	Beneficiary                     *Beneficiary   `json:"beneficiary" validate:"required"`
-	StandingOrderExt                *StandingOrder `json:"standingOrderExt,omitempty"`
-	DirectDebitExt                  *DirectDebit   `json:"directDebitExt,omitempty"`
+	Type                            PaymentType      `json:"type" validate:"required,min=1,max=4"`
+	Amount                          bysquare.Decimal `json:"amount,omitempty"`
+	CurrencyCode                    CurrencyCode     `json:"currencyCode,omitempty" validate:"omitempty,iso4217"`
+	PaymentDueDate                  string           `json:"paymentDueDate,omitempty" validate:"omitempty,len=8,numeric"`
+	VariableSymbol                  string           `json:"variableSymbol,omitempty"`
+	ConstantSymbol                  string           `json:"constantSymbol,omitempty"`
+	SpecificSymbol                  string           `json:"specificSymbol,omitempty"`
+	OriginatorsReferenceInformation string           `json:"originatorsReferenceInformation,omitempty"`
+	PaymentNote                     string           `json:"paymentNote,omitempty"`
+	BankAccounts                    []BankAccount    `json:"bankAccounts" validate:"required,min=1,dive"`
+	Beneficiary                     *Beneficiary     `json:"beneficiary" validate:"required"`
+	StandingOrderExt                *StandingOrder   `json:"standingOrderExt,omitempty"`
+	DirectDebitExt                  *DirectDebit     `json:"directDebitExt,omitempty"`
 }
 
 // StandingOrder represents standing order extension fields.
@@ -120,20 +124,29 @@ type StandingOrder struct {
 
 // DirectDebit represents direct debit extension fields.
 type DirectDebit struct {
-	DirectDebitScheme        uint8   `json:"directDebitScheme,omitempty"`
-	DirectDebitType          uint8   `json:"directDebitType,omitempty"`
-	VariableSymbol           string  `json:"variableSymbol,omitempty"`
-	SpecificSymbol           string  `json:"specificSymbol,omitempty"`
-	OriginatorsReferenceInfo string  `json:"originatorsReferenceInformation,omitempty"`
-	MandateID                string  `json:"mandateId,omitempty"`
-	CreditorID               string  `json:"creditorId,omitempty"`
-	ContractID               string  `json:"contractId,omitempty"`
-	MaxAmount                float64 `json:"maxAmount,omitempty"`
-	ValidTillDate            string  `json:"validTillDate,omitempty"`
+	DirectDebitScheme        uint8            `json:"directDebitScheme,omitempty"`
+	DirectDebitType          uint8            `json:"directDebitType,omitempty"`
+	VariableSymbol           string           `json:"variableSymbol,omitempty"`
+	SpecificSymbol           string           `json:"specificSymbol,omitempty"`
+	OriginatorsReferenceInfo string           `json:"originatorsReferenceInformation,omitempty"`
+	MandateID                string           `json:"mandateId,omitempty"`
+	CreditorID               string           `json:"creditorId,omitempty"`
+	ContractID               string           `json:"contractId,omitempty"`
+	MaxAmount                bysquare.Decimal `json:"maxAmount,omitempty"`
+	ValidTillDate            string           `json:"validTillDate,omitempty"`
 }
 
 // DataModel represents the complete payment data structure.
 type DataModel struct {
 	InvoiceID string          `json:"invoiceId,omitempty"`
 	Payments  []SimplePayment `json:"payments" validate:"required,min=1,dive"`
+	// Extra holds the fields after the end of the known layout, added by
+	// a newer specification revision or a vendor. Decode fills it and
+	// Encode writes it back unchanged, so re-encoding loses nothing.
+	Extra []string `json:"extra,omitempty"`
+}
+
+// BysquareType returns 0x00, the bysquareType of PAY by square.
+func (m *DataModel) BysquareType() uint8 {
+	return 0x00
 }
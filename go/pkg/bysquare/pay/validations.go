@@ -2,6 +2,7 @@ package pay
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
 )
@@ -10,19 +11,38 @@ import (
 type ValidationError struct {
 	Message string
 	Path    string
+	// Err is the underlying cause when there is one, such as a
+	// *bysquare.IBANError.
+	Err error
 }
 
 func (e *ValidationError) Error() string {
 	return fmt.Sprintf("%s (path: %s)", e.Message, e.Path)
 }
 
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateOptions configures ValidateSimplePayment.
+type ValidateOptions struct {
+	// ListedConstantSymbols requires constant symbols to be on the short
+	// list of common symbols embedded in bysquare, see
+	// bysquare.ValidateConstantSymbol. It is off by default, as banks
+	// accept many more.
+	ListedConstantSymbols bool
+}
+
 // ValidateDataModel validates the complete data model.
 func ValidateDataModel(model *DataModel, version ...bysquare.Version) error {
 	v := bysquare.Version120
 	if len(version) > 0 {
 		v = version[0]
 	}
+	return validateDataModel(model, v, ValidateOptions{})
+}
 
+func validateDataModel(model *DataModel, v bysquare.Version, options ValidateOptions) error {
 	if len(model.Payments) == 0 {
 		return &ValidationError{
 			Message: "at least one payment required",
@@ -32,7 +52,7 @@ func ValidateDataModel(model *DataModel, version ...bysquare.Version) error {
 
 	for i, payment := range model.Payments {
 		path := fmt.Sprintf("payments[%d]", i)
-		if err := ValidateSimplePayment(&payment, path, v); err != nil {
+		if err := ValidateSimplePayment(&payment, path, v, options); err != nil {
 			return err
 		}
 	}
@@ -40,8 +60,85 @@ func ValidateDataModel(model *DataModel, version ...bysquare.Version) error {
 	return nil
 }
 
-// ValidateSimplePayment validates a single payment.
-func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare.Version) error {
+// ValidateRepertoire checks every text field of model against repertoire
+// and reports all characters outside it in one *bysquare.RepertoireError.
+// bysquare.RepertoireAny accepts any model.
+func ValidateRepertoire(model *DataModel, repertoire bysquare.Repertoire) error {
+	if repertoire == bysquare.RepertoireAny {
+		return nil
+	}
+
+	var invalid []bysquare.InvalidRune
+	walkText(model, func(_ Field, path string, s *string) {
+		invalid = append(invalid, repertoire.Check(path, *s)...)
+	})
+	if len(invalid) > 0 {
+		return &bysquare.RepertoireError{Repertoire: repertoire, Runes: invalid}
+	}
+	return nil
+}
+
+// walkText calls fn for every text field of model in payload order, with
+// the Field selecting it for deburring or zero.
+func walkText(model *DataModel, fn func(field Field, path string, s *string)) {
+	fn(FieldInvoiceID, "invoiceId", &model.InvoiceID)
+
+	for i := range model.Payments {
+		payment := &model.Payments[i]
+		p := fmt.Sprintf("payments[%d]", i)
+
+		fn(0, p+".paymentDueDate", &payment.PaymentDueDate)
+		fn(0, p+".variableSymbol", &payment.VariableSymbol)
+		fn(0, p+".constantSymbol", &payment.ConstantSymbol)
+		fn(0, p+".specificSymbol", &payment.SpecificSymbol)
+		fn(FieldOriginatorsReferenceInformation, p+".originatorsReferenceInformation", &payment.OriginatorsReferenceInformation)
+		fn(FieldPaymentNote, p+".paymentNote", &payment.PaymentNote)
+
+		for j := range payment.BankAccounts {
+			account := &payment.BankAccounts[j]
+			fn(0, fmt.Sprintf("%s.bankAccounts[%d].iban", p, j), &account.IBAN)
+			fn(0, fmt.Sprintf("%s.bankAccounts[%d].bic", p, j), &account.BIC)
+		}
+
+		if so := payment.StandingOrderExt; so != nil {
+			fn(0, p+".standingOrderExt.lastDate", &so.LastDate)
+		}
+
+		if dd := payment.DirectDebitExt; dd != nil {
+			fn(0, p+".directDebitExt.variableSymbol", &dd.VariableSymbol)
+			fn(0, p+".directDebitExt.specificSymbol", &dd.SpecificSymbol)
+			fn(FieldDirectDebit, p+".directDebitExt.originatorsReferenceInformation", &dd.OriginatorsReferenceInfo)
+			fn(FieldDirectDebit, p+".directDebitExt.mandateId", &dd.MandateID)
+			fn(FieldDirectDebit, p+".directDebitExt.creditorId", &dd.CreditorID)
+			fn(FieldDirectDebit, p+".directDebitExt.contractId", &dd.ContractID)
+			fn(0, p+".directDebitExt.validTillDate", &dd.ValidTillDate)
+		}
+	}
+
+	for i := range model.Payments {
+		if b := model.Payments[i].Beneficiary; b != nil {
+			p := fmt.Sprintf("payments[%d].beneficiary", i)
+			fn(FieldBeneficiaryName, p+".name", &b.Name)
+			fn(FieldBeneficiaryStreet, p+".street", &b.Street)
+			fn(FieldBeneficiaryCity, p+".city", &b.City)
+		}
+	}
+}
+
+// ValidateSimplePayment validates a single payment. Symbols must pass
+// bysquare.NormalizeSymbol; the returned error then wraps
+// bysquare.ErrSymbol. With ValidateOptions.ListedConstantSymbols, unlisted
+// constant symbols are reported with bysquare.ErrConstantSymbol.
+// Originator's references that start like an ISO 11649 creditor reference,
+// RF and two check digits in either case, must be valid ones, otherwise the
+// error wraps bysquare.ErrCreditorReference. Other references are free
+// text.
+func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare.Version, opts ...ValidateOptions) error {
+	var options ValidateOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
 	if len(payment.BankAccounts) == 0 {
 		return &ValidationError{
 			Message: "at least one bank account required",
@@ -56,6 +153,13 @@ func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare
 		}
 	}
 
+	if payment.Amount.Validate() != nil {
+		return &ValidationError{
+			Message: "invalid decimal number",
+			Path:    fmt.Sprintf("%s.amount", path),
+		}
+	}
+
 	if payment.CurrencyCode != "" {
 		if !bysquare.IsValidCurrencyCode(string(payment.CurrencyCode)) {
 			return &ValidationError{
@@ -74,6 +178,24 @@ func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare
 		}
 	}
 
+	if err := validateSymbols(payment, path); err != nil {
+		return err
+	}
+
+	if options.ListedConstantSymbols {
+		if err := bysquare.ValidateConstantSymbol(payment.ConstantSymbol); err != nil {
+			return &ValidationError{
+				Message: err.Error(),
+				Path:    path + ".constantSymbol",
+				Err:     err,
+			}
+		}
+	}
+
+	if err := validateCreditorReferences(payment, path); err != nil {
+		return err
+	}
+
 	if payment.Type == PaymentTypeStandingOrder && payment.StandingOrderExt != nil {
 		if payment.StandingOrderExt.LastDate != "" && !bysquare.IsValidDate(payment.StandingOrderExt.LastDate) {
 			return &ValidationError{
@@ -84,6 +206,12 @@ func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare
 	}
 
 	if payment.Type == PaymentTypeDirectDebit && payment.DirectDebitExt != nil {
+		if payment.DirectDebitExt.MaxAmount.Validate() != nil {
+			return &ValidationError{
+				Message: "invalid decimal number",
+				Path:    fmt.Sprintf("%s.directDebitExt.maxAmount", path),
+			}
+		}
 		if payment.DirectDebitExt.ValidTillDate != "" && !bysquare.IsValidDate(payment.DirectDebitExt.ValidTillDate) {
 			return &ValidationError{
 				Message: "invalid date format (YYYYMMDD per v1.2 specification)",
@@ -102,12 +230,83 @@ func ValidateSimplePayment(payment *SimplePayment, path string, version bysquare
 	return nil
 }
 
-// ValidateBankAccount validates IBAN and BIC.
+// symbolField is a payment symbol of a SimplePayment with its JSON path.
+type symbolField struct {
+	kind  bysquare.Symbol
+	path  string
+	value *string
+}
+
+// symbolFields returns the symbols of payment, including those of its
+// direct debit extension.
+func symbolFields(payment *SimplePayment, path string) []symbolField {
+	fields := []symbolField{
+		{bysquare.SymbolVariable, path + ".variableSymbol", &payment.VariableSymbol},
+		{bysquare.SymbolConstant, path + ".constantSymbol", &payment.ConstantSymbol},
+		{bysquare.SymbolSpecific, path + ".specificSymbol", &payment.SpecificSymbol},
+	}
+	if dd := payment.DirectDebitExt; dd != nil {
+		fields = append(fields,
+			symbolField{bysquare.SymbolVariable, path + ".directDebitExt.variableSymbol", &dd.VariableSymbol},
+			symbolField{bysquare.SymbolSpecific, path + ".directDebitExt.specificSymbol", &dd.SpecificSymbol},
+		)
+	}
+	return fields
+}
+
+func validateSymbols(payment *SimplePayment, path string) error {
+	for _, f := range symbolFields(payment, path) {
+		if _, err := bysquare.NormalizeSymbol(f.kind, *f.value); err != nil {
+			return &ValidationError{
+				Message: err.Error(),
+				Path:    f.path,
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// validateCreditorReferences checks the originator's references of payment
+// that start with "RF", in either case and ignoring spaces, as creditor
+// references.
+func validateCreditorReferences(payment *SimplePayment, path string) error {
+	if err := validateCreditorReference(payment.OriginatorsReferenceInformation, path+".originatorsReferenceInformation"); err != nil {
+		return err
+	}
+	if dd := payment.DirectDebitExt; dd != nil {
+		return validateCreditorReference(dd.OriginatorsReferenceInfo, path+".directDebitExt.originatorsReferenceInformation")
+	}
+	return nil
+}
+
+// creditorReferenceShape matches the start of an ISO 11649 creditor
+// reference in printed or electronic form.
+var creditorReferenceShape = regexp.MustCompile(`^ *[Rr][Ff][0-9]{2}`)
+
+func validateCreditorReference(ref, path string) error {
+	if !creditorReferenceShape.MatchString(ref) {
+		return nil
+	}
+	if err := bysquare.ValidateCreditorReference(ref); err != nil {
+		return &ValidationError{
+			Message: err.Error(),
+			Path:    path,
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// ValidateBankAccount validates IBAN and BIC. The IBAN is checked against
+// the format registered for its country; the returned error wraps the
+// *bysquare.IBANError with the reason.
 func ValidateBankAccount(account *BankAccount, path string) error {
-	if !bysquare.IsValidIBAN(account.IBAN) {
+	if err := bysquare.ValidateIBAN(account.IBAN); err != nil {
 		return &ValidationError{
-			Message: "invalid IBAN (ISO 13616)",
+			Message: fmt.Sprintf("invalid IBAN (ISO 13616): %v", err),
 			Path:    fmt.Sprintf("%s.iban", path),
+			Err:     err,
 		}
 	}
 
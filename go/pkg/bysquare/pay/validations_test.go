@@ -1,6 +1,7 @@
 package pay
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/xseman/bysquare/go/pkg/bysquare"
@@ -18,7 +19,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -42,7 +43,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{},
 					Beneficiary:  &Beneficiary{Name: "Test"},
@@ -56,7 +57,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "INVALID"},
@@ -72,7 +73,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: "XX",
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -88,7 +89,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -104,7 +105,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:         PaymentTypePaymentOrder,
-					Amount:       100,
+					Amount:       "100",
 					CurrencyCode: CurrencyEUR,
 					BankAccounts: []BankAccount{
 						{IBAN: "SK9611000000002918599669"},
@@ -120,7 +121,7 @@ func TestValidateDataModel(t *testing.T) {
 			model: DataModel{
 				Payments: []SimplePayment{{
 					Type:           PaymentTypePaymentOrder,
-					Amount:         100,
+					Amount:         "100",
 					CurrencyCode:   CurrencyEUR,
 					PaymentDueDate: "2024-12-31",
 					BankAccounts: []BankAccount{
@@ -143,3 +144,216 @@ func TestValidateDataModel(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRepertoire(t *testing.T) {
+	model := DataModel{
+		Payments: []SimplePayment{{
+			Type:         PaymentTypePaymentOrder,
+			Amount:       "10",
+			CurrencyCode: CurrencyEUR,
+			PaymentNote:  "Ďakujem 😀",
+			BankAccounts: []BankAccount{
+				{IBAN: "SK9611000000002918599669"},
+			},
+			Beneficiary: &Beneficiary{Name: "Шевченко"},
+		}},
+	}
+
+	if err := ValidateDataModel(&model); err != nil {
+		t.Fatalf("expected no error without a repertoire, got %v", err)
+	}
+
+	err := ValidateRepertoire(&model, bysquare.RepertoireLatin2)
+	var repErr *bysquare.RepertoireError
+	if !errors.As(err, &repErr) {
+		t.Fatalf("expected RepertoireError, got %v", err)
+	}
+
+	if len(repErr.Runes) != 9 {
+		t.Fatalf("expected 9 invalid runes, got %d: %v", len(repErr.Runes), repErr.Runes)
+	}
+	if expected := (bysquare.InvalidRune{Path: "payments[0].paymentNote", Offset: 8, Rune: '😀'}); repErr.Runes[0] != expected {
+		t.Errorf("expected %v, got %v", expected, repErr.Runes[0])
+	}
+	if expected := (bysquare.InvalidRune{Path: "payments[0].beneficiary.name", Offset: 0, Rune: 'Ш'}); repErr.Runes[1] != expected {
+		t.Errorf("expected %v, got %v", expected, repErr.Runes[1])
+	}
+}
+
+func TestValidateBankAccountReason(t *testing.T) {
+	tests := []struct {
+		iban string
+		err  error
+	}{
+		{"SK961100000000291859966", bysquare.ErrIBANLength},
+		{"CZ5508000000001234567A99", bysquare.ErrIBANFormat},
+		{"SK9611000000002918599668", bysquare.ErrIBANChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.iban, func(t *testing.T) {
+			err := ValidateBankAccount(&BankAccount{IBAN: tt.iban}, "payments[0].bankAccounts[0]")
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Path != "payments[0].bankAccounts[0].iban" {
+				t.Fatalf("expected ValidationError for the IBAN, got %v", err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateSimplePaymentSymbols(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment SimplePayment
+		path    string
+		err     error
+	}{
+		{
+			name:    "valid symbols",
+			payment: SimplePayment{VariableSymbol: "0000000001234567890", ConstantSymbol: "308", SpecificSymbol: "42"},
+		},
+		{
+			name:    "long variable symbol",
+			payment: SimplePayment{VariableSymbol: "12345678901"},
+			path:    "payments[0].variableSymbol",
+			err:     bysquare.ErrSymbol,
+		},
+		{
+			name:    "unlisted constant symbol",
+			payment: SimplePayment{ConstantSymbol: "0138"},
+		},
+		{
+			name:    "long constant symbol",
+			payment: SimplePayment{ConstantSymbol: "12345"},
+			path:    "payments[0].constantSymbol",
+			err:     bysquare.ErrSymbol,
+		},
+		{
+			name:    "letters in specific symbol",
+			payment: SimplePayment{SpecificSymbol: "SS42"},
+			path:    "payments[0].specificSymbol",
+			err:     bysquare.ErrSymbol,
+		},
+		{
+			name: "direct debit variable symbol",
+			payment: SimplePayment{
+				Type:           PaymentTypeDirectDebit,
+				DirectDebitExt: &DirectDebit{VariableSymbol: "12 34"},
+			},
+			path: "payments[0].directDebitExt.variableSymbol",
+			err:  bysquare.ErrSymbol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := tt.payment
+			if payment.Type == 0 {
+				payment.Type = PaymentTypePaymentOrder
+			}
+			payment.BankAccounts = []BankAccount{{IBAN: "SK9611000000002918599669"}}
+			payment.Beneficiary = &Beneficiary{Name: "Test"}
+
+			err := ValidateSimplePayment(&payment, "payments[0]", bysquare.Version120)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Path != tt.path {
+				t.Fatalf("expected ValidationError at %s, got %v", tt.path, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateSimplePaymentListedConstantSymbols(t *testing.T) {
+	payment := SimplePayment{
+		Type:           PaymentTypePaymentOrder,
+		Amount:         "10",
+		CurrencyCode:   CurrencyEUR,
+		ConstantSymbol: "1178",
+		BankAccounts:   []BankAccount{{IBAN: "SK9611000000002918599669"}},
+		Beneficiary:    &Beneficiary{Name: "Test"},
+	}
+	listed := ValidateOptions{ListedConstantSymbols: true}
+
+	if err := ValidateSimplePayment(&payment, "payment", bysquare.Version120); err != nil {
+		t.Fatalf("expected no error by default, got %v", err)
+	}
+
+	err := ValidateSimplePayment(&payment, "payment", bysquare.Version120, listed)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Path != "payment.constantSymbol" {
+		t.Fatalf("expected ValidationError for the constant symbol, got %v", err)
+	}
+	if !errors.Is(err, bysquare.ErrConstantSymbol) {
+		t.Errorf("expected ErrConstantSymbol, got %v", err)
+	}
+
+	payment.ConstantSymbol = "308"
+	if err := ValidateSimplePayment(&payment, "payment", bysquare.Version120, listed); err != nil {
+		t.Errorf("expected listed symbol to pass, got %v", err)
+	}
+}
+
+func TestValidateSimplePaymentCreditorReference(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		dd   string
+		path string
+	}{
+		{name: "valid reference", ref: "RF18539007547034"},
+		{name: "printed reference", ref: "RF18 5390 0754 7034"},
+		{name: "lower case reference", ref: "rf18 5390 0754 7034"},
+		{name: "free text", ref: "Invoice 2024/42"},
+		{name: "free text starting with rf", ref: "rf invoice 12"},
+		{name: "free text starting with RF", ref: "RFQ-2024/15"},
+		{name: "lower case wrong check digits", ref: "rf19 5390 0754 7034", path: "payments[0].originatorsReferenceInformation"},
+		{name: "leading space", ref: " RF19539007547034", path: "payments[0].originatorsReferenceInformation"},
+		{name: "wrong check digits", ref: "RF19539007547034", path: "payments[0].originatorsReferenceInformation"},
+		{name: "malformed reference", ref: "RF18-5390", path: "payments[0].originatorsReferenceInformation"},
+		{name: "direct debit reference", dd: "RF18539007547035", path: "payments[0].directDebitExt.originatorsReferenceInformation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := SimplePayment{
+				Type:                            PaymentTypePaymentOrder,
+				OriginatorsReferenceInformation: tt.ref,
+				BankAccounts:                    []BankAccount{{IBAN: "SK9611000000002918599669"}},
+				Beneficiary:                     &Beneficiary{Name: "Test"},
+			}
+			if tt.dd != "" {
+				payment.Type = PaymentTypeDirectDebit
+				payment.DirectDebitExt = &DirectDebit{OriginatorsReferenceInfo: tt.dd}
+			}
+
+			err := ValidateSimplePayment(&payment, "payments[0]", bysquare.Version120)
+			if tt.path == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Path != tt.path {
+				t.Fatalf("expected ValidationError at %s, got %v", tt.path, err)
+			}
+			if !errors.Is(err, bysquare.ErrCreditorReference) {
+				t.Errorf("expected ErrCreditorReference, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package qr
+
+import (
+	"image"
+)
+
+// bitMatrix is a binarized image; true marks a dark pixel.
+type bitMatrix struct {
+	width, height int
+	bits          []bool
+}
+
+func newBitMatrix(width, height int) *bitMatrix {
+	return &bitMatrix{
+		width:  width,
+		height: height,
+		bits:   make([]bool, width*height),
+	}
+}
+
+// get reports whether the pixel at (x, y) is dark. Pixels outside the
+// matrix are light.
+func (m *bitMatrix) get(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return false
+	}
+	return m.bits[y*m.width+x]
+}
+
+// luminance converts an image to 8-bit grayscale. Transparent pixels are
+// composited over white, since PNG exports often have no background.
+func luminance(img image.Image) (lum []uint8, width, height int) {
+	b := img.Bounds()
+	width, height = b.Dx(), b.Dy()
+	lum = make([]uint8, width*height)
+
+	switch src := img.(type) {
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			copy(lum[y*width:(y+1)*width], src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):])
+		}
+	case *image.YCbCr:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				lum[y*width+x] = src.Y[src.YOffset(b.Min.X+x, b.Min.Y+y)]
+			}
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+				// Premultiplied channels; add the white background
+				// showing through transparency.
+				v := (299*r + 587*g + 114*bl) / 1000
+				v += 0xFFFF - a
+				lum[y*width+x] = uint8(v >> 8)
+			}
+		}
+	}
+
+	return lum, width, height
+}
+
+// binarizeGlobal thresholds the whole image at a single level chosen by
+// Otsu's method. It works well for evenly lit images such as PDF renders
+// and flatbed scans.
+func binarizeGlobal(lum []uint8, width, height int) *bitMatrix {
+	var histogram [256]int
+	for _, v := range lum {
+		histogram[v]++
+	}
+
+	total := len(lum)
+	sum := 0
+	for i, n := range histogram {
+		sum += i * n
+	}
+
+	threshold := 127
+	var best float64
+	sumB, weightB := 0, 0
+	for t := 0; t < 256; t++ {
+		weightB += histogram[t]
+		if weightB == 0 {
+			continue
+		}
+		weightF := total - weightB
+		if weightF == 0 {
+			break
+		}
+		sumB += t * histogram[t]
+		meanB := float64(sumB) / float64(weightB)
+		meanF := float64(sum-sumB) / float64(weightF)
+		between := float64(weightB) * float64(weightF) * (meanB - meanF) * (meanB - meanF)
+		if between > best {
+			best = between
+			threshold = t
+		}
+	}
+
+	m := newBitMatrix(width, height)
+	for i, v := range lum {
+		m.bits[i] = int(v) <= threshold
+	}
+	return m
+}
+
+// Block size and minimum contrast for local binarization.
+const (
+	localBlockSize   = 8
+	localMinContrast = 24
+)
+
+// binarizeLocal thresholds each 8x8 block at the average of the block
+// means in its 5x5 neighbourhood. It copes with shadows and uneven
+// lighting in photos and phone scans.
+//
+// Low-contrast blocks take the threshold of an already processed
+// neighbour when that neighbour saw darker content, so the inside of large
+// dark areas such as finder pattern centers stays dark.
+func binarizeLocal(lum []uint8, width, height int) *bitMatrix {
+	blocksX := (width + localBlockSize - 1) / localBlockSize
+	blocksY := (height + localBlockSize - 1) / localBlockSize
+
+	averages := make([]int, blocksX*blocksY)
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			sum, count := 0, 0
+			minV, maxV := 255, 0
+			for y := by * localBlockSize; y < min((by+1)*localBlockSize, height); y++ {
+				for x := bx * localBlockSize; x < min((bx+1)*localBlockSize, width); x++ {
+					v := int(lum[y*width+x])
+					sum += v
+					count++
+					minV = min(minV, v)
+					maxV = max(maxV, v)
+				}
+			}
+
+			average := sum / count
+			if maxV-minV <= localMinContrast {
+				// Assume a uniform block is background unless a
+				// neighbour says otherwise.
+				average = minV / 2
+				if by > 0 && bx > 0 {
+					neighbours := (averages[(by-1)*blocksX+bx] +
+						2*averages[by*blocksX+bx-1] +
+						averages[(by-1)*blocksX+bx-1]) / 4
+					if minV < neighbours {
+						average = neighbours
+					}
+				}
+			}
+			averages[by*blocksX+bx] = average
+		}
+	}
+
+	m := newBitMatrix(width, height)
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			sum := 0
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					ny := min(max(by+dy, 0), blocksY-1)
+					nx := min(max(bx+dx, 0), blocksX-1)
+					sum += averages[ny*blocksX+nx]
+				}
+			}
+			threshold := sum / 25
+
+			for y := by * localBlockSize; y < min((by+1)*localBlockSize, height); y++ {
+				for x := bx * localBlockSize; x < min((bx+1)*localBlockSize, width); x++ {
+					m.bits[y*width+x] = int(lum[y*width+x]) <= threshold
+				}
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,303 @@
+package qr
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode/utf8"
+)
+
+// readFormat decodes the format information from both copies in a sampled
+// grid, tolerating up to three bit errors.
+//
+// @see ISO/IEC 18004:2015, 7.9.
+func readFormat(modules []bool, size int) (Level, int, bool) {
+	at := func(x, y int) uint {
+		if modules[y*size+x] {
+			return 1
+		}
+		return 0
+	}
+
+	var first, second uint
+	for i := 0; i <= 5; i++ {
+		first |= at(8, i) << uint(i)
+	}
+	first |= at(8, 7) << 6
+	first |= at(8, 8) << 7
+	first |= at(7, 8) << 8
+	for i := 9; i < 15; i++ {
+		first |= at(14-i, 8) << uint(i)
+	}
+
+	for i := 0; i < 8; i++ {
+		second |= at(size-1-i, 8) << uint(i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= at(8, size-15+i) << uint(i)
+	}
+
+	bestDistance := 4
+	var bestLevel Level
+	bestMask := 0
+	for level := LevelL; level <= LevelH; level++ {
+		for mask := 0; mask < 8; mask++ {
+			want := formatBits(level, mask)
+			d := min(bits.OnesCount(first^want), bits.OnesCount(second^want))
+			if d < bestDistance {
+				bestDistance, bestLevel, bestMask = d, level, mask
+			}
+		}
+	}
+
+	return bestLevel, bestMask, bestDistance <= 3
+}
+
+// readVersion decodes the version information blocks of a sampled grid
+// with size >= 45, tolerating up to three bit errors.
+//
+// @see ISO/IEC 18004:2015, 7.10.
+func readVersion(modules []bool, size int) (int, bool) {
+	var topRight, bottomLeft uint
+	for i := 0; i < 18; i++ {
+		a, b := size-11+i%3, i/3
+		if modules[b*size+a] {
+			topRight |= 1 << uint(i)
+		}
+		if modules[a*size+b] {
+			bottomLeft |= 1 << uint(i)
+		}
+	}
+
+	bestDistance := 4
+	bestVersion := 0
+	for v := 7; v <= MaxVersion; v++ {
+		want := versionBits(v)
+		d := min(bits.OnesCount(topRight^want), bits.OnesCount(bottomLeft^want))
+		if d < bestDistance {
+			bestDistance, bestVersion = d, v
+		}
+	}
+
+	return bestVersion, bestDistance <= 3
+}
+
+// decodeGrid reads the text from a sampled module grid of a known version.
+func decodeGrid(modules []bool, version int) (string, error) {
+	size := symbolSize(version)
+
+	level, mask, ok := readFormat(modules, size)
+	if !ok {
+		return "", fmt.Errorf("unreadable format information")
+	}
+
+	function := make([]bool, size*size)
+	mark := func(x, y int, _ bool) {
+		function[y*size+x] = true
+	}
+	drawFunctionPatterns(version, size, mark)
+	drawFormatBits(0, size, mark)
+
+	rawCodewords := numRawDataModules(version) / 8
+	codewords := make([]byte, rawCodewords)
+	i := 0
+	forEachDataModule(size, function, func(x, y int) {
+		if i >= rawCodewords*8 {
+			return
+		}
+		if modules[y*size+x] != maskBit(mask, x, y) {
+			codewords[i/8] |= 0x80 >> uint(i%8)
+		}
+		i++
+	})
+
+	data, err := correctErrors(codewords, version, level)
+	if err != nil {
+		return "", err
+	}
+
+	return parseSegments(data, version)
+}
+
+// correctErrors de-interleaves codewords into blocks, corrects each block,
+// and returns the concatenated data codewords. It is the inverse of
+// addErrorCorrection.
+func correctErrors(codewords []byte, version int, level Level) ([]byte, error) {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	blocks := make([][]byte, numBlocks)
+	for i := range blocks {
+		blocks[i] = make([]byte, shortBlockLen+1)
+	}
+
+	k := 0
+	for i := 0; i <= shortBlockLen; i++ {
+		for j, block := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				block[i] = codewords[k]
+				k++
+			}
+		}
+	}
+
+	data := make([]byte, 0, numDataCodewords(version, level))
+	for j, block := range blocks {
+		if j < numShortBlocks {
+			// Drop the interleaving placeholder.
+			block = append(block[:shortBlockLen-eccLen], block[shortBlockLen-eccLen+1:]...)
+		}
+		if _, err := rsCorrect(block, eccLen); err != nil {
+			return nil, fmt.Errorf("block %d: %w", j, err)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	return data, nil
+}
+
+// bitReader reads big-endian bit fields from a byte slice.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) available() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) read(n int) (uint, error) {
+	if n > r.available() {
+		return 0, fmt.Errorf("truncated segment data")
+	}
+	var v uint
+	for i := 0; i < n; i++ {
+		bit := (r.data[r.pos/8] >> uint(7-r.pos%8)) & 1
+		v = v<<1 | uint(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+// parseSegments decodes the data segments of a corrected bit stream.
+//
+// Numeric, alphanumeric and byte segments are decoded; ECI designators,
+// FNC1 indicators and structured append headers are skipped. Byte segments
+// are interpreted as UTF-8 when valid and ISO 8859-1 otherwise.
+//
+// @see ISO/IEC 18004:2015, 7.4.
+func parseSegments(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	var sb strings.Builder
+
+	for r.available() >= 4 {
+		m, _ := r.read(4)
+
+		switch mode(m) {
+		case modeTerminator:
+			return sb.String(), nil
+
+		case modeNumeric:
+			count, err := r.read(charCountBits(modeNumeric, version))
+			if err != nil {
+				return "", err
+			}
+			for count > 0 {
+				digits := min(count, 3)
+				v, err := r.read(int(digits)*3 + 1)
+				if err != nil {
+					return "", err
+				}
+				s := fmt.Sprintf("%0*d", digits, v)
+				if len(s) != int(digits) {
+					return "", fmt.Errorf("invalid numeric segment")
+				}
+				sb.WriteString(s)
+				count -= digits
+			}
+
+		case modeAlphanumeric:
+			count, err := r.read(charCountBits(modeAlphanumeric, version))
+			if err != nil {
+				return "", err
+			}
+			for ; count >= 2; count -= 2 {
+				v, err := r.read(11)
+				if err != nil {
+					return "", err
+				}
+				if v >= 45*45 {
+					return "", fmt.Errorf("invalid alphanumeric segment")
+				}
+				sb.WriteByte(alphanumericCharset[v/45])
+				sb.WriteByte(alphanumericCharset[v%45])
+			}
+			if count == 1 {
+				v, err := r.read(6)
+				if err != nil {
+					return "", err
+				}
+				if v >= 45 {
+					return "", fmt.Errorf("invalid alphanumeric segment")
+				}
+				sb.WriteByte(alphanumericCharset[v])
+			}
+
+		case modeByte:
+			count, err := r.read(charCountBits(modeByte, version))
+			if err != nil {
+				return "", err
+			}
+			raw := make([]byte, count)
+			for i := range raw {
+				v, err := r.read(8)
+				if err != nil {
+					return "", err
+				}
+				raw[i] = byte(v)
+			}
+			if utf8.Valid(raw) {
+				sb.Write(raw)
+			} else {
+				for _, b := range raw {
+					sb.WriteRune(rune(b))
+				}
+			}
+
+		case 0b0111: // ECI
+			first, err := r.read(8)
+			if err != nil {
+				return "", err
+			}
+			switch {
+			case first&0x80 == 0:
+			case first&0xC0 == 0x80:
+				_, err = r.read(8)
+			default:
+				_, err = r.read(16)
+			}
+			if err != nil {
+				return "", err
+			}
+
+		case 0b0011: // Structured append
+			if _, err := r.read(16); err != nil {
+				return "", err
+			}
+
+		case 0b0101: // FNC1, first position
+		case 0b1001: // FNC1, second position
+			if _, err := r.read(8); err != nil {
+				return "", err
+			}
+
+		default:
+			return "", fmt.Errorf("%w: %04b", ErrUnsupportedMode, m)
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,197 @@
+package qr
+
+import (
+	"math"
+)
+
+// perspective is a projective transform between two planes.
+//
+//	x' = (a11*x + a21*y + a31) / (a13*x + a23*y + a33)
+//	y' = (a12*x + a22*y + a32) / (a13*x + a23*y + a33)
+type perspective struct {
+	a11, a21, a31 float64
+	a12, a22, a32 float64
+	a13, a23, a33 float64
+}
+
+func (p perspective) apply(x, y float64) (float64, float64) {
+	denominator := p.a13*x + p.a23*y + p.a33
+	return (p.a11*x + p.a21*y + p.a31) / denominator,
+		(p.a12*x + p.a22*y + p.a32) / denominator
+}
+
+// squareToQuad maps the unit square corners (0,0), (1,0), (1,1), (0,1) to
+// the quadrilateral p0, p1, p2, p3.
+func squareToQuad(p0, p1, p2, p3 point) perspective {
+	dx3 := p0.x - p1.x + p2.x - p3.x
+	dy3 := p0.y - p1.y + p2.y - p3.y
+	if dx3 == 0 && dy3 == 0 {
+		// Affine case.
+		return perspective{
+			a11: p1.x - p0.x, a21: p2.x - p1.x, a31: p0.x,
+			a12: p1.y - p0.y, a22: p2.y - p1.y, a32: p0.y,
+			a33: 1,
+		}
+	}
+
+	dx1, dx2 := p1.x-p2.x, p3.x-p2.x
+	dy1, dy2 := p1.y-p2.y, p3.y-p2.y
+	denominator := dx1*dy2 - dx2*dy1
+	a13 := (dx3*dy2 - dx2*dy3) / denominator
+	a23 := (dx1*dy3 - dx3*dy1) / denominator
+	return perspective{
+		a11: p1.x - p0.x + a13*p1.x, a21: p3.x - p0.x + a23*p3.x, a31: p0.x,
+		a12: p1.y - p0.y + a13*p1.y, a22: p3.y - p0.y + a23*p3.y, a32: p0.y,
+		a13: a13, a23: a23, a33: 1,
+	}
+}
+
+// adjoint returns the adjugate matrix, which inverts the transform up to
+// scale.
+func (p perspective) adjoint() perspective {
+	return perspective{
+		a11: p.a22*p.a33 - p.a23*p.a32,
+		a21: p.a23*p.a31 - p.a21*p.a33,
+		a31: p.a21*p.a32 - p.a22*p.a31,
+		a12: p.a13*p.a32 - p.a12*p.a33,
+		a22: p.a11*p.a33 - p.a13*p.a31,
+		a32: p.a12*p.a31 - p.a11*p.a32,
+		a13: p.a12*p.a23 - p.a13*p.a22,
+		a23: p.a13*p.a21 - p.a11*p.a23,
+		a33: p.a11*p.a22 - p.a12*p.a21,
+	}
+}
+
+// times returns the transform that applies o first and then p.
+func (p perspective) times(o perspective) perspective {
+	return perspective{
+		a11: p.a11*o.a11 + p.a21*o.a12 + p.a31*o.a13,
+		a21: p.a11*o.a21 + p.a21*o.a22 + p.a31*o.a23,
+		a31: p.a11*o.a31 + p.a21*o.a32 + p.a31*o.a33,
+		a12: p.a12*o.a11 + p.a22*o.a12 + p.a32*o.a13,
+		a22: p.a12*o.a21 + p.a22*o.a22 + p.a32*o.a23,
+		a32: p.a12*o.a31 + p.a22*o.a32 + p.a32*o.a33,
+		a13: p.a13*o.a11 + p.a23*o.a12 + p.a33*o.a13,
+		a23: p.a13*o.a21 + p.a23*o.a22 + p.a33*o.a23,
+		a33: p.a13*o.a31 + p.a23*o.a32 + p.a33*o.a33,
+	}
+}
+
+// quadToQuad maps quadrilateral src onto quadrilateral dst, corners given
+// clockwise from the top-left.
+func quadToQuad(src, dst [4]point) perspective {
+	toSquare := squareToQuad(src[0], src[1], src[2], src[3]).adjoint()
+	fromSquare := squareToQuad(dst[0], dst[1], dst[2], dst[3])
+	return fromSquare.times(toSquare)
+}
+
+// estimateDimension derives the symbol size in modules from the distances
+// between finder centers, rounded to the nearest valid size (4v + 17).
+func estimateDimension(t finderTriple, moduleSize float64) int {
+	top := distance(t.topLeft.point, t.topRight.point) / moduleSize
+	left := distance(t.topLeft.point, t.bottomLeft.point) / moduleSize
+	dimension := int(math.Round((top+left)/2)) + 7
+
+	// Snap to 4v + 17, i.e. dimension % 4 == 1.
+	switch dimension % 4 {
+	case 0:
+		dimension++
+	case 2:
+		dimension--
+	case 3:
+		dimension -= 2
+	}
+	return dimension
+}
+
+// findAlignment searches around the position predicted for the bottom-right
+// alignment pattern. It matches the 5x5 module template at sub-module steps
+// and returns the average position of the best matches.
+//
+// @see ISO/IEC 18004:2015, 6.3.6.
+func findAlignment(m *bitMatrix, t finderTriple, dimension int, moduleSize float64) (point, bool) {
+	span := float64(dimension - 7)
+	// Module step vectors along the symbol's x and y axes.
+	ux := point{(t.topRight.x - t.topLeft.x) / span, (t.topRight.y - t.topLeft.y) / span}
+	uy := point{(t.bottomLeft.x - t.topLeft.x) / span, (t.bottomLeft.y - t.topLeft.y) / span}
+
+	// The alignment center sits 3 modules in from the finder axes.
+	offset := span - 3
+	estimate := point{
+		t.topLeft.x + offset*(ux.x+uy.x),
+		t.topLeft.y + offset*(ux.y+uy.y),
+	}
+
+	score := func(c point) int {
+		matches := 0
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				x := c.x + float64(dx)*ux.x + float64(dy)*uy.x
+				y := c.y + float64(dx)*ux.y + float64(dy)*uy.y
+				want := max(abs(dx), abs(dy)) != 1
+				if m.get(int(x), int(y)) == want {
+					matches++
+				}
+			}
+		}
+		return matches
+	}
+
+	step := math.Max(1, moduleSize/3)
+	for _, radius := range []float64{4, 8, 16} {
+		limit := radius * moduleSize
+		best := 0
+		var sum point
+		count := 0
+
+		for dy := -limit; dy <= limit; dy += step {
+			for dx := -limit; dx <= limit; dx += step {
+				c := point{estimate.x + dx, estimate.y + dy}
+				s := score(c)
+				switch {
+				case s > best:
+					best, sum, count = s, c, 1
+				case s == best && distance(c, point{sum.x / float64(count), sum.y / float64(count)}) <= moduleSize:
+					sum.x += c.x
+					sum.y += c.y
+					count++
+				}
+			}
+		}
+
+		// Allow a few misread modules in noisy images.
+		if best >= 23 {
+			return point{sum.x / float64(count), sum.y / float64(count)}, true
+		}
+	}
+
+	return point{}, false
+}
+
+// sampleGrid reads a dimension x dimension module grid through the finder
+// and, when present, alignment pattern centers.
+func sampleGrid(m *bitMatrix, t finderTriple, dimension int, alignment *point) []bool {
+	far := float64(dimension) - 3.5
+	src := [4]point{{3.5, 3.5}, {far, 3.5}, {far, far}, {3.5, far}}
+	dst := [4]point{
+		t.topLeft.point,
+		t.topRight.point,
+		{t.topRight.x - t.topLeft.x + t.bottomLeft.x, t.topRight.y - t.topLeft.y + t.bottomLeft.y},
+		t.bottomLeft.point,
+	}
+	if alignment != nil {
+		src[2] = point{far - 3, far - 3}
+		dst[2] = *alignment
+	}
+
+	transform := quadToQuad(src, dst)
+
+	modules := make([]bool, dimension*dimension)
+	for y := 0; y < dimension; y++ {
+		for x := 0; x < dimension; x++ {
+			px, py := transform.apply(float64(x)+0.5, float64(y)+0.5)
+			modules[y*dimension+x] = m.get(int(math.Floor(px)), int(math.Floor(py)))
+		}
+	}
+	return modules
+}
@@ -0,0 +1,22 @@
+package qr
+
+import "errors"
+
+var (
+	// ErrInvalidCharacter indicates text outside the alphanumeric mode
+	// character set.
+	ErrInvalidCharacter = errors.New("character not encodable in alphanumeric mode")
+
+	// ErrDataTooLong indicates text that does not fit any allowed version.
+	ErrDataTooLong = errors.New("data too long for QR code")
+
+	// ErrNotFound indicates that no readable QR code was found in an image.
+	ErrNotFound = errors.New("no QR code found")
+
+	// ErrTooManyErrors indicates a codeword block with more errors than
+	// Reed-Solomon correction can repair.
+	ErrTooManyErrors = errors.New("too many errors to correct")
+
+	// ErrUnsupportedMode indicates a segment mode the decoder cannot read.
+	ErrUnsupportedMode = errors.New("unsupported segment mode")
+)
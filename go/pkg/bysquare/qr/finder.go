@@ -0,0 +1,332 @@
+package qr
+
+import (
+	"math"
+	"sort"
+)
+
+// point is a position in image pixel coordinates.
+type point struct {
+	x, y float64
+}
+
+func distance(a, b point) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+// finderCandidate is a possible finder pattern center.
+type finderCandidate struct {
+	point
+	moduleSize float64
+	// count is the number of scan lines that confirmed the candidate.
+	count int
+}
+
+// findFinderCandidates scans a binarized image for the 1:1:3:1:1
+// dark-light-dark-light-dark run pattern of finder patterns, confirms each
+// hit vertically and horizontally, and merges hits that describe the same
+// pattern.
+//
+// @see ISO/IEC 18004:2015, 6.3.3.
+func findFinderCandidates(m *bitMatrix) []finderCandidate {
+	var candidates []finderCandidate
+
+	for y := 0; y < m.height; y++ {
+		var counts [5]int
+		state := 0
+
+		for x := 0; x <= m.width; x++ {
+			dark := x < m.width && m.get(x, y)
+
+			if dark {
+				if state%2 == 1 {
+					state++
+				}
+				counts[state]++
+				continue
+			}
+
+			if state%2 == 1 {
+				counts[state]++
+				continue
+			}
+			if state == 0 {
+				if counts[0] > 0 {
+					state = 1
+					counts[1]++
+				}
+				continue
+			}
+			if state < 4 {
+				state++
+				counts[state]++
+				continue
+			}
+
+			// A dark-light-dark-light-dark sequence just ended at x.
+			if isFinderRatio(counts) {
+				if c, ok := confirmFinder(m, counts, x, y); ok {
+					candidates = mergeCandidate(candidates, c)
+				}
+			}
+			// Keep the trailing dark-light-dark runs as the start of the
+			// next possible pattern.
+			counts = [5]int{counts[2], counts[3], counts[4], 1, 0}
+			state = 3
+		}
+	}
+
+	return candidates
+}
+
+// isFinderRatio reports whether five run lengths approximate 1:1:3:1:1.
+func isFinderRatio(counts [5]int) bool {
+	total := 0
+	for _, c := range counts {
+		if c == 0 {
+			return false
+		}
+		total += c
+	}
+	if total < 7 {
+		return false
+	}
+
+	module := float64(total) / 7
+	variance := module / 2
+	return math.Abs(module-float64(counts[0])) < variance &&
+		math.Abs(module-float64(counts[1])) < variance &&
+		math.Abs(3*module-float64(counts[2])) < 3*variance &&
+		math.Abs(module-float64(counts[3])) < variance &&
+		math.Abs(module-float64(counts[4])) < variance
+}
+
+// confirmFinder cross-checks a horizontal hit that ended at endX on row y
+// along the vertical, the horizontal, and one diagonal through its center.
+func confirmFinder(m *bitMatrix, counts [5]int, endX, y int) (finderCandidate, bool) {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	centerX := float64(endX-counts[4]-counts[3]) - float64(counts[2])/2
+
+	centerY, vTotal, ok := crossCheck(m, int(centerX), y, 0, 1, counts[2], total)
+	if !ok {
+		return finderCandidate{}, false
+	}
+	centerX, hTotal, ok := crossCheck(m, int(centerX), int(centerY), 1, 0, counts[2], total)
+	if !ok {
+		return finderCandidate{}, false
+	}
+	// Diagonal run lengths depend on rotation, so only the ratio counts.
+	if _, _, ok := crossCheck(m, int(centerX), int(centerY), 1, 1, counts[2]*2, 0); !ok {
+		return finderCandidate{}, false
+	}
+
+	return finderCandidate{
+		point:      point{centerX, centerY},
+		moduleSize: float64(vTotal+hTotal) / 14,
+		count:      1,
+	}, true
+}
+
+// crossCheck walks from (x, y) in both directions along (dx, dy) counting
+// the five runs of a finder pattern. It returns the center coordinate along
+// the walked axis and the total pattern length. A positive originalTotal
+// also rejects patterns whose length differs from it by 40% or more.
+func crossCheck(m *bitMatrix, x, y, dx, dy, maxCount, originalTotal int) (float64, int, bool) {
+	var counts [5]int
+
+	at := func(i int) bool {
+		return m.get(x+i*dx, y+i*dy)
+	}
+	inside := func(i int) bool {
+		px, py := x+i*dx, y+i*dy
+		return px >= 0 && py >= 0 && px < m.width && py < m.height
+	}
+
+	i := 0
+	for inside(i) && at(i) {
+		counts[2]++
+		i--
+	}
+	if !inside(i) {
+		return 0, 0, false
+	}
+	for inside(i) && !at(i) && counts[1] <= maxCount {
+		counts[1]++
+		i--
+	}
+	if !inside(i) || counts[1] > maxCount {
+		return 0, 0, false
+	}
+	for inside(i) && at(i) && counts[0] <= maxCount {
+		counts[0]++
+		i--
+	}
+	if counts[0] > maxCount {
+		return 0, 0, false
+	}
+
+	i = 1
+	for inside(i) && at(i) {
+		counts[2]++
+		i++
+	}
+	if !inside(i) {
+		return 0, 0, false
+	}
+	for inside(i) && !at(i) && counts[3] < maxCount {
+		counts[3]++
+		i++
+	}
+	if !inside(i) || counts[3] >= maxCount {
+		return 0, 0, false
+	}
+	for inside(i) && at(i) && counts[4] < maxCount {
+		counts[4]++
+		i++
+	}
+	if counts[4] >= maxCount {
+		return 0, 0, false
+	}
+
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	if originalTotal > 0 && 5*abs(total-originalTotal) >= 2*originalTotal {
+		return 0, 0, false
+	}
+	if !isFinderRatio(counts) {
+		return 0, 0, false
+	}
+
+	end := i
+	offset := float64(end-counts[4]-counts[3]) - float64(counts[2])/2
+	base := x
+	if dx == 0 {
+		base = y
+	}
+	return float64(base) + offset, total, true
+}
+
+// mergeCandidate folds c into an existing candidate at the same position
+// and scale, or appends it.
+func mergeCandidate(candidates []finderCandidate, c finderCandidate) []finderCandidate {
+	for i := range candidates {
+		e := &candidates[i]
+		if math.Abs(c.x-e.x) <= e.moduleSize && math.Abs(c.y-e.y) <= e.moduleSize &&
+			math.Abs(c.moduleSize-e.moduleSize) <= math.Max(1, e.moduleSize/2) {
+			n := float64(e.count)
+			e.x = (e.x*n + c.x) / (n + 1)
+			e.y = (e.y*n + c.y) / (n + 1)
+			e.moduleSize = (e.moduleSize*n + c.moduleSize) / (n + 1)
+			e.count++
+			return candidates
+		}
+	}
+	return append(candidates, c)
+}
+
+// finderTriple holds three finder patterns in symbol orientation.
+type finderTriple struct {
+	topLeft, topRight, bottomLeft finderCandidate
+}
+
+// maxFinderCandidates bounds the number of candidates combined into
+// triples, keeping the search cubic in a small constant.
+const maxFinderCandidates = 12
+
+// selectFinderTriples returns plausible finder pattern triples, best first.
+// A triple is plausible when its module sizes agree and the three centers
+// form a roughly isosceles right triangle.
+func selectFinderTriples(candidates []finderCandidate) []finderTriple {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].count > candidates[j].count
+	})
+	if len(candidates) > maxFinderCandidates {
+		candidates = candidates[:maxFinderCandidates]
+	}
+
+	type scored struct {
+		triple finderTriple
+		score  float64
+	}
+	var results []scored
+
+	n := len(candidates)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				a, b, c := candidates[i], candidates[j], candidates[k]
+
+				minSize := math.Min(a.moduleSize, math.Min(b.moduleSize, c.moduleSize))
+				maxSize := math.Max(a.moduleSize, math.Max(b.moduleSize, c.moduleSize))
+				if maxSize > minSize*1.5 {
+					continue
+				}
+
+				t := orderFinderTriple(a, b, c)
+				legA := distance(t.topLeft.point, t.topRight.point)
+				legB := distance(t.topLeft.point, t.bottomLeft.point)
+				hyp := distance(t.topRight.point, t.bottomLeft.point)
+
+				// Legs of equal length and a right angle at the corner.
+				legRatio := math.Abs(legA-legB) / math.Max(legA, legB)
+				hypRatio := math.Abs(hyp-math.Hypot(legA, legB)) / hyp
+				if legRatio > 0.4 || hypRatio > 0.15 {
+					continue
+				}
+
+				// Finder centers are at least 14 modules apart, even in version 1.
+				module := (a.moduleSize + b.moduleSize + c.moduleSize) / 3
+				if math.Min(legA, legB) < module*12 {
+					continue
+				}
+
+				sizeRatio := (maxSize - minSize) / minSize
+				countPenalty := 1 / float64(a.count+b.count+c.count)
+				results = append(results, scored{t, legRatio + hypRatio + sizeRatio + countPenalty})
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score < results[j].score
+	})
+
+	triples := make([]finderTriple, len(results))
+	for i, r := range results {
+		triples[i] = r.triple
+	}
+	return triples
+}
+
+// orderFinderTriple assigns roles: the top-left pattern is opposite the
+// longest side, and the remaining two are ordered so that the symbol is
+// not mirrored.
+func orderFinderTriple(a, b, c finderCandidate) finderTriple {
+	ab := distance(a.point, b.point)
+	bc := distance(b.point, c.point)
+	ac := distance(a.point, c.point)
+
+	var corner, p, q finderCandidate
+	switch {
+	case bc >= ab && bc >= ac:
+		corner, p, q = a, b, c
+	case ac >= ab && ac >= bc:
+		corner, p, q = b, a, c
+	default:
+		corner, p, q = c, a, b
+	}
+
+	// In image coordinates (y down), top-right x bottom-left relative to
+	// top-left has a positive z component.
+	cross := (p.x-corner.x)*(q.y-corner.y) - (p.y-corner.y)*(q.x-corner.x)
+	if cross < 0 {
+		p, q = q, p
+	}
+
+	return finderTriple{topLeft: corner, topRight: p, bottomLeft: q}
+}
@@ -0,0 +1,321 @@
+// Package qr renders bysquare strings as QR Code symbols.
+//
+// The output of pay.Encode and invoice.Encode uses the base32hex alphabet
+// (0-9, A-V), which is a subset of the QR alphanumeric character set, so the
+// payload is always stored in alphanumeric mode at 5.5 bits per character.
+//
+// The package has no dependencies outside the standard library.
+//
+// @see ISO/IEC 18004:2015
+package qr
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MinVersion is the smallest QR Code version (21x21 modules).
+	MinVersion = 1
+
+	// MaxVersion is the largest QR Code version (177x177 modules).
+	MaxVersion = 40
+)
+
+// Level is the error correction level of a QR Code symbol.
+type Level uint8
+
+const (
+	// LevelL recovers approximately 7% of the codewords.
+	LevelL Level = iota
+	// LevelM recovers approximately 15% of the codewords.
+	LevelM
+	// LevelQ recovers approximately 25% of the codewords.
+	LevelQ
+	// LevelH recovers approximately 30% of the codewords.
+	LevelH
+)
+
+// String returns the single-letter name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelL:
+		return "L"
+	case LevelM:
+		return "M"
+	case LevelQ:
+		return "Q"
+	case LevelH:
+		return "H"
+	default:
+		return fmt.Sprintf("Level(%d)", uint8(l))
+	}
+}
+
+// mode is a segment mode indicator.
+//
+// @see ISO/IEC 18004:2015, Table 2.
+type mode uint8
+
+const (
+	modeTerminator   mode = 0b0000
+	modeNumeric      mode = 0b0001
+	modeAlphanumeric mode = 0b0010
+	modeByte         mode = 0b0100
+	modeKanji        mode = 0b1000
+)
+
+// alphanumericCharset is the 45-character alphanumeric mode alphabet; the
+// index of a character is its value.
+//
+// @see ISO/IEC 18004:2015, Table 5.
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// Code is an encoded QR Code symbol.
+type Code struct {
+	// Version is the symbol version (1-40).
+	Version int
+	// Level is the error correction level.
+	Level Level
+	// Mask is the data mask pattern (0-7) applied to the symbol.
+	Mask int
+	// Size is the number of modules per side.
+	Size int
+
+	modules []bool
+}
+
+// Black reports whether the module at column x and row y is dark. Positions
+// outside the symbol are light.
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// EncodeOptions configures QR Code generation.
+type EncodeOptions struct {
+	// Level is the error correction level.
+	Level Level
+	// MinVersion is the smallest version to consider (0 means 1).
+	MinVersion int
+	// MaxVersion is the largest version to consider (0 means 40).
+	MaxVersion int
+}
+
+// DefaultEncodeOptions returns default encoding options.
+func DefaultEncodeOptions() EncodeOptions {
+	return EncodeOptions{
+		Level:      LevelM,
+		MinVersion: MinVersion,
+		MaxVersion: MaxVersion,
+	}
+}
+
+// Encode builds a QR Code symbol for text using alphanumeric mode.
+//
+// The smallest version within the configured range that fits the text at
+// the requested error correction level is chosen. Lowercase letters are
+// upper-cased, matching the loose base32hex decoding used by pay.Decode and
+// invoice.Decode.
+//
+// The encoding process:
+// 1. Alphanumeric segment construction
+// 2. Version selection
+// 3. Terminator and padding
+// 4. Reed-Solomon error correction and block interleaving
+// 5. Module placement and mask selection
+func Encode(text string, opts ...EncodeOptions) (*Code, error) {
+	options := DefaultEncodeOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	minVersion, maxVersion := options.MinVersion, options.MaxVersion
+	if minVersion == 0 {
+		minVersion = MinVersion
+	}
+	if maxVersion == 0 {
+		maxVersion = MaxVersion
+	}
+	if minVersion < MinVersion || maxVersion > MaxVersion || minVersion > maxVersion {
+		return nil, fmt.Errorf("invalid version range %d-%d", minVersion, maxVersion)
+	}
+	if options.Level > LevelH {
+		return nil, fmt.Errorf("invalid error correction level: %d", options.Level)
+	}
+
+	text = strings.ToUpper(text)
+	for i, r := range text {
+		if !strings.ContainsRune(alphanumericCharset, r) {
+			return nil, fmt.Errorf("%w: %q at offset %d", ErrInvalidCharacter, r, i)
+		}
+	}
+
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if alphanumericBits(len(text), version) <= numDataCodewords(version, options.Level)*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, fmt.Errorf("%w: %d characters at level %s exceed version %d", ErrDataTooLong, len(text), options.Level, maxVersion)
+	}
+
+	data := encodeAlphanumeric(text, version, options.Level)
+	codewords := addErrorCorrection(data, version, options.Level)
+
+	code := &Code{
+		Version: version,
+		Level:   options.Level,
+		Size:    symbolSize(version),
+	}
+	code.build(codewords)
+
+	return code, nil
+}
+
+// Capacity returns the maximum number of alphanumeric characters a symbol
+// of the given version and level can hold.
+func Capacity(version int, level Level) int {
+	if version < MinVersion || version > MaxVersion || level > LevelH {
+		return 0
+	}
+
+	available := numDataCodewords(version, level)*8 - 4 - charCountBits(modeAlphanumeric, version)
+	if available < 0 {
+		return 0
+	}
+
+	n := available / 11 * 2
+	if available%11 >= 6 {
+		n++
+	}
+	return n
+}
+
+// MinVersionFor returns the smallest version whose symbol at the given
+// level holds length alphanumeric characters, or 0 if even version 40 is
+// too small.
+func MinVersionFor(length int, level Level) int {
+	for version := MinVersion; version <= MaxVersion; version++ {
+		if Capacity(version, level) >= length {
+			return version
+		}
+	}
+	return 0
+}
+
+// alphanumericBits returns the segment length in bits for n alphanumeric
+// characters, including mode indicator and character count.
+func alphanumericBits(n, version int) int {
+	countBits := charCountBits(modeAlphanumeric, version)
+	if n >= 1<<countBits {
+		return 1 << 30
+	}
+	return 4 + countBits + n/2*11 + n%2*6
+}
+
+// encodeAlphanumeric builds the padded data codewords for a single
+// alphanumeric segment.
+//
+// @see ISO/IEC 18004:2015, 7.4.4 and 7.4.10.
+func encodeAlphanumeric(text string, version int, level Level) []byte {
+	var bb bitBuffer
+	bb.append(uint(modeAlphanumeric), 4)
+	bb.append(uint(len(text)), charCountBits(modeAlphanumeric, version))
+
+	for i := 0; i+1 < len(text); i += 2 {
+		a := strings.IndexByte(alphanumericCharset, text[i])
+		b := strings.IndexByte(alphanumericCharset, text[i+1])
+		bb.append(uint(a*45+b), 11)
+	}
+	if len(text)%2 == 1 {
+		bb.append(uint(strings.IndexByte(alphanumericCharset, text[len(text)-1])), 6)
+	}
+
+	capacityBits := numDataCodewords(version, level) * 8
+
+	terminator := capacityBits - bb.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	bb.append(uint(modeTerminator), terminator)
+	bb.append(0, (8-bb.len()%8)%8)
+
+	for pad := uint(0xEC); bb.len() < capacityBits; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	return bb.bytes()
+}
+
+// addErrorCorrection splits data into blocks, appends Reed-Solomon
+// codewords to each, and interleaves the result.
+//
+// @see ISO/IEC 18004:2015, 7.5.2 and 7.6.
+func addErrorCorrection(data []byte, version int, level Level) []byte {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	generator := rsGenerator(eccLen)
+
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range blocks {
+		dataLen := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			dataLen++
+		}
+		block := make([]byte, 0, shortBlockLen+1)
+		block = append(block, data[k:k+dataLen]...)
+		k += dataLen
+
+		ecc := rsRemainder(block, generator)
+		if i < numShortBlocks {
+			// Short blocks get a placeholder so every block has the same
+			// length during interleaving.
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i <= shortBlockLen; i++ {
+		for j, block := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// bitBuffer is an append-only sequence of bits, most significant first.
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>uint(i))&1 != 0)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b.bits)+7)/8)
+	for i, bit := range b.bits {
+		if bit {
+			result[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return result
+}
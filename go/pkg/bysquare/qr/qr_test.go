@@ -0,0 +1,263 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeAlphanumericCodewords(t *testing.T) {
+	// Reference values for "HELLO WORLD" at 1-M.
+	data := encodeAlphanumeric("HELLO WORLD", 1, LevelM)
+	expected := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("data codewords: got %v, want %v", data, expected)
+	}
+
+	ecc := rsRemainder(data, rsGenerator(10))
+	expectedECC := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if !bytes.Equal(ecc, expectedECC) {
+		t.Errorf("error correction codewords: got %v, want %v", ecc, expectedECC)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	testCases := []struct {
+		level    Level
+		mask     int
+		expected uint
+	}{
+		{LevelM, 0, 0b101010000010010},
+		{LevelL, 4, 0b110011000101111},
+		{LevelQ, 7, 0b010101111101101},
+		{LevelH, 2, 0b001110011100111},
+	}
+
+	for _, tc := range testCases {
+		got := formatBits(tc.level, tc.mask)
+		if got != tc.expected {
+			t.Errorf("formatBits(%s, %d): got %015b, want %015b", tc.level, tc.mask, got, tc.expected)
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	testCases := map[int]uint{
+		7:  0x07C94,
+		21: 0x15683,
+		40: 0x28C69,
+	}
+
+	for version, expected := range testCases {
+		if got := versionBits(version); got != expected {
+			t.Errorf("versionBits(%d): got %#x, want %#x", version, got, expected)
+		}
+	}
+}
+
+func TestAlignmentPatternPositions(t *testing.T) {
+	testCases := map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	}
+
+	for version, expected := range testCases {
+		if got := alignmentPatternPositions(version); !reflect.DeepEqual(got, expected) {
+			t.Errorf("alignmentPatternPositions(%d): got %v, want %v", version, got, expected)
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	testCases := []struct {
+		version  int
+		level    Level
+		expected int
+	}{
+		{1, LevelL, 25},
+		{1, LevelM, 20},
+		{1, LevelH, 10},
+		{10, LevelM, 311},
+		{40, LevelL, 4296},
+		{40, LevelH, 1852},
+	}
+
+	for _, tc := range testCases {
+		if got := Capacity(tc.version, tc.level); got != tc.expected {
+			t.Errorf("Capacity(%d, %s): got %d, want %d", tc.version, tc.level, got, tc.expected)
+		}
+	}
+}
+
+func TestMinVersionFor(t *testing.T) {
+	testCases := []struct {
+		length   int
+		level    Level
+		expected int
+	}{
+		{0, LevelL, 1},
+		{25, LevelL, 1},
+		{26, LevelL, 2},
+		{311, LevelM, 10},
+		{312, LevelM, 11},
+		{4296, LevelL, 40},
+		{4297, LevelL, 0},
+	}
+
+	for _, tc := range testCases {
+		if got := MinVersionFor(tc.length, tc.level); got != tc.expected {
+			t.Errorf("MinVersionFor(%d, %s): got %d, want %d", tc.length, tc.level, got, tc.expected)
+		}
+	}
+}
+
+func TestEncodeVersionSelection(t *testing.T) {
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for version := 1; version <= MaxVersion; version++ {
+			n := Capacity(version, level)
+
+			code, err := Encode(strings.Repeat("A", n), EncodeOptions{Level: level})
+			if err != nil {
+				t.Fatalf("Encode(%d chars, %s): %v", n, level, err)
+			}
+			if code.Version != version {
+				t.Errorf("Encode(%d chars, %s): got version %d, want %d", n, level, code.Version, version)
+			}
+			if code.Size != version*4+17 {
+				t.Errorf("version %d: got size %d", version, code.Size)
+			}
+		}
+	}
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	code, err := Encode("0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	// Finder pattern centers and their light separators.
+	corners := [][2]int{{3, 3}, {code.Size - 4, 3}, {3, code.Size - 4}}
+	for _, c := range corners {
+		if !code.Black(c[0], c[1]) {
+			t.Errorf("finder center (%d, %d) is light", c[0], c[1])
+		}
+		if code.Black(c[0]+2, c[1]) {
+			t.Errorf("finder ring (%d, %d) is dark", c[0]+2, c[1])
+		}
+	}
+
+	// Timing patterns alternate, starting dark.
+	for i := 8; i < code.Size-8; i++ {
+		if code.Black(i, 6) != (i%2 == 0) {
+			t.Errorf("horizontal timing at %d is wrong", i)
+		}
+		if code.Black(6, i) != (i%2 == 0) {
+			t.Errorf("vertical timing at %d is wrong", i)
+		}
+	}
+
+	if !code.Black(8, code.Size-8) {
+		t.Error("dark module is light")
+	}
+
+	// Format information is readable from the first copy.
+	var bits uint
+	for i := 0; i <= 5; i++ {
+		if code.Black(8, i) {
+			bits |= 1 << uint(i)
+		}
+	}
+	if code.Black(8, 7) {
+		bits |= 1 << 6
+	}
+	if code.Black(8, 8) {
+		bits |= 1 << 7
+	}
+	if code.Black(7, 8) {
+		bits |= 1 << 8
+	}
+	for i := 9; i < 15; i++ {
+		if code.Black(14-i, 8) {
+			bits |= 1 << uint(i)
+		}
+	}
+	if bits != formatBits(code.Level, code.Mask) {
+		t.Errorf("format bits: got %015b, want %015b", bits, formatBits(code.Level, code.Mask))
+	}
+}
+
+func TestEncodeLowercase(t *testing.T) {
+	upper, err := Encode("0804Q000AEM958")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	lower, err := Encode("0804q000aem958")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	if !reflect.DeepEqual(upper, lower) {
+		t.Error("lowercase input produced a different symbol")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		text  string
+		opts  EncodeOptions
+		isErr error
+	}{
+		{
+			name:  "invalid character",
+			text:  "ABC\tDEF",
+			opts:  DefaultEncodeOptions(),
+			isErr: ErrInvalidCharacter,
+		},
+		{
+			name:  "too long for max version",
+			text:  strings.Repeat("A", Capacity(2, LevelM)+1),
+			opts:  EncodeOptions{Level: LevelM, MaxVersion: 2},
+			isErr: ErrDataTooLong,
+		},
+		{
+			name:  "too long for any version",
+			text:  strings.Repeat("A", Capacity(40, LevelL)+1),
+			opts:  EncodeOptions{Level: LevelL},
+			isErr: ErrDataTooLong,
+		},
+		{
+			name: "invalid version range",
+			text: "A",
+			opts: EncodeOptions{MinVersion: 10, MaxVersion: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Encode(tc.text, tc.opts)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if tc.isErr != nil && !errors.Is(err, tc.isErr) {
+				t.Errorf("expected %v, got %v", tc.isErr, err)
+			}
+		})
+	}
+}
+
+func TestEncodeMinVersion(t *testing.T) {
+	code, err := Encode("A", EncodeOptions{Level: LevelM, MinVersion: 7})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if code.Version != 7 {
+		t.Errorf("expected version 7, got %d", code.Version)
+	}
+}
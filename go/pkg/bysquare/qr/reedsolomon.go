@@ -0,0 +1,209 @@
+package qr
+
+// QR codes use Reed-Solomon codes over GF(2^8) with the primitive
+// polynomial x^8 + x^4 + x^3 + x^2 + 1 (0x11D) and generator element 2.
+//
+// @see ISO/IEC 18004:2015, 7.5.2.
+
+const gfPrimitive = 0x11D
+
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= gfPrimitive
+		}
+	}
+	// Duplicate the table so that gfExp[a+b] never needs a modulo.
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+// gfMul multiplies two field elements.
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// rsGenerator returns the generator polynomial of the given degree, with
+// roots 2^0 .. 2^(degree-1). Coefficients are stored from highest to lowest
+// power, omitting the leading 1.
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMul(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data, i.e. the
+// remainder of data(x) * x^len(generator) divided by the generator.
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range generator {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfInverse returns the multiplicative inverse of a non-zero element.
+func gfInverse(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfPow returns 2^n for any integer n.
+func gfPow(n int) byte {
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
+	return gfExp[n]
+}
+
+// polyEval evaluates a polynomial with coefficients stored from lowest to
+// highest power at x.
+func polyEval(poly []byte, x byte) byte {
+	var result byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ poly[i]
+	}
+	return result
+}
+
+// rsCorrect corrects up to len(ecc)/2 byte errors in place. The block holds
+// the data codewords followed by numECC error correction codewords, with
+// block[0] as the highest-power coefficient. It returns the number of
+// corrected errors.
+//
+// Syndromes are computed for roots 2^0 .. 2^(numECC-1), the error locator
+// is found with Berlekamp-Massey, error positions with a Chien search, and
+// error values with the Forney algorithm.
+func rsCorrect(block []byte, numECC int) (int, error) {
+	n := len(block)
+
+	// Syndromes S_j = r(2^j), stored from lowest to highest index.
+	syndromes := make([]byte, numECC)
+	clean := true
+	for j := 0; j < numECC; j++ {
+		var s byte
+		x := gfPow(j)
+		for _, b := range block {
+			s = gfMul(s, x) ^ b
+		}
+		syndromes[j] = s
+		if s != 0 {
+			clean = false
+		}
+	}
+	if clean {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey; polynomials are stored from lowest to highest power.
+	locator := []byte{1}
+	prev := []byte{1}
+	errCount := 0
+	shift := 1
+	prevDiscrepancy := byte(1)
+	for k := 0; k < numECC; k++ {
+		discrepancy := syndromes[k]
+		for i := 1; i <= errCount && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], syndromes[k-i])
+		}
+
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		scale := gfMul(discrepancy, gfInverse(prevDiscrepancy))
+		next := make([]byte, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		for i, c := range prev {
+			next[i+shift] ^= gfMul(scale, c)
+		}
+
+		if 2*errCount <= k {
+			prev = locator
+			errCount = k + 1 - errCount
+			prevDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+
+	for len(locator) > 1 && locator[len(locator)-1] == 0 {
+		locator = locator[:len(locator)-1]
+	}
+	if len(locator)-1 != errCount || 2*errCount > numECC {
+		return 0, ErrTooManyErrors
+	}
+
+	// Chien search: an error at power p makes locator(2^-p) vanish.
+	positions := make([]int, 0, errCount)
+	for p := 0; p < n; p++ {
+		if polyEval(locator, gfPow(-p)) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	if len(positions) != errCount {
+		return 0, ErrTooManyErrors
+	}
+
+	// Error evaluator omega(x) = S(x) * locator(x) mod x^numECC.
+	omega := make([]byte, numECC)
+	for i, s := range syndromes {
+		for j, l := range locator {
+			if i+j < numECC {
+				omega[i+j] ^= gfMul(s, l)
+			}
+		}
+	}
+
+	// Formal derivative of the locator keeps the odd-power terms.
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	for _, p := range positions {
+		xInv := gfPow(-p)
+		denominator := polyEval(derivative, xInv)
+		if denominator == 0 {
+			return 0, ErrTooManyErrors
+		}
+		// With a first consecutive root of 2^0 the Forney value is scaled
+		// by X = 2^p.
+		magnitude := gfMul(gfPow(p), gfMul(polyEval(omega, xInv), gfInverse(denominator)))
+		block[n-1-p] ^= magnitude
+	}
+
+	return errCount, nil
+}
@@ -0,0 +1,146 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// QuietZone is the width of the light border around the symbol, in
+// modules, added by all renderers.
+//
+// @see ISO/IEC 18004:2015, 6.3.8.
+const QuietZone = 4
+
+var palette = color.Palette{color.White, color.Black}
+
+// Image renders the symbol with scale pixels per module, including the
+// quiet zone. A scale below 1 is treated as 1.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+
+	side := (c.Size + 2*QuietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), palette)
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			px := (x + QuietZone) * scale
+			py := (y + QuietZone) * scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(py+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[px+dx] = 1
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// PNG renders the symbol as a two-color PNG with scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the symbol as a standalone SVG document. The drawing uses
+// one unit per module, and scale sets the width and height attributes in
+// pixels.
+func (c *Code) SVG(scale int) string {
+	if scale < 1 {
+		scale = 1
+	}
+
+	side := c.Size + 2*QuietZone
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`,
+		side, side, side*scale, side*scale)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/>`, side, side)
+	sb.WriteString(`<path fill="#000" d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			// Merge horizontal runs into a single rectangle.
+			run := 1
+			for c.Black(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&sb, "M%d %dh%dv1h-%dz", x+QuietZone, y+QuietZone, run, run)
+			x += run - 1
+		}
+	}
+	sb.WriteString(`"/></svg>`)
+
+	return sb.String()
+}
+
+// String renders the symbol with Unicode half-block characters, two module
+// rows per text line. Dark modules are drawn as ink, so the output reads
+// correctly on a light terminal background.
+func (c *Code) String() string {
+	return c.text(func(top, bottom bool) string {
+		switch {
+		case top && bottom:
+			return "█"
+		case top:
+			return "▀"
+		case bottom:
+			return "▄"
+		default:
+			return " "
+		}
+	})
+}
+
+// ANSI renders the symbol with Unicode half-block characters and explicit
+// ANSI foreground and background colors, so it scans correctly regardless
+// of the terminal color scheme.
+func (c *Code) ANSI() string {
+	const (
+		fgBlack = "\x1b[30m"
+		fgWhite = "\x1b[97m"
+		bgBlack = "\x1b[40m"
+		bgWhite = "\x1b[107m"
+		reset   = "\x1b[0m"
+	)
+
+	return c.text(func(top, bottom bool) string {
+		fg, bg := fgWhite, bgWhite
+		if top {
+			fg = fgBlack
+		}
+		if bottom {
+			bg = bgBlack
+		}
+		return fg + bg + "▀" + reset
+	})
+}
+
+// text renders the symbol and quiet zone as lines of cells, each cell
+// covering one column of two module rows.
+func (c *Code) text(cell func(top, bottom bool) string) string {
+	var sb strings.Builder
+	for y := -QuietZone; y < c.Size+QuietZone; y += 2 {
+		for x := -QuietZone; x < c.Size+QuietZone; x++ {
+			sb.WriteString(cell(c.Black(x, y), c.Black(x, y+1)))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testQR = "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5K2BATU8DV6PA0G2P9U05QCF640MRVMTLLI3OJ8CEGOUEP5GR3LIJ4C0A8ERUI3JHM3VTNG00"
+
+func TestImage(t *testing.T) {
+	code, err := Encode(testQR)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	scale := 3
+	img := code.Image(scale)
+
+	side := (code.Size + 2*QuietZone) * scale
+	if img.Bounds().Dx() != side || img.Bounds().Dy() != side {
+		t.Fatalf("expected %dx%d image, got %v", side, side, img.Bounds())
+	}
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			r, _, _, _ := img.At((x+QuietZone)*scale+1, (y+QuietZone)*scale+1).RGBA()
+			if (r == 0) != code.Black(x, y) {
+				t.Fatalf("pixel for module (%d, %d) does not match", x, y)
+			}
+		}
+	}
+
+	r, _, _, _ := img.At(0, 0).RGBA()
+	if r == 0 {
+		t.Error("quiet zone is not light")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode(testQR)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	data, err := code.PNG(4)
+	if err != nil {
+		t.Fatalf("PNG() error: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error: %v", err)
+	}
+
+	side := (code.Size + 2*QuietZone) * 4
+	if img.Bounds().Dx() != side {
+		t.Errorf("expected width %d, got %d", side, img.Bounds().Dx())
+	}
+}
+
+func TestSVG(t *testing.T) {
+	code, err := Encode(testQR)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	svg := code.SVG(5)
+
+	if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("unexpected SVG framing: %.40s...", svg)
+	}
+
+	side := code.Size + 2*QuietZone
+	viewBox := `viewBox="0 0 ` + strconv.Itoa(side) + " " + strconv.Itoa(side) + `"`
+	if !strings.Contains(svg, viewBox) {
+		t.Errorf("SVG missing %s", viewBox)
+	}
+
+	// Each path segment draws one horizontal run of dark modules.
+	width := 0
+	for _, seg := range strings.Split(svg, "M")[1:] {
+		var x, y, run int
+		if _, err := fmt.Sscanf(seg, "%d %dh%d", &x, &y, &run); err != nil {
+			t.Fatalf("malformed path segment %q: %v", seg, err)
+		}
+		width += run
+	}
+
+	dark := 0
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				dark++
+			}
+		}
+	}
+	if width != dark {
+		t.Errorf("SVG covers %d modules, symbol has %d dark modules", width, dark)
+	}
+}
+
+func TestString(t *testing.T) {
+	code, err := Encode("HELLO")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(code.String(), "\n"), "\n")
+
+	side := code.Size + 2*QuietZone
+	if len(lines) != (side+1)/2 {
+		t.Fatalf("expected %d lines, got %d", (side+1)/2, len(lines))
+	}
+	for i, line := range lines {
+		if n := len([]rune(line)); n != side {
+			t.Errorf("line %d: expected %d cells, got %d", i, side, n)
+		}
+	}
+
+	// The top-left finder starts on row QuietZone (even), so its top edge
+	// is a full block.
+	row := []rune(lines[QuietZone/2])
+	if row[QuietZone] != '█' {
+		t.Errorf("expected full block at finder corner, got %q", row[QuietZone])
+	}
+}
+
+func TestANSI(t *testing.T) {
+	code, err := Encode("HELLO")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	out := code.ANSI()
+	side := code.Size + 2*QuietZone
+	if n := strings.Count(out, "▀"); n != side*((side+1)/2) {
+		t.Errorf("expected %d cells, got %d", side*((side+1)/2), n)
+	}
+	if !strings.Contains(out, "\x1b[30m\x1b[40m") {
+		t.Error("expected dark-on-dark cell for finder pattern")
+	}
+}
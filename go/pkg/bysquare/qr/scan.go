@@ -0,0 +1,99 @@
+package qr
+
+import (
+	"image"
+)
+
+// Scan locates a QR code in an image and returns the text it encodes.
+//
+// The image is binarized with a global threshold first, which suits PDF
+// renders and flatbed scans, and then with a local threshold, which copes
+// with shadows and uneven lighting in photos. Finder patterns are matched
+// into candidate symbols, each sampled through a perspective transform and
+// decoded with Reed-Solomon error correction. The first symbol that decodes
+// wins.
+//
+// Numeric, alphanumeric and byte mode segments are supported, which covers
+// every PAY by square and Invoice by square code. ErrNotFound is returned
+// when no symbol could be decoded.
+func Scan(img image.Image) (string, error) {
+	lum, width, height := luminance(img)
+	if width == 0 || height == 0 {
+		return "", ErrNotFound
+	}
+
+	binarizers := []func([]uint8, int, int) *bitMatrix{
+		binarizeGlobal,
+		binarizeLocal,
+	}
+
+	for _, binarize := range binarizers {
+		m := binarize(lum, width, height)
+		candidates := findFinderCandidates(m)
+		for _, t := range selectFinderTriples(candidates) {
+			if text, ok := decodeTriple(m, t); ok {
+				return text, nil
+			}
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// decodeTriple samples and decodes the symbol described by a finder triple.
+// When the estimated dimension fails, the neighbouring versions are tried,
+// since perspective and blur skew the finder distances.
+func decodeTriple(m *bitMatrix, t finderTriple) (string, bool) {
+	moduleSize := (t.topLeft.moduleSize + t.topRight.moduleSize + t.bottomLeft.moduleSize) / 3
+	estimate := estimateDimension(t, moduleSize)
+
+	for _, delta := range []int{0, 4, -4} {
+		dimension := estimate + delta
+		version := (dimension - 17) / 4
+		if version < MinVersion || version > MaxVersion {
+			continue
+		}
+		if text, ok := decodeDimension(m, t, dimension, moduleSize); ok {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+// decodeDimension decodes the symbol for one assumed dimension. Version 7
+// and up carry version information, which takes precedence over the
+// estimate and triggers a resample when they disagree.
+func decodeDimension(m *bitMatrix, t finderTriple, dimension int, moduleSize float64) (string, bool) {
+	version := (dimension - 17) / 4
+
+	var alignment *point
+	if version >= 2 {
+		if p, ok := findAlignment(m, t, dimension, moduleSize); ok {
+			alignment = &p
+		}
+	}
+	modules := sampleGrid(m, t, dimension, alignment)
+
+	if version >= 7 {
+		read, ok := readVersion(modules, dimension)
+		if !ok {
+			return "", false
+		}
+		if read != version {
+			version = read
+			dimension = symbolSize(version)
+			alignment = nil
+			if p, ok := findAlignment(m, t, dimension, moduleSize); ok {
+				alignment = &p
+			}
+			modules = sampleGrid(m, t, dimension, alignment)
+		}
+	}
+
+	text, err := decodeGrid(modules, version)
+	if err != nil {
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,248 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"strings"
+	"testing"
+)
+
+// payQR is a PAY by square string produced by the pay package.
+const payQR = "0804Q000AEM958SPQK31JJFA00H0OBFGMH6PKV0OQSNQPQK5KCH0BB12EJI6C2NFLCHS43I7E8NVVNCAMCF3GSRUMS4EK680FG7L2H6H9UDVLMR955998RVVVBUV000"
+
+func mustEncode(t *testing.T, text string, opts EncodeOptions) *Code {
+	t.Helper()
+	code, err := Encode(text, opts)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return code
+}
+
+func TestScanRoundTrip(t *testing.T) {
+	long := strings.Repeat("0123456789ABCDEFGHIJKLMNOPQRSTUV", 20)
+
+	testCases := []struct {
+		name  string
+		text  string
+		level Level
+		scale int
+	}{
+		{"version 1", "HELLO WORLD", LevelM, 4},
+		{"pay by square", payQR, LevelM, 3},
+		{"level L", payQR, LevelL, 2},
+		{"level H", payQR, LevelH, 5},
+		{"version 7 and up", long, LevelQ, 3},
+		{"one pixel per module", payQR, LevelM, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := DefaultEncodeOptions()
+			opts.Level = tc.level
+			code := mustEncode(t, tc.text, opts)
+
+			got, err := Scan(code.Image(tc.scale))
+			if err != nil {
+				t.Fatalf("Scan (version %d): %v", code.Version, err)
+			}
+			if got != tc.text {
+				t.Errorf("expected %q, got %q", tc.text, got)
+			}
+		})
+	}
+}
+
+func TestScanPlacement(t *testing.T) {
+	code := mustEncode(t, payQR, DefaultEncodeOptions())
+	src := code.Image(4)
+
+	t.Run("offset on larger canvas", func(t *testing.T) {
+		b := src.Bounds()
+		canvas := image.NewRGBA(image.Rect(0, 0, b.Dx()+300, b.Dy()+200))
+		draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(canvas, b.Add(image.Pt(170, 90)), src, image.Point{}, draw.Src)
+		assertScan(t, canvas, payQR)
+	})
+
+	t.Run("rotated 90 degrees", func(t *testing.T) {
+		assertScan(t, rotate(src, math.Pi/2), payQR)
+	})
+
+	t.Run("rotated 180 degrees", func(t *testing.T) {
+		assertScan(t, rotate(src, math.Pi), payQR)
+	})
+
+	t.Run("rotated 10 degrees", func(t *testing.T) {
+		assertScan(t, rotate(src, 10*math.Pi/180), payQR)
+	})
+
+	t.Run("jpeg", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 60}); err != nil {
+			t.Fatal(err)
+		}
+		img, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertScan(t, img, payQR)
+	})
+
+	t.Run("uneven lighting", func(t *testing.T) {
+		b := src.Bounds()
+		shaded := image.NewGray(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				gray := color.GrayModel.Convert(src.At(x, y)).(color.Gray)
+				// Darken towards the right edge, as under a side light.
+				shade := 1 - 0.6*float64(x)/float64(b.Dx())
+				shaded.SetGray(x, y, color.Gray{Y: uint8(float64(gray.Y)*0.85*shade + 20)})
+			}
+		}
+		assertScan(t, shaded, payQR)
+	})
+}
+
+func TestScanCorrectsDamage(t *testing.T) {
+	opts := DefaultEncodeOptions()
+	opts.Level = LevelH
+	code := mustEncode(t, payQR, opts)
+
+	// Flip a band of data modules away from the function patterns.
+	for y := 12; y < 16; y++ {
+		for x := 12; x < 20; x++ {
+			i := y*code.Size + x
+			code.modules[i] = !code.modules[i]
+		}
+	}
+
+	assertScan(t, code.Image(4), payQR)
+}
+
+func TestScanNotFound(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 200, 200))
+	draw.Draw(blank, blank.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if _, err := Scan(blank); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := Scan(image.NewGray(image.Rect(0, 0, 0, 0))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for empty image, got %v", err)
+	}
+}
+
+func TestRSCorrect(t *testing.T) {
+	data := encodeAlphanumeric("HELLO WORLD", 1, LevelM)
+	block := append(append([]byte{}, data...), rsRemainder(data, rsGenerator(10))...)
+
+	t.Run("no errors", func(t *testing.T) {
+		b := append([]byte{}, block...)
+		n, err := rsCorrect(b, 10)
+		if err != nil || n != 0 {
+			t.Fatalf("expected 0 corrections, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("up to capacity", func(t *testing.T) {
+		b := append([]byte{}, block...)
+		for _, i := range []int{0, 3, 7, 15, 20} {
+			b[i] ^= byte(0x5A + i)
+		}
+		n, err := rsCorrect(b, 10)
+		if err != nil {
+			t.Fatalf("rsCorrect: %v", err)
+		}
+		if n != 5 {
+			t.Errorf("expected 5 corrections, got %d", n)
+		}
+		if !bytes.Equal(b, block) {
+			t.Errorf("expected %v, got %v", block, b)
+		}
+	})
+
+	t.Run("beyond capacity", func(t *testing.T) {
+		b := append([]byte{}, block...)
+		for i := 0; i < 8; i++ {
+			b[i*3] ^= 0xFF
+		}
+		if _, err := rsCorrect(b, 10); !errors.Is(err, ErrTooManyErrors) {
+			t.Errorf("expected ErrTooManyErrors, got %v", err)
+		}
+	})
+}
+
+func TestParseSegments(t *testing.T) {
+	var b bitBuffer
+	// ECI designator 26 (UTF-8), then a byte segment.
+	b.append(0b0111, 4)
+	b.append(26, 8)
+	b.append(uint(modeByte), 4)
+	b.append(3, 8)
+	for _, c := range []byte("čo") {
+		b.append(uint(c), 8)
+	}
+	// Numeric segment "0123".
+	b.append(uint(modeNumeric), 4)
+	b.append(4, 10)
+	b.append(12, 10)
+	b.append(3, 4)
+	b.append(uint(modeTerminator), 4)
+
+	got, err := parseSegments(b.bytes(), 1)
+	if err != nil {
+		t.Fatalf("parseSegments: %v", err)
+	}
+	if got != "čo0123" {
+		t.Errorf("expected %q, got %q", "čo0123", got)
+	}
+
+	var kanji bitBuffer
+	kanji.append(uint(modeKanji), 4)
+	kanji.append(1, 8)
+	if _, err := parseSegments(kanji.bytes(), 1); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("expected ErrUnsupportedMode, got %v", err)
+	}
+}
+
+func assertScan(t *testing.T, img image.Image, expected string) {
+	t.Helper()
+	got, err := Scan(img)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+// rotate returns img rotated by angle radians about its center on a white
+// canvas large enough to hold it, using nearest-neighbour sampling.
+func rotate(img image.Image, angle float64) image.Image {
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	side := int(math.Ceil(math.Hypot(w, h)))
+	out := image.NewGray(image.Rect(0, 0, side, side))
+
+	sin, cos := math.Sincos(angle)
+	cx, cy := float64(side)/2, float64(side)/2
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			sx := cos*dx + sin*dy + w/2
+			sy := -sin*dx + cos*dy + h/2
+			px, py := int(math.Floor(sx)), int(math.Floor(sy))
+			v := color.Gray{Y: 255}
+			if px >= 0 && py >= 0 && px < b.Dx() && py < b.Dy() {
+				v = color.GrayModel.Convert(img.At(b.Min.X+px, b.Min.Y+py)).(color.Gray)
+			}
+			out.SetGray(x, y, v)
+		}
+	}
+	return out
+}
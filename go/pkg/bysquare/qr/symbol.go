@@ -0,0 +1,326 @@
+package qr
+
+// Penalty weights for mask evaluation.
+//
+// @see ISO/IEC 18004:2015, 7.8.3.
+const (
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
+
+// build draws function patterns, places codewords, and applies the mask
+// with the lowest penalty score.
+func (c *Code) build(codewords []byte) {
+	size := c.Size
+	c.modules = make([]bool, size*size)
+	function := make([]bool, size*size)
+
+	set := func(x, y int, dark bool) {
+		c.modules[y*size+x] = dark
+		function[y*size+x] = true
+	}
+
+	drawFunctionPatterns(c.Version, size, set)
+	// Reserve format information areas; the real bits are drawn per mask.
+	drawFormatBits(0, size, set)
+
+	placeCodewords(codewords, size, c.modules, function)
+
+	bestMask, bestPenalty := 0, -1
+	for m := 0; m < 8; m++ {
+		applyMask(m, size, c.modules, function)
+		drawFormatBits(formatBits(c.Level, m), size, set)
+		penalty := penaltyScore(size, c.modules)
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = m, penalty
+		}
+		// Masking is an XOR, so applying it again undoes it.
+		applyMask(m, size, c.modules, function)
+	}
+
+	c.Mask = bestMask
+	applyMask(bestMask, size, c.modules, function)
+	drawFormatBits(formatBits(c.Level, bestMask), size, set)
+}
+
+// drawFunctionPatterns draws finder, separator, timing, alignment and
+// version information patterns.
+//
+// @see ISO/IEC 18004:2015, 6.3.
+func drawFunctionPatterns(version, size int, set func(x, y int, dark bool)) {
+	for i := 0; i < size; i++ {
+		set(6, i, i%2 == 0)
+		set(i, 6, i%2 == 0)
+	}
+
+	drawFinder := func(cx, cy int) {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := cx+dx, cy+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+	drawFinder(3, 3)
+	drawFinder(size-4, 3)
+	drawFinder(3, size-4)
+
+	positions := alignmentPatternPositions(version)
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			// Skip the three corners occupied by finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	if version >= 7 {
+		bits := versionBits(version)
+		for i := 0; i < 18; i++ {
+			dark := (bits>>uint(i))&1 != 0
+			a, b := size-11+i%3, i/3
+			set(a, b, dark)
+			set(b, a, dark)
+		}
+	}
+}
+
+// formatBits returns the 15-bit BCH-protected format information for a
+// level and mask, already XORed with the fixed mask pattern.
+//
+// @see ISO/IEC 18004:2015, 7.9.
+func formatBits(level Level, mask int) uint {
+	data := formatLevelBits[level]<<3 | uint(mask)
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the 18-bit BCH-protected version information.
+//
+// @see ISO/IEC 18004:2015, 7.10.
+func versionBits(version int) uint {
+	rem := uint(version)
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return uint(version)<<12 | rem
+}
+
+// drawFormatBits draws both copies of the format information and the
+// always-dark module.
+func drawFormatBits(bits uint, size int, set func(x, y int, dark bool)) {
+	bit := func(i int) bool {
+		return (bits>>uint(i))&1 != 0
+	}
+
+	// Copy around the top-left finder.
+	for i := 0; i <= 5; i++ {
+		set(8, i, bit(i))
+	}
+	set(8, 7, bit(6))
+	set(8, 8, bit(7))
+	set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		set(14-i, 8, bit(i))
+	}
+
+	// Copy split between the other two finders.
+	for i := 0; i < 8; i++ {
+		set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		set(8, size-15+i, bit(i))
+	}
+	set(8, size-8, true)
+}
+
+// placeCodewords writes codeword bits in the two-column zigzag order,
+// skipping function modules. Remainder modules stay light.
+//
+// @see ISO/IEC 18004:2015, 7.7.3.
+func placeCodewords(codewords []byte, size int, modules, function []bool) {
+	i := 0
+	total := len(codewords) * 8
+	forEachDataModule(size, function, func(x, y int) {
+		if i >= total {
+			return
+		}
+		modules[y*size+x] = (codewords[i/8]>>uint(7-i%8))&1 != 0
+		i++
+	})
+}
+
+// forEachDataModule visits every non-function module in codeword placement
+// order: two-column strips from right to left, alternating upwards and
+// downwards, skipping the vertical timing pattern.
+func forEachDataModule(size int, function []bool, fn func(x, y int)) {
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern.
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if !function[y*size+x] {
+					fn(x, y)
+				}
+			}
+		}
+	}
+}
+
+// maskBit reports whether data mask pattern m inverts the module at (x, y).
+//
+// @see ISO/IEC 18004:2015, Table 10.
+func maskBit(m, x, y int) bool {
+	switch m {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask XORs the data mask pattern m onto all non-function modules.
+func applyMask(m, size int, modules, function []bool) {
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			i := y*size + x
+			if !function[i] && maskBit(m, x, y) {
+				modules[i] = !modules[i]
+			}
+		}
+	}
+}
+
+// penaltyScore evaluates a masked symbol; lower is better.
+//
+// @see ISO/IEC 18004:2015, 7.8.3.
+func penaltyScore(size int, modules []bool) int {
+	at := func(x, y int) bool {
+		return modules[y*size+x]
+	}
+
+	penalty := 0
+
+	// N1: runs of five or more same-colored modules in a row or column.
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < size; a++ {
+			run := 0
+			var prev bool
+			for b := 0; b < size; b++ {
+				x, y := b, a
+				if !horizontal {
+					x, y = a, b
+				}
+				v := at(x, y)
+				if b > 0 && v == prev {
+					run++
+				} else {
+					if run >= 5 {
+						penalty += penaltyN1 + run - 5
+					}
+					run = 1
+					prev = v
+				}
+			}
+			if run >= 5 {
+				penalty += penaltyN1 + run - 5
+			}
+		}
+	}
+
+	// N2: 2x2 blocks of the same color.
+	for y := 0; y < size-1; y++ {
+		for x := 0; x < size-1; x++ {
+			v := at(x, y)
+			if v == at(x+1, y) && v == at(x, y+1) && v == at(x+1, y+1) {
+				penalty += penaltyN2
+			}
+		}
+	}
+
+	// N3: 1:1:3:1:1 finder-like patterns with four light modules on either
+	// side.
+	finderLike := [11]bool{true, false, true, true, true, false, true, false, false, false, false}
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < size; a++ {
+			for b := 0; b+11 <= size; b++ {
+				forward, backward := true, true
+				for k := 0; k < 11 && (forward || backward); k++ {
+					x, y := b+k, a
+					if !horizontal {
+						x, y = a, b+k
+					}
+					v := at(x, y)
+					if v != finderLike[k] {
+						forward = false
+					}
+					if v != finderLike[10-k] {
+						backward = false
+					}
+				}
+				if forward {
+					penalty += penaltyN3
+				}
+				if backward {
+					penalty += penaltyN3
+				}
+			}
+		}
+	}
+
+	// N4: deviation of the dark module ratio from 50% in 5% steps.
+	dark := 0
+	for _, m := range modules {
+		if m {
+			dark++
+		}
+	}
+	total := size * size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	penalty += k * penaltyN4
+
+	return penalty
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
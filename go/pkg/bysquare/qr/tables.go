@@ -0,0 +1,121 @@
+package qr
+
+// eccCodewordsPerBlock lists the number of error correction codewords in
+// each block, indexed by [Level][Version]. Index 0 is unused.
+//
+// @see ISO/IEC 18004:2015, Table 9.
+var eccCodewordsPerBlock = [4][41]int{
+	// L
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	// M
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	// Q
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	// H
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// numErrorCorrectionBlocks lists the number of error correction blocks,
+// indexed by [Level][Version]. Index 0 is unused.
+//
+// @see ISO/IEC 18004:2015, Table 9.
+var numErrorCorrectionBlocks = [4][41]int{
+	// L
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	// M
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	// Q
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	// H
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// formatLevelBits maps a Level to the 2-bit indicator stored in the format
+// information. The indicator order (M, L, H, Q) differs from the order of
+// correction strength.
+//
+// @see ISO/IEC 18004:2015, Table 12.
+var formatLevelBits = [4]uint{
+	LevelL: 0b01,
+	LevelM: 0b00,
+	LevelQ: 0b11,
+	LevelH: 0b10,
+}
+
+// symbolSize returns the number of modules per side for a version.
+func symbolSize(version int) int {
+	return version*4 + 17
+}
+
+// numRawDataModules returns the number of modules available for data and
+// error correction codewords, after all function patterns are excluded.
+// The result includes remainder bits.
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// numDataCodewords returns the number of 8-bit data codewords available for
+// a version and error correction level.
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 -
+		eccCodewordsPerBlock[level][version]*numErrorCorrectionBlocks[level][version]
+}
+
+// alignmentPatternPositions returns the row and column centers of the
+// alignment patterns for a version, in ascending order.
+//
+// @see ISO/IEC 18004:2015, Annex E.
+func alignmentPatternPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+
+	result := make([]int, numAlign)
+	result[0] = 6
+	pos := symbolSize(version) - 7
+	for i := numAlign - 1; i >= 1; i-- {
+		result[i] = pos
+		pos -= step
+	}
+	return result
+}
+
+// charCountBits returns the width of the character count indicator for a
+// mode and version.
+//
+// @see ISO/IEC 18004:2015, Table 3.
+func charCountBits(m mode, version int) int {
+	var widths [3]int
+	switch m {
+	case modeNumeric:
+		widths = [3]int{10, 12, 14}
+	case modeAlphanumeric:
+		widths = [3]int{9, 11, 13}
+	case modeByte:
+		widths = [3]int{8, 16, 16}
+	case modeKanji:
+		widths = [3]int{8, 10, 12}
+	default:
+		return 0
+	}
+
+	switch {
+	case version <= 9:
+		return widths[0]
+	case version <= 26:
+		return widths[1]
+	default:
+		return widths[2]
+	}
+}
@@ -0,0 +1,120 @@
+package bysquare
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Codec encodes and decodes one kind of document, selected by the
+// bysquareType and documentType header nibbles. Packages register their
+// codecs from init, so auto-detection, the CLI and the FFI pick up every
+// linked document kind.
+//
+//	+--------------+--------------+-------------------+
+//	| bysquareType | documentType | Name              |
+//	+--------------+--------------+-------------------+
+//	|     0x00     |     0x00     | pay               |
+//	|     0x01     |  0x00-0x04   | invoice, ...      |
+//	+--------------+--------------+-------------------+
+//
+// @see 3.5.
+type Codec struct {
+	// Name identifies the codec on the command line and in the FFI, e.g.
+	// "pay" or "credit-note".
+	Name string
+
+	BysquareType uint8
+	DocumentType uint8
+
+	// New returns an empty document to unmarshal JSON input into.
+	New func() Document
+
+	// Encode encodes a document of the type New returns with default
+	// options.
+	Encode func(doc Document) (string, error)
+
+	// Decode decodes a QR string whose header selects this codec.
+	Decode func(qr string) (Document, error)
+
+	// Schema is the JSON Schema of the document, see JSONSchema.
+	Schema json.RawMessage
+}
+
+// EncodeJSON unmarshals data into a new document and encodes it.
+func (c Codec) EncodeJSON(data []byte) (string, error) {
+	doc := c.New()
+	if err := json.Unmarshal(data, doc); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return c.Encode(doc)
+}
+
+var registry struct {
+	sync.RWMutex
+	codecs []Codec
+}
+
+// RegisterCodec makes a codec available by header and by name. It panics
+// if a field is missing, a nibble exceeds 15, or the header pair or the
+// name is already registered.
+func RegisterCodec(c Codec) {
+	if c.Name == "" || c.New == nil || c.Encode == nil || c.Decode == nil {
+		panic("bysquare: RegisterCodec needs Name, New, Encode and Decode")
+	}
+	if c.BysquareType > 0x0F || c.DocumentType > 0x0F {
+		panic(fmt.Sprintf("bysquare: codec %q header nibbles out of range", c.Name))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, r := range registry.codecs {
+		if r.Name == c.Name {
+			panic(fmt.Sprintf("bysquare: codec %q registered twice", c.Name))
+		}
+		if r.BysquareType == c.BysquareType && r.DocumentType == c.DocumentType {
+			panic(fmt.Sprintf("bysquare: codec %q registered for the header of %q", c.Name, r.Name))
+		}
+	}
+
+	registry.codecs = append(registry.codecs, c)
+	slices.SortFunc(registry.codecs, func(a, b Codec) int {
+		return int(a.BysquareType)<<4 + int(a.DocumentType) - int(b.BysquareType)<<4 - int(b.DocumentType)
+	})
+}
+
+// LookupCodec returns the codec registered for a header.
+func LookupCodec(bysquareType, documentType uint8) (Codec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, c := range registry.codecs {
+		if c.BysquareType == bysquareType && c.DocumentType == documentType {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// CodecByName returns the codec registered under name.
+func CodecByName(name string) (Codec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, c := range registry.codecs {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// Codecs returns every registered codec ordered by header.
+func Codecs() []Codec {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return slices.Clone(registry.codecs)
+}
@@ -0,0 +1,132 @@
+package bysquare
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testDocument is a document of an experimental bysquareType.
+type testDocument struct {
+	Text string `json:"text"`
+}
+
+func (d *testDocument) BysquareType() uint8 { return 0x0E }
+
+func TestRegisterCodec(t *testing.T) {
+	codec := Codec{
+		Name:         "test-experimental",
+		BysquareType: 0x0E,
+		DocumentType: 0x03,
+		New:          func() Document { return &testDocument{} },
+		Encode: func(doc Document) (string, error) {
+			return Seal(BysquareHeader{BySquareType: 0x0E, DocumentType: 0x03}, doc.(*testDocument).Text)
+		},
+		Decode: func(qr string) (Document, error) {
+			_, payload, err := Open(qr, 0x0E)
+			if err != nil {
+				return nil, err
+			}
+			return &testDocument{Text: payload}, nil
+		},
+		Schema: JSONSchema(testDocument{}),
+	}
+	RegisterCodec(codec)
+
+	byName, ok := CodecByName("test-experimental")
+	if !ok || byName.DocumentType != 0x03 {
+		t.Fatalf("expected codec by name, got %+v, %v", byName, ok)
+	}
+
+	byHeader, ok := LookupCodec(0x0E, 0x03)
+	if !ok || byHeader.Name != "test-experimental" {
+		t.Fatalf("expected codec by header, got %+v, %v", byHeader, ok)
+	}
+
+	qr, err := byName.EncodeJSON([]byte(`{"text":"hello"}`))
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	doc, err := byHeader.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if doc.(*testDocument).Text != "hello" {
+		t.Errorf("expected %q, got %q", "hello", doc.(*testDocument).Text)
+	}
+
+	if _, err := byName.EncodeJSON([]byte(`{`)); err == nil {
+		t.Error("expected JSON error")
+	}
+
+	codecs := Codecs()
+	if codecs[len(codecs)-1].Name != "test-experimental" {
+		t.Errorf("expected codecs ordered by header, got %+v", codecs)
+	}
+
+	tests := []struct {
+		name  string
+		codec Codec
+	}{
+		{"duplicate name", codec},
+		{"duplicate header", func() Codec { c := codec; c.Name = "other"; return c }()},
+		{"nibble out of range", func() Codec { c := codec; c.Name = "other"; c.BysquareType = 0x10; return c }()},
+		{"missing decode", Codec{Name: "other", New: codec.New, Encode: codec.Encode}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			RegisterCodec(tt.codec)
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	type inner struct {
+		Count uint8 `json:"count"`
+	}
+	type embedded struct {
+		Name string `json:"name"`
+	}
+	type model struct {
+		embedded
+		Amount  Decimal  `json:"amount,omitempty"`
+		Items   []inner  `json:"items"`
+		Next    *inner   `json:"next,omitempty"`
+		Delta   int16    `json:"delta,omitempty"`
+		Ignored string   `json:"-"`
+		Tags    []string `json:"tags,omitempty"`
+	}
+
+	var schema struct {
+		Dialect    string                     `json:"$schema"`
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(JSONSchema(model{}), &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+
+	if schema.Dialect != schemaDialect || schema.Type != "object" {
+		t.Errorf("unexpected header %q %q", schema.Dialect, schema.Type)
+	}
+	if len(schema.Properties) != 6 {
+		t.Errorf("expected 6 properties, got %d", len(schema.Properties))
+	}
+	if _, ok := schema.Properties["Ignored"]; ok {
+		t.Error("expected json:\"-\" field skipped")
+	}
+	if got := string(schema.Properties["delta"]); got != `{"maximum":32767,"minimum":-32768,"type":"integer"}` {
+		t.Errorf("unexpected int16 schema %s", got)
+	}
+	if got := string(schema.Properties["items"]); got != `{"items":{"properties":{"count":{"maximum":255,"minimum":0,"type":"integer"}},"required":["count"],"type":"object"},"type":"array"}` {
+		t.Errorf("unexpected array schema %s", got)
+	}
+	if len(schema.Required) != 2 || schema.Required[0] != "name" || schema.Required[1] != "items" {
+		t.Errorf("expected required [name items], got %v", schema.Required)
+	}
+}
@@ -0,0 +1,160 @@
+package bysquare
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Repertoire is the set of characters a text field may hold. Some mobile
+// banking apps fail on emoji, control characters or letters outside
+// Latin-2, so encoders can restrict text to a repertoire they display.
+type Repertoire uint8
+
+const (
+	// RepertoireAny allows every character; it disables the check.
+	RepertoireAny Repertoire = iota
+
+	// RepertoireASCII allows printable ASCII, U+0020 to U+007E.
+	RepertoireASCII
+
+	// RepertoireLatin2 allows the printable characters of ISO 8859-2,
+	// which covers Slovak, Czech, Polish and Hungarian.
+	RepertoireLatin2
+
+	// RepertoirePrintable allows printable Unicode: letters, marks,
+	// numbers, punctuation, symbols and the ASCII space. Control, format,
+	// private use and unassigned characters are rejected; emoji are
+	// symbols and pass.
+	RepertoirePrintable
+)
+
+// latin2High holds the characters of ISO 8859-2 from 0xA0 to 0xFF.
+const latin2High = "\u00a0Ą˘Ł¤ĽŚ§¨ŠŞŤŹ\u00adŽŻ°ą˛ł´ľśˇ¸šşťź˝žż" +
+	"ŔÁÂĂÄĹĆÇČÉĘËĚÍÎĎĐŃŇÓÔŐÖ×ŘŮÚŰÜÝŢß" +
+	"ŕáâăäĺćçčéęëěíîďđńňóôőö÷řůúűüýţ˙"
+
+// Contains reports whether c belongs to the repertoire.
+func (rep Repertoire) Contains(c rune) bool {
+	switch rep {
+	case RepertoireASCII:
+		return c >= 0x20 && c <= 0x7e
+	case RepertoireLatin2:
+		return c >= 0x20 && c <= 0x7e || strings.ContainsRune(latin2High, c)
+	case RepertoirePrintable:
+		return unicode.IsPrint(c)
+	default:
+		return true
+	}
+}
+
+// String returns the name of the repertoire.
+func (rep Repertoire) String() string {
+	switch rep {
+	case RepertoireAny:
+		return "any"
+	case RepertoireASCII:
+		return "ASCII"
+	case RepertoireLatin2:
+		return "Latin-2"
+	case RepertoirePrintable:
+		return "printable Unicode"
+	default:
+		return fmt.Sprintf("Repertoire(%d)", uint8(rep))
+	}
+}
+
+// Check returns the characters of s outside the repertoire, reported under
+// path.
+func (rep Repertoire) Check(path, s string) []InvalidRune {
+	var invalid []InvalidRune
+	offset := 0
+	for _, c := range s {
+		if !rep.Contains(c) {
+			invalid = append(invalid, InvalidRune{Path: path, Offset: offset, Rune: c})
+		}
+		offset++
+	}
+	return invalid
+}
+
+// Coerce rewrites the characters of s outside the repertoire. Each run of
+// such characters is transliterated with t; characters still outside, or
+// all of them when t is nil, become '?'.
+//
+//	RepertoireASCII, ExtendedTransliterator: "Čaj 😀 ЖУК" -> "Caj ? ZHUK"
+//	RepertoireLatin2, ExtendedTransliterator: "Čaj Ș"     -> "Čaj S"
+func (rep Repertoire) Coerce(s string, t Transliterator) string {
+	runes := []rune(s)
+
+	var result strings.Builder
+	result.Grow(len(s))
+
+	for i := 0; i < len(runes); {
+		if rep.Contains(runes[i]) {
+			result.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && !rep.Contains(runes[j]) {
+			j++
+		}
+
+		run := string(runes[i:j])
+		if t != nil {
+			run = t.Transliterate(run)
+		}
+		for _, c := range run {
+			if !rep.Contains(c) {
+				c = '?'
+			}
+			result.WriteRune(c)
+		}
+		i = j
+	}
+
+	return result.String()
+}
+
+// RepertoirePolicy selects what an encoder does with characters outside
+// its repertoire.
+type RepertoirePolicy uint8
+
+const (
+	// RepertoireReject fails validation with a RepertoireError.
+	RepertoireReject RepertoirePolicy = iota
+	// RepertoireReplace replaces every such character with '?'.
+	RepertoireReplace
+	// RepertoireTransliterate transliterates such characters and replaces
+	// those still outside with '?', see Repertoire.Coerce.
+	RepertoireTransliterate
+)
+
+// InvalidRune is a character outside a Repertoire. Offset counts runes, not
+// bytes, from the start of the field named by Path.
+type InvalidRune struct {
+	Path   string `json:"path"`
+	Offset int    `json:"offset"`
+	Rune   rune   `json:"rune"`
+}
+
+// String formats r as "path@offset U+XXXX 'c'".
+func (r InvalidRune) String() string {
+	return fmt.Sprintf("%s@%d %#U", r.Path, r.Offset, r.Rune)
+}
+
+// RepertoireError lists every character of a model outside a Repertoire.
+type RepertoireError struct {
+	Repertoire Repertoire
+	Runes      []InvalidRune
+}
+
+func (e *RepertoireError) Error() string {
+	parts := make([]string, len(e.Runes))
+	for i, r := range e.Runes {
+		parts[i] = r.String()
+	}
+	return fmt.Sprintf("%d characters outside %s: %s", len(e.Runes), e.Repertoire, strings.Join(parts, ", "))
+}
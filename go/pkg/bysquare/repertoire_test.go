@@ -0,0 +1,122 @@
+package bysquare
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRepertoireContains(t *testing.T) {
+	testCases := []struct {
+		name       string
+		repertoire Repertoire
+		valid      string
+		invalid    string
+	}{
+		{
+			name:       "ASCII",
+			repertoire: RepertoireASCII,
+			valid:      "Hello, World ~ 123",
+			invalid:    "\t\n\x7fčß😀",
+		},
+		{
+			name:       "Latin-2",
+			repertoire: RepertoireLatin2,
+			valid:      "Príliš žluťoučký kůň, Żółć, Őrült ÷ ß",
+			invalid:    "\tàñøș€😀Ж",
+		},
+		{
+			name:       "printable Unicode",
+			repertoire: RepertoirePrintable,
+			valid:      "Шевченко Αθήνα 東京 ș 😀",
+			invalid:    "\t\n\u200b\ue000\u0378",
+		},
+		{
+			name:       "any",
+			repertoire: RepertoireAny,
+			valid:      "\t😀",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, c := range tc.valid {
+				if !tc.repertoire.Contains(c) {
+					t.Errorf("expected %#U in %s", c, tc.repertoire)
+				}
+			}
+			for _, c := range tc.invalid {
+				if tc.repertoire.Contains(c) {
+					t.Errorf("expected %#U outside %s", c, tc.repertoire)
+				}
+			}
+		})
+	}
+}
+
+func TestRepertoireCheck(t *testing.T) {
+	invalid := RepertoireASCII.Check("paymentNote", "Čaj 😀")
+	expected := []InvalidRune{
+		{Path: "paymentNote", Offset: 0, Rune: 'Č'},
+		{Path: "paymentNote", Offset: 4, Rune: '😀'},
+	}
+	if !slices.Equal(invalid, expected) {
+		t.Errorf("expected %v, got %v", expected, invalid)
+	}
+
+	err := &RepertoireError{Repertoire: RepertoireASCII, Runes: invalid}
+	if !strings.Contains(err.Error(), "paymentNote@4 U+1F600 '😀'") {
+		t.Errorf("expected offset and rune in message, got %q", err.Error())
+	}
+	var target *RepertoireError
+	if !errors.As(error(err), &target) {
+		t.Error("expected errors.As to match RepertoireError")
+	}
+}
+
+func TestRepertoireCoerce(t *testing.T) {
+	testCases := []struct {
+		name           string
+		repertoire     Repertoire
+		transliterator Transliterator
+		input          string
+		expected       string
+	}{
+		{
+			name:       "replace",
+			repertoire: RepertoireASCII,
+			input:      "Čaj 😀",
+			expected:   "?aj ?",
+		},
+		{
+			name:           "transliterate runs",
+			repertoire:     RepertoireASCII,
+			transliterator: ExtendedTransliterator,
+			input:          "Čaj 😀 ЖУК",
+			expected:       "Caj ? ZHUK",
+		},
+		{
+			name:           "Latin-2 keeps its letters",
+			repertoire:     RepertoireLatin2,
+			transliterator: ExtendedTransliterator,
+			input:          "Čaj Ștefan",
+			expected:       "Čaj Stefan",
+		},
+		{
+			name:       "printable drops nothing printable",
+			repertoire: RepertoirePrintable,
+			input:      "a\tb 😀",
+			expected:   "a?b 😀",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := tc.repertoire.Coerce(tc.input, tc.transliterator)
+			if result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
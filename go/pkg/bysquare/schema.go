@@ -0,0 +1,117 @@
+package bysquare
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// schemaDialect is the JSON Schema version JSONSchema produces.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// decimalType is the reflect.Type of Decimal, which marshals as a number.
+var decimalType = reflect.TypeOf(Decimal(""))
+
+// JSONSchema derives a JSON Schema from the json tags of v's type, so a
+// Codec schema stays in sync with its data model.
+//
+//	string kinds      -> "string"
+//	integer kinds     -> "integer" with the range of the Go type
+//	Decimal           -> "number" or decimal "string"
+//	slices            -> "array"
+//	structs, pointers -> "object"; fields without omitempty are required
+//
+// Embedded structs without a json tag are flattened, as encoding/json does.
+func JSONSchema(v any) json.RawMessage {
+	schema := schemaFor(reflect.TypeOf(v))
+	schema["$schema"] = schemaDialect
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		// Only strings, numbers, bools, maps and slices are marshalled.
+		panic(err)
+	}
+	return data
+}
+
+func schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == decimalType {
+		return map[string]any{
+			"type":    []string{"number", "string"},
+			"pattern": `^([+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?)?$`,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := t.Bits()
+		return map[string]any{
+			"type":    "integer",
+			"minimum": int64(-1) << (bits - 1),
+			"maximum": int64(math.MaxInt64 >> (64 - bits)),
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{
+			"type":    "integer",
+			"minimum": 0,
+			"maximum": uint64(math.MaxUint64 >> (64 - t.Bits())),
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		addFields(t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// addFields adds the exported fields of struct type t to properties.
+func addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("json")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && !hasTag {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, properties, required)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = schemaFor(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package bysquare
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:embed constant_symbols.tsv
+var constantSymbolsTSV string
+
+// Symbol selects one of the Slovak payment symbols. Banks read them as
+// numbers, so leading zeros carry no meaning:
+//
+//	+-----------------+------------+------------------------------+
+//	| Symbol          | Max digits | Canonical form               |
+//	+-----------------+------------+------------------------------+
+//	| Variable (VS)   | 10         | without leading zeros        |
+//	| Constant (KS)   | 4          | 4 digits                     |
+//	| Specific (SS)   | 10         | without leading zeros        |
+//	+-----------------+------------+------------------------------+
+type Symbol int
+
+const (
+	SymbolVariable Symbol = iota
+	SymbolConstant
+	SymbolSpecific
+)
+
+func (s Symbol) String() string {
+	switch s {
+	case SymbolVariable:
+		return "variable symbol"
+	case SymbolConstant:
+		return "constant symbol"
+	case SymbolSpecific:
+		return "specific symbol"
+	default:
+		return fmt.Sprintf("Symbol(%d)", int(s))
+	}
+}
+
+// MaxDigits returns the number of significant digits banks accept.
+func (s Symbol) MaxDigits() int {
+	if s == SymbolConstant {
+		return 4
+	}
+	return 10
+}
+
+var constantSymbols = sync.OnceValue(func() map[string]string {
+	m := make(map[string]string)
+	for _, line := range strings.Split(constantSymbolsTSV, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, description, _ := strings.Cut(line, "\t")
+		if len(code) != 4 || !isDigits(code) {
+			panic(fmt.Sprintf("constant_symbols.tsv: malformed symbol %q", code))
+		}
+		m[code] = description
+	}
+	return m
+})
+
+// LookupConstantSymbol returns the description of a constant symbol from
+// the list embedded in this package. Leading zeros may be left out, so
+// "308" finds "0308".
+func LookupConstantSymbol(code string) (string, bool) {
+	code = strings.TrimSpace(code)
+	if code == "" || len(code) > 4 || !isDigits(code) {
+		return "", false
+	}
+	description, ok := constantSymbols()[pad(code, 4)]
+	return description, ok
+}
+
+// NormalizeSymbol returns the canonical form of a payment symbol, or an
+// error wrapping ErrSymbol. Surrounding spaces are ignored and an empty
+// symbol stays empty. Constant symbols are not looked up, see
+// ValidateConstantSymbol.
+//
+// Leading zeros do not count towards the digit limit: "000000000042" is a
+// valid variable symbol and normalizes to "42".
+func NormalizeSymbol(kind Symbol, s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if !isDigits(s) {
+		return "", fmt.Errorf("%w: %s %q must be numeric", ErrSymbol, kind, s)
+	}
+
+	digits := strings.TrimLeft(s, "0")
+	if len(digits) > kind.MaxDigits() {
+		return "", fmt.Errorf("%w: %s %q has more than %d digits", ErrSymbol, kind, s, kind.MaxDigits())
+	}
+
+	if kind == SymbolConstant {
+		return pad(digits, 4), nil
+	}
+
+	if digits == "" {
+		return "0", nil
+	}
+	return digits, nil
+}
+
+// ValidateConstantSymbol checks a constant symbol against the list embedded
+// in this package. It returns an error wrapping ErrSymbol when the symbol is
+// malformed and ErrConstantSymbol when it is not listed. An empty symbol is
+// valid.
+func ValidateConstantSymbol(s string) error {
+	code, err := NormalizeSymbol(SymbolConstant, s)
+	if err != nil || code == "" {
+		return err
+	}
+	if _, ok := constantSymbols()[code]; !ok {
+		return fmt.Errorf("%w: %s", ErrConstantSymbol, code)
+	}
+	return nil
+}
+
+// VariableSymbolFromInvoiceID builds a variable symbol from an invoice
+// number by keeping its digits, so "FV-2024/0042" becomes "20240042".
+// Numbers with more than 10 digits keep the last 10, where the running
+// number is. An invoice number without digits returns an error wrapping
+// ErrSymbol.
+func VariableSymbolFromInvoiceID(id string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(id); i++ {
+		if isDigit(id[i]) {
+			sb.WriteByte(id[i])
+		}
+	}
+
+	digits := sb.String()
+	if digits == "" {
+		return "", fmt.Errorf("%w: invoice number %q has no digits", ErrSymbol, id)
+	}
+	if n := SymbolVariable.MaxDigits(); len(digits) > n {
+		digits = digits[len(digits)-n:]
+	}
+	return NormalizeSymbol(SymbolVariable, digits)
+}
@@ -0,0 +1,110 @@
+package bysquare
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		kind Symbol
+		in   string
+		want string
+		err  error
+	}{
+		{SymbolVariable, "", "", nil},
+		{SymbolVariable, "123", "123", nil},
+		{SymbolVariable, " 0042 ", "42", nil},
+		{SymbolVariable, "000000000001234567890", "1234567890", nil},
+		{SymbolVariable, "0000", "0", nil},
+		{SymbolVariable, "12345678901", "", ErrSymbol},
+		{SymbolVariable, "12A", "", ErrSymbol},
+		{SymbolVariable, "-12", "", ErrSymbol},
+		{SymbolSpecific, "9876543210", "9876543210", nil},
+		{SymbolSpecific, "98765432101", "", ErrSymbol},
+		{SymbolConstant, "0308", "0308", nil},
+		{SymbolConstant, "308", "0308", nil},
+		{SymbolConstant, "8", "0008", nil},
+		{SymbolConstant, "000558", "0558", nil},
+		{SymbolConstant, "12345", "", ErrSymbol},
+		{SymbolConstant, "138", "0138", nil},
+		{SymbolConstant, "1178", "1178", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String()+" "+tt.in, func(t *testing.T) {
+			got, err := NormalizeSymbol(tt.kind, tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLookupConstantSymbol(t *testing.T) {
+	if d, ok := LookupConstantSymbol("308"); !ok || d != "Payments for services" {
+		t.Errorf("expected the services symbol, got %q, %v", d, ok)
+	}
+	for _, code := range []string{"", "1234", "03080", "03a8"} {
+		if _, ok := LookupConstantSymbol(code); ok {
+			t.Errorf("expected %q to be unknown", code)
+		}
+	}
+	if len(constantSymbols()) == 0 {
+		t.Error("expected an embedded constant symbol list")
+	}
+}
+
+func TestValidateConstantSymbol(t *testing.T) {
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"", nil},
+		{"308", nil},
+		{" 0558 ", nil},
+		{"1234", ErrConstantSymbol},
+		{"12345", ErrSymbol},
+		{"3O8", ErrSymbol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if err := ValidateConstantSymbol(tt.in); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestVariableSymbolFromInvoiceID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"FV-2024/0042", "20240042"},
+		{"2024001", "2024001"},
+		{"INV 0000123", "123"},
+		{"2024-0000000042", "42"},
+		{"FA-2024-12-31-000123", "1231000123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := VariableSymbolFromInvoiceID(tt.id)
+			if err != nil {
+				t.Fatalf("VariableSymbolFromInvoiceID() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := VariableSymbolFromInvoiceID("ABC"); !errors.Is(err, ErrSymbol) {
+		t.Errorf("expected ErrSymbol, got %v", err)
+	}
+}
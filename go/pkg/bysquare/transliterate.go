@@ -0,0 +1,280 @@
+package bysquare
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Transliterator rewrites text into characters that older banking apps
+// display, typically basic Latin letters. Implementations must be safe for
+// concurrent use.
+type Transliterator interface {
+	Transliterate(s string) string
+}
+
+// TransliteratorFunc adapts an ordinary function to a Transliterator.
+type TransliteratorFunc func(s string) string
+
+// Transliterate calls f(s).
+func (f TransliteratorFunc) Transliterate(s string) string {
+	return f(s)
+}
+
+var (
+	// DefaultTransliterator is Deburr: Latin-1 Supplement and Latin
+	// Extended-A letters plus combining marks. Its output matches the
+	// TypeScript library, other scripts pass through unchanged.
+	DefaultTransliterator Transliterator = TransliteratorFunc(Deburr)
+
+	// ExtendedTransliterator covers DefaultTransliterator and in addition:
+	//
+	//	Latin Extended-B     ș ț ǎ ơ ư   -> s t a o u
+	//	Latin Ext Additional ạ ế ỗ ự     -> a e o u (Vietnamese)
+	//	Cyrillic             Шевченко    -> Shevchenko (ISO 9 System B)
+	//	Greek                Αθήνα       -> Athina (ELOT 743)
+	//	Punctuation          ‘ ’ “ ” – … -> ' ' " " - ...
+	//
+	// Cyrillic follows ISO 9:1995 System B (GOST 7.79-2000 B) without the
+	// backtick marks, so the hard and soft signs are dropped and ы, э, ґ,
+	// ў read y, e, g, u. Multi-letter replacements are upper-cased inside
+	// upper-case words (ЩИТ -> SHHIT). Greek is transliterated letter by
+	// letter, except that ου reads ou. Characters of other scripts pass
+	// through unchanged.
+	ExtendedTransliterator Transliterator = TransliteratorFunc(transliterateExtended)
+)
+
+// Chain returns a Transliterator applying ts in order, so a custom table can
+// run before a built-in one. Nil entries are skipped.
+func Chain(ts ...Transliterator) Transliterator {
+	return TransliteratorFunc(func(s string) string {
+		for _, t := range ts {
+			if t != nil {
+				s = t.Transliterate(s)
+			}
+		}
+		return s
+	})
+}
+
+// Change records a text field an encoder rewrote, so applications can show
+// users what was altered. Path names the field like FieldError.Name, e.g.
+// "payments[0].beneficiary.name".
+type Change struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Changes collects the Change records of one model.
+type Changes []Change
+
+// Apply replaces *s with t.Transliterate(*s) and records a Change under path
+// when the text differs.
+func (c *Changes) Apply(t Transliterator, path string, s *string) {
+	if *s == "" {
+		return
+	}
+	after := t.Transliterate(*s)
+	if after == *s {
+		return
+	}
+	*c = append(*c, Change{Path: path, Before: *s, After: after})
+	*s = after
+}
+
+// transliterateExtended implements ExtendedTransliterator.
+func transliterateExtended(s string) string {
+	runes := []rune(s)
+
+	var result strings.Builder
+	result.Grow(len(s))
+
+	for i, r := range runes {
+		var prev, next rune
+		if i > 0 {
+			prev = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		replacement, ok := lookupExtended(r)
+		if !ok {
+			if !isExtendedCombiningMark(r) {
+				result.WriteRune(r)
+			}
+			continue
+		}
+
+		switch r {
+		case 'ц', 'Ц':
+			// System B writes cz before e, i, y and j, c elsewhere.
+			if n, _ := lookupExtended(next); n != "" && strings.ContainsRune("eijyEIJY", rune(n[0])) {
+				replacement += "z"
+			}
+		case 'υ', 'ύ', 'Υ', 'Ύ':
+			// ELOT 743 writes the diphthong ου as ou.
+			if strings.ContainsRune("οόΟΌ", prev) {
+				replacement = "u"
+				if unicode.IsUpper(r) {
+					replacement = "U"
+				}
+			}
+		}
+
+		if len(replacement) > 1 && unicode.IsUpper(r) && isUpperContext(prev, next) {
+			replacement = strings.ToUpper(replacement)
+		}
+
+		result.WriteString(replacement)
+	}
+
+	return result.String()
+}
+
+// lookupExtended returns the replacement for r from the deburr map or the
+// extended map.
+func lookupExtended(r rune) (string, bool) {
+	if replacement, ok := deburredLettersMap[r]; ok {
+		return replacement, true
+	}
+	replacement, ok := transliterationMap[r]
+	return replacement, ok
+}
+
+// isUpperContext reports whether a letter sits inside an upper-case word:
+// the next rune is an upper-case letter, or there is no next letter and the
+// previous rune is upper-case.
+func isUpperContext(prev, next rune) bool {
+	if unicode.IsLetter(next) {
+		return unicode.IsUpper(next)
+	}
+	return unicode.IsUpper(prev)
+}
+
+// isExtendedCombiningMark extends isCombiningMark with the supplementary
+// combining blocks (U+1AB0-U+1AFF, U+1DC0-U+1DFF, U+FE20-U+FE2F).
+func isExtendedCombiningMark(r rune) bool {
+	return isCombiningMark(r) ||
+		(r >= '\u1ab0' && r <= '\u1aff') ||
+		(r >= '\u1dc0' && r <= '\u1dff') ||
+		(r >= '\ufe20' && r <= '\ufe2f')
+}
+
+// transliterationMap maps letters outside deburredLettersMap to basic Latin.
+var transliterationMap = map[rune]string{
+	// Latin Extended-B block
+	'\u0180': "b", '\u0181': "B", '\u0182': "B", '\u0183': "b", '\u0187': "C", '\u0188': "c",
+	'\u018a': "D", '\u018b': "D", '\u018c': "d", '\u0191': "F", '\u0192': "f", '\u0193': "G",
+	'\u0197': "I", '\u0198': "K", '\u0199': "k", '\u019a': "l", '\u019d': "N", '\u019e': "n",
+	'\u019f': "O", '\u01a0': "O", '\u01a1': "o", '\u01a2': "Oi", '\u01a3': "oi", '\u01a4': "P",
+	'\u01a5': "p", '\u01ab': "t", '\u01ac': "T", '\u01ad': "t", '\u01ae': "T", '\u01af': "U",
+	'\u01b0': "u", '\u01b2': "V", '\u01b3': "Y", '\u01b4': "y", '\u01b5': "Z", '\u01b6': "z",
+	'\u01c4': "DZ", '\u01c5': "Dz", '\u01c6': "dz", '\u01c7': "LJ", '\u01c8': "Lj", '\u01c9': "lj",
+	'\u01ca': "NJ", '\u01cb': "Nj", '\u01cc': "nj", '\u01cd': "A", '\u01ce': "a", '\u01cf': "I",
+	'\u01d0': "i", '\u01d1': "O", '\u01d2': "o", '\u01d3': "U", '\u01d4': "u", '\u01d5': "U",
+	'\u01d6': "u", '\u01d7': "U", '\u01d8': "u", '\u01d9': "U", '\u01da': "u", '\u01db': "U",
+	'\u01dc': "u", '\u01de': "A", '\u01df': "a", '\u01e0': "A", '\u01e1': "a", '\u01e2': "Ae",
+	'\u01e3': "ae", '\u01e4': "G", '\u01e5': "g", '\u01e6': "G", '\u01e7': "g", '\u01e8': "K",
+	'\u01e9': "k", '\u01ea': "O", '\u01eb': "o", '\u01ec': "O", '\u01ed': "o", '\u01f0': "j",
+	'\u01f1': "DZ", '\u01f2': "Dz", '\u01f3': "dz", '\u01f4': "G", '\u01f5': "g", '\u01f8': "N",
+	'\u01f9': "n", '\u01fa': "A", '\u01fb': "a", '\u01fc': "Ae", '\u01fd': "ae", '\u01fe': "O",
+	'\u01ff': "o", '\u0200': "A", '\u0201': "a", '\u0202': "A", '\u0203': "a", '\u0204': "E",
+	'\u0205': "e", '\u0206': "E", '\u0207': "e", '\u0208': "I", '\u0209': "i", '\u020a': "I",
+	'\u020b': "i", '\u020c': "O", '\u020d': "o", '\u020e': "O", '\u020f': "o", '\u0210': "R",
+	'\u0211': "r", '\u0212': "R", '\u0213': "r", '\u0214': "U", '\u0215': "u", '\u0216': "U",
+	'\u0217': "u", '\u0218': "S", '\u0219': "s", '\u021a': "T", '\u021b': "t", '\u021e': "H",
+	'\u021f': "h", '\u0220': "N", '\u0221': "d", '\u0222': "Ou", '\u0223': "ou", '\u0224': "Z",
+	'\u0225': "z", '\u0226': "A", '\u0227': "a", '\u0228': "E", '\u0229': "e", '\u022a': "O",
+	'\u022b': "o", '\u022c': "O", '\u022d': "o", '\u022e': "O", '\u022f': "o", '\u0230': "O",
+	'\u0231': "o", '\u0232': "Y", '\u0233': "y", '\u0234': "l", '\u0235': "n", '\u0236': "t",
+	'\u023a': "A", '\u023b': "C", '\u023c': "c", '\u023d': "L", '\u023e': "T", '\u023f': "s",
+	'\u0240': "z", '\u0243': "B", '\u0246': "E", '\u0247': "e", '\u0248': "J", '\u0249': "j",
+	'\u024b': "q", '\u024c': "R", '\u024d': "r", '\u024e': "Y", '\u024f': "y",
+
+	// Latin Extended Additional block
+	'\u1e00': "A", '\u1e01': "a", '\u1e02': "B", '\u1e03': "b", '\u1e04': "B", '\u1e05': "b",
+	'\u1e06': "B", '\u1e07': "b", '\u1e08': "C", '\u1e09': "c", '\u1e0a': "D", '\u1e0b': "d",
+	'\u1e0c': "D", '\u1e0d': "d", '\u1e0e': "D", '\u1e0f': "d", '\u1e10': "D", '\u1e11': "d",
+	'\u1e12': "D", '\u1e13': "d", '\u1e14': "E", '\u1e15': "e", '\u1e16': "E", '\u1e17': "e",
+	'\u1e18': "E", '\u1e19': "e", '\u1e1a': "E", '\u1e1b': "e", '\u1e1c': "E", '\u1e1d': "e",
+	'\u1e1e': "F", '\u1e1f': "f", '\u1e20': "G", '\u1e21': "g", '\u1e22': "H", '\u1e23': "h",
+	'\u1e24': "H", '\u1e25': "h", '\u1e26': "H", '\u1e27': "h", '\u1e28': "H", '\u1e29': "h",
+	'\u1e2a': "H", '\u1e2b': "h", '\u1e2c': "I", '\u1e2d': "i", '\u1e2e': "I", '\u1e2f': "i",
+	'\u1e30': "K", '\u1e31': "k", '\u1e32': "K", '\u1e33': "k", '\u1e34': "K", '\u1e35': "k",
+	'\u1e36': "L", '\u1e37': "l", '\u1e38': "L", '\u1e39': "l", '\u1e3a': "L", '\u1e3b': "l",
+	'\u1e3c': "L", '\u1e3d': "l", '\u1e3e': "M", '\u1e3f': "m", '\u1e40': "M", '\u1e41': "m",
+	'\u1e42': "M", '\u1e43': "m", '\u1e44': "N", '\u1e45': "n", '\u1e46': "N", '\u1e47': "n",
+	'\u1e48': "N", '\u1e49': "n", '\u1e4a': "N", '\u1e4b': "n", '\u1e4c': "O", '\u1e4d': "o",
+	'\u1e4e': "O", '\u1e4f': "o", '\u1e50': "O", '\u1e51': "o", '\u1e52': "O", '\u1e53': "o",
+	'\u1e54': "P", '\u1e55': "p", '\u1e56': "P", '\u1e57': "p", '\u1e58': "R", '\u1e59': "r",
+	'\u1e5a': "R", '\u1e5b': "r", '\u1e5c': "R", '\u1e5d': "r", '\u1e5e': "R", '\u1e5f': "r",
+	'\u1e60': "S", '\u1e61': "s", '\u1e62': "S", '\u1e63': "s", '\u1e64': "S", '\u1e65': "s",
+	'\u1e66': "S", '\u1e67': "s", '\u1e68': "S", '\u1e69': "s", '\u1e6a': "T", '\u1e6b': "t",
+	'\u1e6c': "T", '\u1e6d': "t", '\u1e6e': "T", '\u1e6f': "t", '\u1e70': "T", '\u1e71': "t",
+	'\u1e72': "U", '\u1e73': "u", '\u1e74': "U", '\u1e75': "u", '\u1e76': "U", '\u1e77': "u",
+	'\u1e78': "U", '\u1e79': "u", '\u1e7a': "U", '\u1e7b': "u", '\u1e7c': "V", '\u1e7d': "v",
+	'\u1e7e': "V", '\u1e7f': "v", '\u1e80': "W", '\u1e81': "w", '\u1e82': "W", '\u1e83': "w",
+	'\u1e84': "W", '\u1e85': "w", '\u1e86': "W", '\u1e87': "w", '\u1e88': "W", '\u1e89': "w",
+	'\u1e8a': "X", '\u1e8b': "x", '\u1e8c': "X", '\u1e8d': "x", '\u1e8e': "Y", '\u1e8f': "y",
+	'\u1e90': "Z", '\u1e91': "z", '\u1e92': "Z", '\u1e93': "z", '\u1e94': "Z", '\u1e95': "z",
+	'\u1e96': "h", '\u1e97': "t", '\u1e98': "w", '\u1e99': "y", '\u1e9a': "a", '\u1ea0': "A",
+	'\u1ea1': "a", '\u1ea2': "A", '\u1ea3': "a", '\u1ea4': "A", '\u1ea5': "a", '\u1ea6': "A",
+	'\u1ea7': "a", '\u1ea8': "A", '\u1ea9': "a", '\u1eaa': "A", '\u1eab': "a", '\u1eac': "A",
+	'\u1ead': "a", '\u1eae': "A", '\u1eaf': "a", '\u1eb0': "A", '\u1eb1': "a", '\u1eb2': "A",
+	'\u1eb3': "a", '\u1eb4': "A", '\u1eb5': "a", '\u1eb6': "A", '\u1eb7': "a", '\u1eb8': "E",
+	'\u1eb9': "e", '\u1eba': "E", '\u1ebb': "e", '\u1ebc': "E", '\u1ebd': "e", '\u1ebe': "E",
+	'\u1ebf': "e", '\u1ec0': "E", '\u1ec1': "e", '\u1ec2': "E", '\u1ec3': "e", '\u1ec4': "E",
+	'\u1ec5': "e", '\u1ec6': "E", '\u1ec7': "e", '\u1ec8': "I", '\u1ec9': "i", '\u1eca': "I",
+	'\u1ecb': "i", '\u1ecc': "O", '\u1ecd': "o", '\u1ece': "O", '\u1ecf': "o", '\u1ed0': "O",
+	'\u1ed1': "o", '\u1ed2': "O", '\u1ed3': "o", '\u1ed4': "O", '\u1ed5': "o", '\u1ed6': "O",
+	'\u1ed7': "o", '\u1ed8': "O", '\u1ed9': "o", '\u1eda': "O", '\u1edb': "o", '\u1edc': "O",
+	'\u1edd': "o", '\u1ede': "O", '\u1edf': "o", '\u1ee0': "O", '\u1ee1': "o", '\u1ee2': "O",
+	'\u1ee3': "o", '\u1ee4': "U", '\u1ee5': "u", '\u1ee6': "U", '\u1ee7': "u", '\u1ee8': "U",
+	'\u1ee9': "u", '\u1eea': "U", '\u1eeb': "u", '\u1eec': "U", '\u1eed': "u", '\u1eee': "U",
+	'\u1eef': "u", '\u1ef0': "U", '\u1ef1': "u", '\u1ef2': "Y", '\u1ef3': "y", '\u1ef4': "Y",
+	'\u1ef5': "y", '\u1ef6': "Y", '\u1ef7': "y", '\u1ef8': "Y", '\u1ef9': "y", '\u1efe': "Y",
+	'\u1eff': "y", '\u1e9e': "SS",
+
+	// Cyrillic block, ISO 9:1995 System B
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E",
+	'Ё': "Yo", 'Ж': "Zh", 'З': "Z", 'И': "I", 'Й': "J", 'К': "K",
+	'Л': "L", 'М': "M", 'Н': "N", 'О': "O", 'П': "P", 'Р': "R",
+	'С': "S", 'Т': "T", 'У': "U", 'Ф': "F", 'Х': "X", 'Ц': "C",
+	'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shh", 'Ъ': "", 'Ы': "Y", 'Ь': "",
+	'Э': "E", 'Ю': "Yu", 'Я': "Ya",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e",
+	'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i", 'й': "j", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "c",
+	'ч': "ch", 'ш': "sh", 'щ': "shh", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+	// Ukrainian and Belarusian
+	'Ґ': "G", 'Є': "Ye", 'І': "I", 'Ї': "Yi", 'Ў': "U",
+	'ґ': "g", 'є': "ye", 'і': "i", 'ї': "yi", 'ў': "u",
+	// Serbian and Macedonian
+	'Ђ': "Dj", 'Ѓ': "G", 'Ѕ': "Z", 'Ј': "J", 'Љ': "Lj", 'Њ': "Nj", 'Ћ': "C", 'Ќ': "K", 'Џ': "Dh",
+	'ђ': "dj", 'ѓ': "g", 'ѕ': "z", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'ќ': "k", 'џ': "dh",
+
+	// Greek block, ELOT 743
+	'Α': "A", 'Β': "V", 'Γ': "G", 'Δ': "D", 'Ε': "E", 'Ζ': "Z",
+	'Η': "I", 'Θ': "Th", 'Ι': "I", 'Κ': "K", 'Λ': "L", 'Μ': "M",
+	'Ν': "N", 'Ξ': "X", 'Ο': "O", 'Π': "P", 'Ρ': "R", 'Σ': "S",
+	'Τ': "T", 'Υ': "Y", 'Φ': "F", 'Χ': "Ch", 'Ψ': "Ps", 'Ω': "O",
+	'Ά': "A", 'Έ': "E", 'Ή': "I", 'Ί': "I", 'Ό': "O", 'Ύ': "Y", 'Ώ': "O", 'Ϊ': "I", 'Ϋ': "Y",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z",
+	'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+	'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o", 'ϊ': "i", 'ϋ': "y",
+	'ΐ': "i", 'ΰ': "y",
+
+	// Typographic punctuation and spaces
+	'\u00a0': " ", '\u2007': " ", '\u2009': " ", '\u202f': " ",
+	'\u2010': "-", '\u2011': "-", '\u2012': "-", '\u2013': "-", '\u2014': "-", '\u2015': "-", '\u2212': "-",
+	'\u2018': "'", '\u2019': "'", '\u201a': "'", '\u201b': "'", '\u2032': "'", '\u02bc': "'",
+	'\u201c': "\"", '\u201d': "\"", '\u201e': "\"", '\u201f': "\"", '\u2033': "\"",
+	'\u00ab': "\"", '\u00bb': "\"", '\u2039': "'", '\u203a': "'",
+	'\u2026': "...",
+}
@@ -0,0 +1,136 @@
+package bysquare
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendedTransliterator(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "deburr map kept",
+			input:    "Príliš žluťoučký kůň",
+			expected: "Prilis zlutoucky kun",
+		},
+		{
+			name:     "Romanian comma below",
+			input:    "Ștefan Țiriac, București",
+			expected: "Stefan Tiriac, Bucuresti",
+		},
+		{
+			name:     "Vietnamese",
+			input:    "Nguyễn Thị Minh Khai, Hồ Chí Minh",
+			expected: "Nguyen Thi Minh Khai, Ho Chi Minh",
+		},
+		{
+			name:     "Latin Extended-B",
+			input:    "ǅemal ƒ ǎ ơ ư",
+			expected: "Dzemal f a o u",
+		},
+		{
+			name:     "Ukrainian",
+			input:    "Шевченко Юлія, Київ",
+			expected: "Shevchenko Yuliya, Kiyiv",
+		},
+		{
+			name:     "Russian signs dropped",
+			input:    "Ильич объезд",
+			expected: "Ilich obezd",
+		},
+		{
+			name:     "tse before front vowels",
+			input:    "Цибуля цапля",
+			expected: "Czibulya caplya",
+		},
+		{
+			name:     "upper-case word",
+			input:    "ЩИТ ЖУК Ж",
+			expected: "SHHIT ZHUK Zh",
+		},
+		{
+			name:     "Serbian",
+			input:    "Ђорђе Љубица Џон",
+			expected: "Djordje Ljubica Dhon",
+		},
+		{
+			name:     "Greek",
+			input:    "Αθήνα Θεσσαλονίκη",
+			expected: "Athina Thessaloniki",
+		},
+		{
+			name:     "Greek ou",
+			input:    "Κουκούλα ΟΥΖΟ",
+			expected: "Koukoula OUZO",
+		},
+		{
+			name:     "punctuation",
+			input:    "„Quote“ – it’s…",
+			expected: "\"Quote\" - it's...",
+		},
+		{
+			name:     "supplementary combining mark",
+			input:    "a᷄b",
+			expected: "ab",
+		},
+		{
+			name:     "other scripts passthrough",
+			input:    "東京 123",
+			expected: "東京 123",
+		},
+		{
+			name:     "empty string",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ExtendedTransliterator.Transliterate(tc.input)
+			if result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDefaultTransliterator(t *testing.T) {
+	input := "Ľubomír Ștefan Шевченко"
+	if got, want := DefaultTransliterator.Transliterate(input), Deburr(input); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestChain(t *testing.T) {
+	custom := TransliteratorFunc(func(s string) string {
+		return strings.ReplaceAll(s, "Київ", "Kyiv")
+	})
+
+	result := Chain(custom, nil, ExtendedTransliterator).Transliterate("Київ, Львів")
+	if expected := "Kyiv, Lviv"; result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestChangesApply(t *testing.T) {
+	name, city, street := "Ján Nováček", "Bratislava", ""
+
+	var changes Changes
+	changes.Apply(DefaultTransliterator, "name", &name)
+	changes.Apply(DefaultTransliterator, "city", &city)
+	changes.Apply(DefaultTransliterator, "street", &street)
+
+	if name != "Jan Novacek" {
+		t.Errorf("expected %q, got %q", "Jan Novacek", name)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if expected := (Change{Path: "name", Before: "Ján Nováček", After: "Jan Novacek"}); changes[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, changes[0])
+	}
+}
@@ -5,6 +5,8 @@
 // types, encoding, decoding, and validation for each bysquare type.
 package bysquare
 
+import "fmt"
+
 // Version represents the BySquare format version.
 type Version uint8
 
@@ -21,3 +23,17 @@ const (
 	// Released Date: 2025-04-01
 	Version120 Version = 0x02
 )
+
+// String returns the specification version, e.g. "1.2.0".
+func (v Version) String() string {
+	switch v {
+	case Version100:
+		return "1.0.0"
+	case Version110:
+		return "1.1.0"
+	case Version120:
+		return "1.2.0"
+	default:
+		return fmt.Sprintf("Version(%d)", uint8(v))
+	}
+}
@@ -8,9 +8,6 @@ import (
 )
 
 var (
-	// IBAN regex: 2 letters + 2 digits + up to 30 alphanumeric
-	ibanRegex = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{1,30}$`)
-
 	// BIC regex: 4 letters + 2 letters + 2 alphanumeric + optional 3 alphanumeric
 	bicRegex = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
 
@@ -18,38 +15,9 @@ var (
 	dateRegex = regexp.MustCompile(`^\d{8}$`)
 )
 
-// IsValidIBAN checks if IBAN is valid using MOD-97 algorithm.
+// IsValidIBAN checks if IBAN is valid, see ValidateIBAN.
 func IsValidIBAN(iban string) bool {
-	// Remove spaces and convert to uppercase
-	iban = strings.ReplaceAll(strings.ToUpper(iban), " ", "")
-
-	// Check format
-	if !ibanRegex.MatchString(iban) {
-		return false
-	}
-
-	// MOD-97 validation
-	// Move first 4 characters to end
-	rearranged := iban[4:] + iban[0:4]
-
-	// Convert letters to numbers (A=10, B=11, ..., Z=35)
-	var numeric strings.Builder
-	for _, ch := range rearranged {
-		if ch >= 'A' && ch <= 'Z' {
-			fmt.Fprintf(&numeric, "%d", int(ch)-'A'+10)
-		} else {
-			numeric.WriteByte(byte(ch))
-		}
-	}
-
-	// Calculate mod 97
-	numStr := numeric.String()
-	remainder := 0
-	for _, digit := range numStr {
-		remainder = (remainder*10 + int(digit-'0')) % 97
-	}
-
-	return remainder == 1
+	return ValidateIBAN(iban) == nil
 }
 
 // IsValidBIC checks if BIC is valid.
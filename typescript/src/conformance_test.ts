@@ -0,0 +1,185 @@
+/**
+ * Runs the cross-implementation corpus in conformance/ against this library.
+ * The vector format and rules are described in conformance/README.md.
+ *
+ * Covers:
+ * - Encoding vector inputs to the expected payload
+ * - Reproducing QR strings, including those of the Go encoder
+ * - Decoding every QR string
+ * - Re-encoding decoded models to the expected payload
+ */
+
+import {
+	describe,
+	expect,
+	test,
+} from "bun:test";
+import {
+	readdirSync,
+	readFileSync,
+} from "node:fs";
+import { join } from "node:path";
+
+import * as invoice from "./invoice/index.js";
+import * as pay from "./pay/index.js";
+import { Version } from "./types.js";
+
+const CORPUS_DIR = join(import.meta.dir, "../../conformance/v1");
+const CORPUS_FORMAT = 1;
+
+type Model = Record<string, any>;
+
+type Vector = {
+	name: string;
+	type: "pay" | "invoice";
+	version: Version;
+	deburr?: boolean;
+	encoder: "go" | "typescript";
+	decodeOnly?: boolean;
+	input?: Model;
+	payload: string;
+	qr: string;
+	decoded: Model;
+};
+
+function loadVectors(): Vector[] {
+	const vectors: Vector[] = [];
+	for (const file of readdirSync(CORPUS_DIR).filter((f) => f.endsWith(".json")).sort()) {
+		const corpus = JSON.parse(readFileSync(join(CORPUS_DIR, file), "utf8"));
+		if (corpus.format !== CORPUS_FORMAT) {
+			throw new Error(`${file}: unsupported corpus format ${corpus.format}`);
+		}
+		vectors.push(...corpus.vectors);
+	}
+	return vectors;
+}
+
+const DIRECT_DEBIT_RENAMES: Record<string, string> = {
+	variableSymbol: "ddVariableSymbol",
+	specificSymbol: "ddSpecificSymbol",
+	originatorsReferenceInformation: "ddOriginatorsReferenceInformation",
+};
+
+/**
+ * Converts a corpus data model, which uses the JSON of the Go module, to
+ * this library's model by moving the payment extensions inline.
+ */
+function toModel(vector: Vector, model: Model): Model {
+	const m = structuredClone(model);
+	if (vector.type !== "pay") {
+		return m;
+	}
+
+	for (const payment of m.payments) {
+		if (payment.standingOrderExt) {
+			Object.assign(payment, payment.standingOrderExt);
+			delete payment.standingOrderExt;
+		}
+		if (payment.directDebitExt) {
+			for (const [key, value] of Object.entries(payment.directDebitExt)) {
+				payment[DIRECT_DEBIT_RENAMES[key] ?? key] = value;
+			}
+			delete payment.directDebitExt;
+		}
+	}
+	return m;
+}
+
+/**
+ * Drops undefined values, empty strings and empty objects, which the Go
+ * JSON leaves out.
+ */
+function normalize(value: unknown): unknown {
+	if (Array.isArray(value)) {
+		return value.map(normalize);
+	}
+	if (value === null || typeof value !== "object") {
+		return value;
+	}
+
+	const out: Record<string, unknown> = {};
+	for (const [key, v] of Object.entries(value)) {
+		const n = normalize(v);
+		const empty = typeof n === "object" && n !== null && !Array.isArray(n) && Object.keys(n).length === 0;
+		if (n !== undefined && n !== "" && !empty) {
+			out[key] = n;
+		}
+	}
+	return out;
+}
+
+function encode(vector: Vector, model: Model): string {
+	if (vector.type === "pay") {
+		return pay.encode(model as pay.DataModel, {
+			deburr: vector.deburr ?? false,
+			validate: true,
+			version: vector.version,
+		});
+	}
+	return invoice.encode(model as invoice.DataModel, {
+		validate: true,
+		version: vector.version,
+	});
+}
+
+/**
+ * Applies the steps encode runs before serializing: diacritics removal
+ * and validation.
+ */
+function prepare(vector: Vector, model: Model): void {
+	if (vector.type === "pay") {
+		if (vector.deburr) {
+			pay.removeDiacritics(model as pay.DataModel);
+		}
+		pay.validateDataModel(model as pay.DataModel, vector.version);
+		return;
+	}
+	invoice.validateDataModel(model as invoice.DataModel);
+}
+
+function serialize(vector: Vector, model: Model): string {
+	if (vector.type === "pay") {
+		return pay.serialize(model as pay.DataModel);
+	}
+	return invoice.serialize(model as invoice.DataModel);
+}
+
+function decode(vector: Vector): Model {
+	if (vector.type === "pay") {
+		return pay.decode(vector.qr);
+	}
+	return invoice.decode(vector.qr);
+}
+
+describe("conformance", () => {
+	for (const vector of loadVectors()) {
+		// The beneficiary block is written at every version, so PAY 1.0.0
+		// payloads, which have none, are only decoded.
+		const encodable = !vector.decodeOnly
+			&& !(vector.type === "pay" && vector.version === Version["1.0.0"]);
+
+		describe(vector.name, () => {
+			if (encodable) {
+				test("encodes input to payload", () => {
+					const model = toModel(vector, vector.input!);
+					prepare(vector, model);
+					expect(serialize(vector, model)).toBe(vector.payload);
+				});
+
+				test("reproduces qr", () => {
+					expect(encode(vector, toModel(vector, vector.input!))).toBe(vector.qr);
+				});
+			}
+
+			test("decodes qr", () => {
+				expect(normalize(decode(vector))).toEqual(normalize(toModel(vector, vector.decoded)));
+			});
+
+			if (encodable) {
+				test("re-encodes decoded model to payload", () => {
+					expect(serialize(vector, decode(vector))).toBe(vector.payload);
+				});
+			}
+		});
+	}
+});